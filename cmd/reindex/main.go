@@ -0,0 +1,107 @@
+// Command reindex rebuilds the derived search data from Postgres.
+//
+//	go run ./cmd/reindex [--entity=users|posts|comments|reports] [--dry-run]
+//
+// Progress is saved to a checkpoint file after every batch; running the command
+// again after an interruption resumes where it stopped.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"time"
+
+	_ "github.com/lib/pq" // Import the postgres driver
+
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/reindex"
+	"github.com/imhasandl/search-service/internal/translit"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	entity := flag.String("entity", "", "reindex only users, posts, comments or reports (default all)")
+	dryRun := flag.Bool("dry-run", false, "only count the rows that would be reindexed")
+	batchSize := flag.Int("batch-size", 500, "rows per batch")
+	checkpointPath := flag.String("checkpoint", "reindex.checkpoint.json", "checkpoint file used to resume an interrupted run")
+	restart := flag.Bool("restart", false, "ignore an existing checkpoint and start from the beginning")
+	progressInterval := flag.Duration("progress-interval", 5*time.Second, "how often to report progress")
+	flag.Parse()
+
+	if *batchSize <= 0 {
+		log.Fatalf("Invalid --batch-size %d, must be positive", *batchSize)
+	}
+
+	entities := index.Entities
+	if *entity != "" {
+		if !slices.Contains(index.Entities, index.Entity(*entity)) {
+			log.Fatalf("Unknown entity %q, expected one of %v", *entity, index.Entities)
+		}
+		entities = []index.Entity{index.Entity(*entity)}
+	}
+
+	if os.Getenv("DOCKER_CONTAINER") != "true" {
+		if err := godotenv.Load(".env"); err != nil {
+			log.Print("Error loading env file")
+		}
+	}
+
+	dbURL := os.Getenv("DB_URL")
+	if dbURL == "" {
+		log.Fatalf("Set db connection in env")
+	}
+
+	dbConn, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Error opening database: %s", err)
+	}
+	defer dbConn.Close()
+
+	fieldAnalyzers, err := analyzer.NewFields(analyzer.NewRegistry(), os.Getenv("SEARCH_FIELD_ANALYZERS"))
+	if err != nil {
+		log.Fatalf("Error configuring analyzers: %v", err)
+	}
+
+	transliterationTable := translit.DefaultTable
+	if tableFile := os.Getenv("TRANSLIT_TABLE_FILE"); tableFile != "" {
+		transliterationTable, err = translit.LoadTable(tableFile)
+		if err != nil {
+			log.Fatalf("Error loading transliteration table: %v", err)
+		}
+	}
+
+	if *restart {
+		if *dryRun {
+			*checkpointPath = ""
+		} else if err := os.Remove(*checkpointPath); err != nil && !os.IsNotExist(err) {
+			log.Fatalf("Error removing checkpoint: %v", err)
+		}
+	}
+
+	reindexer := &reindex.Reindexer{
+		DB:               database.New(dbConn),
+		Transliterator:   translit.New(transliterationTable),
+		UsernameAnalyzer: fieldAnalyzers.For(analyzer.FieldUsername),
+		BatchSize:        int32(*batchSize),
+		CheckpointPath:   *checkpointPath,
+		DryRun:           *dryRun,
+		ProgressInterval: *progressInterval,
+	}
+
+	// An interrupted run keeps its checkpoint, so it can be resumed.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	startTime := time.Now()
+	if err := reindexer.Run(ctx, entities); err != nil {
+		log.Fatalf("Reindex failed: %v", err)
+	}
+	log.Printf("Reindex finished in %v", time.Since(startTime).Round(time.Second))
+}
@@ -0,0 +1,195 @@
+// Command searchctl runs admin tasks against a running search service.
+//
+//	go run ./cmd/searchctl verify [--addr=localhost:50051] [--token=...] [--entity=users,posts] [--sample=1000] [--repair]
+//	go run ./cmd/searchctl audit [--addr=localhost:50051] [--token=...] [--actor=user-id] [--since=24h] [--limit=100]
+//
+// verify compares the in-memory index with Postgres and exits with status 1 when they differ.
+// audit prints the audit trail of sensitive searches, newest first.
+//
+// The token is an admin bearer token, read from SEARCH_TOKEN by default. With --tls-ca the connection
+// uses TLS, presenting the certificate in --tls-cert and --tls-key to services that require one.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/imhasandl/search-service/protos"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "verify":
+		verify(os.Args[2:])
+	case "audit":
+		auditLog(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: searchctl verify|audit [flags]")
+	os.Exit(2)
+}
+
+func verify(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	entities := flags.String("entity", "", "comma separated entities to check (default users,posts,reports)")
+	sample := flags.Int("sample", 0, "rows to check per entity, 0 scans every row")
+	repair := flags.Bool("repair", false, "re-index the differences found")
+	timeout := flags.Duration("timeout", 10*time.Minute, "how long to wait for the check")
+	conn := connectionFlags(flags)
+	_ = flags.Parse(args)
+
+	client, closeConn := conn.dial()
+	defer closeConn()
+
+	req := &pb.VerifyIndexRequest{SampleSize: int32(*sample), Repair: *repair}
+	if *entities != "" {
+		req.Entities = strings.Split(*entities, ",")
+	}
+
+	ctx, cancel := conn.context(*timeout)
+	defer cancel()
+	resp, err := client.VerifyIndex(ctx, req)
+	if err != nil {
+		log.Fatalf("Verification failed: %v", err)
+	}
+
+	consistent := true
+	for _, entity := range resp.Entities {
+		fmt.Printf("%s: %d checked, %d missing, %d stale, %d orphaned, %d repaired\n",
+			entity.Entity, entity.Checked, entity.MissingCount, entity.StaleCount, entity.OrphanedCount, entity.Repaired)
+		printIDs("missing", entity.Missing)
+		printIDs("stale", entity.Stale)
+		printIDs("orphaned", entity.Orphaned)
+		if entity.MissingCount+entity.StaleCount+entity.OrphanedCount > entity.Repaired {
+			consistent = false
+		}
+	}
+	if !consistent {
+		os.Exit(1)
+	}
+}
+
+func auditLog(args []string) {
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	actor := flags.String("actor", "", "only entries of this caller")
+	since := flags.Duration("since", 24*time.Hour, "how far back to look")
+	limit := flags.Int("limit", 100, "maximum entries to print")
+	conn := connectionFlags(flags)
+	_ = flags.Parse(args)
+
+	client, closeConn := conn.dial()
+	defer closeConn()
+
+	ctx, cancel := conn.context(time.Minute)
+	defer cancel()
+	resp, err := client.ListAuditLog(ctx, &pb.ListAuditLogRequest{
+		Actor: *actor,
+		Since: timestamppb.New(time.Now().Add(-*since)),
+		Limit: int32(*limit),
+	})
+	if err != nil {
+		log.Fatalf("Listing audit log failed: %v", err)
+	}
+
+	for _, entry := range resp.Entries {
+		fmt.Printf("%s  %-36s  %-20s  %-18s  %4d  %q",
+			entry.CreatedAt.AsTime().Format(time.RFC3339), entry.Actor, entry.Rpc, entry.Status, entry.ResultCount, entry.Query)
+		keys := make([]string, 0, len(entry.Filters))
+		for key := range entry.Filters {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("  %s=%s", key, entry.Filters[key])
+		}
+		fmt.Println()
+	}
+}
+
+// connection holds the flags of the connection to the search service shared by every subcommand.
+type connection struct {
+	addr, token            string
+	tlsCA, tlsCert, tlsKey string
+}
+
+func connectionFlags(flags *flag.FlagSet) *connection {
+	c := &connection{}
+	flags.StringVar(&c.addr, "addr", "localhost"+os.Getenv("PORT"), "address of the search service")
+	flags.StringVar(&c.token, "token", os.Getenv("SEARCH_TOKEN"), "bearer token sent with the request")
+	flags.StringVar(&c.tlsCA, "tls-ca", "", "CA bundle verifying the service certificate, enables TLS")
+	flags.StringVar(&c.tlsCert, "tls-cert", "", "client certificate for mutual TLS")
+	flags.StringVar(&c.tlsKey, "tls-key", "", "key of the client certificate")
+	return c
+}
+
+// dial connects to the search service and returns its client and a function closing the connection.
+func (c *connection) dial() (pb.SearchServiceClient, func()) {
+	creds, err := transportCredentials(c.tlsCA, c.tlsCert, c.tlsKey)
+	if err != nil {
+		log.Fatalf("Error loading TLS settings: %v", err)
+	}
+	conn, err := grpc.NewClient(c.addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		log.Fatalf("Error connecting to %s: %v", c.addr, err)
+	}
+	return pb.NewSearchServiceClient(conn), func() { conn.Close() }
+}
+
+// context returns a context for a call, carrying the bearer token.
+func (c *connection) context(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	if c.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.token)
+	}
+	return ctx, cancel
+}
+
+// transportCredentials returns TLS credentials when a CA bundle is given, and plaintext otherwise.
+func transportCredentials(caFile, certFile, keyFile string) (credentials.TransportCredentials, error) {
+	if caFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+	ca, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: x509.NewCertPool()}
+	if !config.RootCAs.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(config), nil
+}
+
+func printIDs(kind string, ids []string) {
+	for _, id := range ids {
+		fmt.Printf("  %s %s\n", kind, id)
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/imhasandl/search-service/cmd/helper"
+	"github.com/imhasandl/search-service/internal/database"
+	pb "github.com/imhasandl/search-service/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditQuerier reads the audit trail of sensitive searches.
+type AuditQuerier interface {
+	ListAuditEntries(ctx context.Context, arg database.ListAuditEntriesParams) ([]database.SearchAuditLog, error)
+}
+
+// ListAuditLog returns the audited calls of an actor, or of every actor, in a time range, newest first.
+// DefaultPolicy only lets admins call it.
+func (s *server) ListAuditLog(ctx context.Context, req *pb.ListAuditLogRequest) (*pb.ListAuditLogResponse, error) {
+	if s.auditLog == nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.FailedPrecondition, "audit log is not enabled - ListAuditLog", nil)
+	}
+
+	params := database.ListAuditEntriesParams{
+		Actor:    req.GetActor(),
+		Since:    time.Unix(0, 0).UTC(),
+		Until:    time.Now(),
+		RowLimit: defaultAuditLimit,
+	}
+	if req.GetSince() != nil {
+		params.Since = req.GetSince().AsTime()
+	}
+	if req.GetUntil() != nil {
+		params.Until = req.GetUntil().AsTime()
+	}
+	if !params.Since.Before(params.Until) {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.InvalidArgument, "since must be before until - ListAuditLog", nil)
+	}
+	switch limit := req.GetLimit(); {
+	case limit < 0 || limit > maxAuditLimit:
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.InvalidArgument, "limit must be between 0 and 1000 - ListAuditLog", nil)
+	case limit > 0:
+		params.RowLimit = limit
+	}
+
+	rows, err := s.auditLog.ListAuditEntries(ctx, params)
+	if err != nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't list audit log - ListAuditLog", err)
+	}
+
+	entries := make([]*pb.AuditEntry, len(rows))
+	for i, row := range rows {
+		var filters map[string]string
+		if err := json.Unmarshal(row.Filters, &filters); err != nil {
+			return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't decode audit log filters - ListAuditLog", err)
+		}
+		entries[i] = &pb.AuditEntry{
+			Id:          row.ID,
+			CreatedAt:   timestamppb.New(row.CreatedAt),
+			Actor:       row.Actor,
+			Rpc:         row.Rpc,
+			Query:       row.Query,
+			Filters:     filters,
+			ResultCount: row.ResultCount,
+			Status:      row.Status,
+		}
+	}
+
+	return &pb.ListAuditLogResponse{Entries: entries}, nil
+}
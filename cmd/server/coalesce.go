@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/imhasandl/search-service/internal/coalesce"
+	"github.com/imhasandl/search-service/internal/database"
+)
+
+// coalescer is a DatabaseQuerier that shares one backend query between concurrent identical searches.
+type coalescer struct {
+	next  DatabaseQuerier
+	group *coalesce.Group
+}
+
+func coalesced[T any](ctx context.Context, c *coalescer, key string, search func(context.Context) ([]T, error)) ([]T, error) {
+	rows, _, err := coalesce.Do(ctx, c.group, key, search)
+	return rows, err
+}
+
+// argKey encodes a search argument for coalescing keys, distinguishing a NULL query from an empty one.
+func argKey(arg sql.NullString) string {
+	return strconv.FormatBool(arg.Valid) + ":" + strconv.Quote(arg.String)
+}
+
+func (c *coalescer) SearchUsers(ctx context.Context, arg sql.NullString) ([]database.SearchUser, error) {
+	return coalesced(ctx, c, "SearchUsers "+argKey(arg), func(ctx context.Context) ([]database.SearchUser, error) {
+		return c.next.SearchUsers(ctx, arg)
+	})
+}
+
+func (c *coalescer) SearchUsersByDate(ctx context.Context, arg sql.NullString) ([]database.SearchUser, error) {
+	return coalesced(ctx, c, "SearchUsersByDate "+argKey(arg), func(ctx context.Context) ([]database.SearchUser, error) {
+		return c.next.SearchUsersByDate(ctx, arg)
+	})
+}
+
+func (c *coalescer) SearchUsersByPhonetic(ctx context.Context, arg sql.NullString) ([]database.SearchUser, error) {
+	return coalesced(ctx, c, "SearchUsersByPhonetic "+argKey(arg), func(ctx context.Context) ([]database.SearchUser, error) {
+		return c.next.SearchUsersByPhonetic(ctx, arg)
+	})
+}
+
+func (c *coalescer) SearchPosts(ctx context.Context, arg sql.NullString) ([]database.Post, error) {
+	return coalesced(ctx, c, "SearchPosts "+argKey(arg), func(ctx context.Context) ([]database.Post, error) {
+		return c.next.SearchPosts(ctx, arg)
+	})
+}
+
+func (c *coalescer) SearchPostsByDate(ctx context.Context, arg sql.NullString) ([]database.Post, error) {
+	return coalesced(ctx, c, "SearchPostsByDate "+argKey(arg), func(ctx context.Context) ([]database.Post, error) {
+		return c.next.SearchPostsByDate(ctx, arg)
+	})
+}
+
+func (c *coalescer) SearchPostsByLanguage(ctx context.Context, arg database.SearchPostsByLanguageParams) ([]database.Post, error) {
+	key := "SearchPostsByLanguage " + argKey(arg.Query) + " " + argKey(arg.Language)
+	return coalesced(ctx, c, key, func(ctx context.Context) ([]database.Post, error) {
+		return c.next.SearchPostsByLanguage(ctx, arg)
+	})
+}
+
+func (c *coalescer) SearchPostsBySubstring(ctx context.Context, arg database.SearchPostsBySubstringParams) ([]database.Post, error) {
+	key := "SearchPostsBySubstring " + strconv.Quote(arg.Pattern) + " " + argKey(arg.Language)
+	return coalesced(ctx, c, key, func(ctx context.Context) ([]database.Post, error) {
+		return c.next.SearchPostsBySubstring(ctx, arg)
+	})
+}
+
+func (c *coalescer) SearchReports(ctx context.Context, arg sql.NullString) ([]database.Report, error) {
+	return coalesced(ctx, c, "SearchReports "+argKey(arg), func(ctx context.Context) ([]database.Report, error) {
+		return c.next.SearchReports(ctx, arg)
+	})
+}
+
+func (c *coalescer) SearchReportsByDate(ctx context.Context, arg sql.NullString) ([]database.Report, error) {
+	return coalesced(ctx, c, "SearchReportsByDate "+argKey(arg), func(ctx context.Context) ([]database.Report, error) {
+		return c.next.SearchReportsByDate(ctx, arg)
+	})
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/imhasandl/search-service/internal/auth"
+	pb "github.com/imhasandl/search-service/protos"
+)
+
+// DefaultPolicy is the role required to call each RPC. Reports name who reported whom, so only
+// moderators search them; admin RPCs change the index or read private user data.
+var DefaultPolicy = map[string]string{
+	"SearchUsers":       auth.RoleUser,
+	"SearchUsersByDate": auth.RoleUser,
+	"GetUserProfile":    auth.RoleAdmin,
+
+	"SearchPosts":       auth.RoleUser,
+	"SearchPostsByDate": auth.RoleUser,
+
+	"SearchReports":       auth.RoleModerator,
+	"SearchReportsByDate": auth.RoleModerator,
+
+	"ListSynonyms":   auth.RoleAdmin,
+	"ReloadSynonyms": auth.RoleAdmin,
+
+	"ListIndexVersions":    auth.RoleAdmin,
+	"BuildIndexVersion":    auth.RoleAdmin,
+	"SwapIndexVersion":     auth.RoleAdmin,
+	"RollbackIndexVersion": auth.RoleAdmin,
+
+	"VerifyIndex": auth.RoleAdmin,
+
+	"ListAuditLog": auth.RoleAdmin,
+}
+
+// NewPolicy returns DefaultPolicy changed by overrides, a comma separated list of rpc=role pairs
+// such as "SearchReports=admin". RPCs DefaultPolicy reserves for admins can't be opened to other roles.
+func NewPolicy(overrides string) (auth.Policy, error) {
+	roles := make(map[string]string, len(DefaultPolicy))
+	for rpc, role := range DefaultPolicy {
+		roles[rpc] = role
+	}
+
+	for _, pair := range strings.Split(overrides, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		rpc, role, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid access rule %q, expected rpc=role", pair)
+		}
+		rpc, role = strings.TrimSpace(rpc), strings.TrimSpace(role)
+		if !isRPC(rpc) {
+			return nil, fmt.Errorf("unknown RPC %q", rpc)
+		}
+		if !auth.ValidRole(role) {
+			return nil, fmt.Errorf("RPC %s requires unknown role %q, available: %s, %s, %s",
+				rpc, role, auth.RoleUser, auth.RoleModerator, auth.RoleAdmin)
+		}
+		if DefaultPolicy[rpc] == auth.RoleAdmin && role != auth.RoleAdmin {
+			return nil, fmt.Errorf("RPC %s is admin-only", rpc)
+		}
+		roles[rpc] = role
+	}
+
+	policy := make(auth.Policy, len(roles))
+	for rpc, role := range roles {
+		policy[fullMethod(rpc)] = role
+	}
+	return policy, nil
+}
+
+// CheckPublicRPCs returns an error if one of rpcs, as passed to WithPublicRPCs, is reserved for admins
+// by policy. The server never lets such RPCs be called without a token.
+func CheckPublicRPCs(policy auth.Policy, rpcs ...string) error {
+	for _, rpc := range rpcs {
+		if policy[fullMethod(rpc)] == auth.RoleAdmin {
+			return fmt.Errorf("RPC %s is admin-only and can't be public", rpc)
+		}
+	}
+	return nil
+}
+
+// isRPC reports whether rpc is the name of an RPC of the search service.
+func isRPC(rpc string) bool {
+	for _, method := range pb.SearchService_ServiceDesc.Methods {
+		if method.MethodName == rpc {
+			return true
+		}
+	}
+	for _, stream := range pb.SearchService_ServiceDesc.Streams {
+		if stream.StreamName == rpc {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/cmd/helper"
+	"github.com/imhasandl/search-service/internal/auth"
+	pb "github.com/imhasandl/search-service/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GetUserProfile returns the full profile of a user, including the fields search results leave out.
+// It fails closed: callers that aren't authenticated as admins are refused before anything is read,
+// whatever the policy says.
+func (s *server) GetUserProfile(ctx context.Context, req *pb.GetUserProfileRequest) (*pb.GetUserProfileResponse, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.Unauthenticated, "authentication required - GetUserProfile", nil)
+	}
+	if !principal.HasRole(auth.RoleAdmin) {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.PermissionDenied, "admin role required - GetUserProfile", nil)
+	}
+	if s.profiles == nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.FailedPrecondition, "user profiles are not enabled - GetUserProfile", nil)
+	}
+
+	id, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.InvalidArgument, "invalid user id - GetUserProfile", err)
+	}
+
+	user, err := s.profiles.GetUserProfile(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.NotFound, "user not found - GetUserProfile", err)
+	}
+	if err != nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't get user profile - GetUserProfile", err)
+	}
+
+	return &pb.GetUserProfileResponse{
+		User: &pb.User{
+			Id:               user.ID.String(),
+			CreatedAt:        timestamppb.New(user.CreatedAt),
+			UpdatedAt:        timestamppb.New(user.UpdatedAt),
+			Email:            user.Email,
+			Username:         user.Username,
+			IsPremium:        user.IsPremium,
+			VerificationCode: user.VerificationCode,
+			IsVerified:       user.IsVerified,
+		},
+	}, nil
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/phonetic"
+)
+
+// queryVariants returns the database parameters for every rewrite of query that should be searched.
+// The original query always comes first so its matches rank ahead of synonym matches.
+func (s *server) queryVariants(query string) []sql.NullString {
+	if query == "" {
+		return []sql.NullString{{String: query, Valid: false}}
+	}
+
+	expansions := []string{query}
+	if s.synonyms != nil {
+		expansions = s.synonyms.Expand(query)
+	}
+
+	variants := make([]sql.NullString, len(expansions))
+	for i, expansion := range expansions {
+		variants[i] = sql.NullString{String: expansion, Valid: true}
+	}
+	return variants
+}
+
+// fieldVariants extends queryVariants with every variant run through the analyzer of field,
+// the form the in-memory index matches the field in, so both backends search the same terms.
+func (s *server) fieldVariants(field, query string) []sql.NullString {
+	variants := s.queryVariants(query)
+	if query == "" {
+		return variants
+	}
+	return addVariants(variants, s.fieldAnalyzers().For(field).Normalize)
+}
+
+// fieldAnalyzers returns the analyzers queries are analyzed with: those of the live index version
+// if there are versions, so a swap changes both at once, and the configured ones otherwise.
+func (s *server) fieldAnalyzers() *analyzer.Fields {
+	if s.versions != nil {
+		return s.versions.Analyzers()
+	}
+	return s.analyzers
+}
+
+// usernameVariants extends fieldVariants with the script independent key of every variant,
+// so a Cyrillic query finds Latin usernames and the other way around. The key is built exactly
+// like users.username_latin: the users.username analyzer followed by transliteration.
+func (s *server) usernameVariants(query string) []sql.NullString {
+	variants := s.fieldVariants(analyzer.FieldUsername, query)
+	if s.translit == nil || query == "" {
+		return variants
+	}
+
+	usernameAnalyzer := s.fieldAnalyzers().For(analyzer.FieldUsername)
+	return addVariants(variants, func(variant string) string {
+		return s.translit.Key(usernameAnalyzer.Normalize(variant))
+	})
+}
+
+// addVariants appends the rewrite of every variant that is neither empty nor a variant already.
+func addVariants(variants []sql.NullString, rewrite func(string) string) []sql.NullString {
+	seen := make(map[string]bool, len(variants))
+	for _, variant := range variants {
+		seen[variant.String] = true
+	}
+	for _, variant := range variants {
+		rewritten := rewrite(variant.String)
+		if rewritten == "" || seen[rewritten] {
+			continue
+		}
+		seen[rewritten] = true
+		variants = append(variants, sql.NullString{String: rewritten, Valid: true})
+	}
+	return variants
+}
+
+// phoneticCode returns the phonetic code of query, built exactly like users.username_phonetic.
+// The code is invalid when the query has no letters to encode.
+func (s *server) phoneticCode(query string) sql.NullString {
+	key := s.fieldAnalyzers().For(analyzer.FieldUsername).Normalize(query)
+	if s.translit != nil {
+		key = s.translit.Key(key)
+	}
+	code := phonetic.Encode(key)
+	return sql.NullString{String: code, Valid: code != ""}
+}
+
+// searchVariants runs search once per query variant and merges the results,
+// keeping the first occurrence of every row.
+func searchVariants[T any](ctx context.Context, variants []sql.NullString, search func(context.Context, sql.NullString) ([]T, error), id func(T) uuid.UUID) ([]T, error) {
+	if len(variants) == 1 {
+		return search(ctx, variants[0])
+	}
+
+	var merged []T
+	seen := make(map[uuid.UUID]bool)
+	for _, variant := range variants {
+		rows, err := search(ctx, variant)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			if seen[id(row)] {
+				continue
+			}
+			seen[id(row)] = true
+			merged = append(merged, row)
+		}
+	}
+	return merged, nil
+}
+
+// appendUnseen appends the rows of more that are not in rows yet. rows may be shared,
+// by the cache or with coalesced requests, so it is never appended to in place.
+func appendUnseen[T any](rows, more []T, id func(T) uuid.UUID) []T {
+	rows = slices.Clip(rows)
+	seen := make(map[uuid.UUID]bool, len(rows))
+	for _, row := range rows {
+		seen[id(row)] = true
+	}
+	for _, row := range more {
+		if !seen[id(row)] {
+			seen[id(row)] = true
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func userID(user database.SearchUser) uuid.UUID { return user.ID }
+
+func postID(post database.Post) uuid.UUID { return post.ID }
+
+func reportID(report database.Report) uuid.UUID { return report.ID }
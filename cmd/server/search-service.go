@@ -4,11 +4,25 @@ import (
 	"context"
 	"database/sql"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/imhasandl/search-service/cmd/helper"
+	"github.com/imhasandl/search-service/internal/alias"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/auth"
+	"github.com/imhasandl/search-service/internal/cache"
+	"github.com/imhasandl/search-service/internal/coalesce"
 	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/langdetect"
+	"github.com/imhasandl/search-service/internal/ngram"
+	"github.com/imhasandl/search-service/internal/synonyms"
+	"github.com/imhasandl/search-service/internal/translit"
+	"github.com/imhasandl/search-service/internal/verify"
 	pb "github.com/imhasandl/search-service/protos"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -16,97 +30,303 @@ import (
 // DatabaseQuerier defines the interface for database operations used by the search service.
 // It contains methods for searching users, posts, and reports with various filtering options.
 type DatabaseQuerier interface {
-	SearchUsers(ctx context.Context, arg sql.NullString) ([]database.User, error)
-	SearchUsersByDate(ctx context.Context, arg sql.NullString) ([]database.User, error)
+	SearchUsers(ctx context.Context, arg sql.NullString) ([]database.SearchUser, error)
+	SearchUsersByDate(ctx context.Context, arg sql.NullString) ([]database.SearchUser, error)
+	SearchUsersByPhonetic(ctx context.Context, arg sql.NullString) ([]database.SearchUser, error)
 	SearchPosts(ctx context.Context, arg sql.NullString) ([]database.Post, error)
 	SearchPostsByDate(ctx context.Context, arg sql.NullString) ([]database.Post, error)
+	SearchPostsByLanguage(ctx context.Context, arg database.SearchPostsByLanguageParams) ([]database.Post, error)
+	SearchPostsBySubstring(ctx context.Context, arg database.SearchPostsBySubstringParams) ([]database.Post, error)
 	SearchReports(ctx context.Context, arg sql.NullString) ([]database.Report, error)
 	SearchReportsByDate(ctx context.Context, arg sql.NullString) ([]database.Report, error)
 }
 
+// ProfileQuerier reads full user profiles, which only admins may see.
+type ProfileQuerier interface {
+	GetUserProfile(ctx context.Context, id uuid.UUID) (database.GetUserProfileRow, error)
+}
+
 // Server represents the gRPC server for the search service.
 type Server interface {
 	pb.SearchServiceServer
+	// UnaryInterceptor and StreamInterceptor authenticate calls with bearer tokens signed with the token secret
+	// and check the caller's roles against the policy.
+	UnaryInterceptor() grpc.UnaryServerInterceptor
+	StreamInterceptor() grpc.StreamServerInterceptor
 }
 
 type server struct {
 	pb.UnimplementedSearchServiceServer
 	db          DatabaseQuerier
 	tokenSecret string
+	synonyms    *synonyms.Store
+	translit    *translit.Transliterator
+	analyzers   *analyzer.Fields
+	versions    *alias.Alias
+	verifier    *verify.Checker
+	cache       *cache.Cache
+	cached      DatabaseQuerier
+	cachedRPCs  map[string]bool
+	coalesce    *coalesce.Group
+	profiles    ProfileQuerier
+	auditLog    AuditQuerier
+	publicRPCs  []string
+	policy      auth.Policy
+	auth        *auth.Authenticator
+}
+
+// Option configures optional server subsystems.
+type Option func(*server)
+
+// WithSynonyms enables query-time synonym expansion for SearchUsers and SearchPosts.
+func WithSynonyms(store *synonyms.Store) Option {
+	return func(s *server) {
+		s.synonyms = store
+	}
+}
+
+// WithTransliterator makes SearchUsers match a query in either Cyrillic or Latin script against the other.
+func WithTransliterator(t *translit.Transliterator) Option {
+	return func(s *server) {
+		s.translit = t
+	}
+}
+
+// WithAnalyzers overrides the analyzers used for searchable fields.
+// With index versions, the analyzers of the live version are used instead.
+func WithAnalyzers(fields *analyzer.Fields) Option {
+	return func(s *server) {
+		s.analyzers = fields
+	}
+}
+
+// WithIndexVersions enables the admin RPCs that build, swap and roll back in-memory index versions.
+func WithIndexVersions(a *alias.Alias) Option {
+	return func(s *server) {
+		s.versions = a
+	}
+}
+
+// WithVerifier enables the VerifyIndex admin RPC.
+func WithVerifier(c *verify.Checker) Option {
+	return func(s *server) {
+		s.verifier = c
+	}
+}
+
+// WithCache serves the searches of the given RPCs, such as "SearchUsers", from c.
+// Other RPCs keep querying the DatabaseQuerier directly.
+func WithCache(c *cache.Cache, rpcs ...string) Option {
+	return func(s *server) {
+		s.cache = c
+		s.cachedRPCs = make(map[string]bool, len(rpcs))
+		for _, rpc := range rpcs {
+			s.cachedRPCs[rpc] = true
+		}
+	}
+}
+
+// WithCoalescing makes concurrent identical searches share one DatabaseQuerier call, grouped by g.
+// A caller that cancels only stops waiting; the shared call is cancelled once every caller has.
+// Cache misses are coalesced too.
+func WithCoalescing(g *coalesce.Group) Option {
+	return func(s *server) {
+		s.coalesce = g
+	}
+}
+
+// WithProfiles enables the admin-only GetUserProfile RPC, reading profiles from db.
+func WithProfiles(db ProfileQuerier) Option {
+	return func(s *server) {
+		s.profiles = db
+	}
+}
+
+// WithAuditLog enables the admin-only ListAuditLog RPC, reading the audit trail from db.
+func WithAuditLog(db AuditQuerier) Option {
+	return func(s *server) {
+		s.auditLog = db
+	}
+}
+
+// WithPublicRPCs lets the given RPCs, such as "SearchUsers", be called without a token.
+// Names containing a "/" are full method names, for RPCs of other services such as reflection.
+func WithPublicRPCs(rpcs ...string) Option {
+	return func(s *server) {
+		s.publicRPCs = append(s.publicRPCs, rpcs...)
+	}
+}
+
+// WithPolicy sets the roles required to call each RPC, see NewPolicy. DefaultPolicy is used without it.
+func WithPolicy(policy auth.Policy) Option {
+	return func(s *server) {
+		s.policy = policy
+	}
 }
 
 // NewServer creates and returns a new instance of the search service server.
 // It requires database queries implementation and a token secret for authentication.
-func NewServer(dbQueries DatabaseQuerier, tokenSecret string) Server {
-	return &server{
+func NewServer(dbQueries DatabaseQuerier, tokenSecret string, opts ...Option) Server {
+	s := &server{
 		db:          dbQueries,
 		tokenSecret: tokenSecret,
+		analyzers:   analyzer.DefaultFields(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.coalesce != nil {
+		s.db = &coalescer{next: s.db, group: s.coalesce}
+	}
+	if s.cache != nil {
+		s.cached = s.cache.Wrap(s.db)
+	}
+
+	publicMethods := make([]string, len(s.publicRPCs))
+	for i, rpc := range s.publicRPCs {
+		publicMethods[i] = fullMethod(rpc)
+	}
+	if s.policy == nil {
+		s.policy, _ = NewPolicy("")
+	}
+	s.auth = auth.NewAuthenticator(s.tokenSecret, auth.WithPublicMethods(publicMethods...), auth.WithPolicy(s.policy))
+	return s
+}
+
+// fullMethod returns the full method name of an RPC of the search service; full names are kept.
+func fullMethod(rpc string) string {
+	if strings.Contains(rpc, "/") {
+		return rpc
+	}
+	return "/" + pb.SearchService_ServiceDesc.ServiceName + "/" + rpc
+}
+
+func (s *server) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return s.auth.UnaryServerInterceptor()
+}
+
+func (s *server) StreamInterceptor() grpc.StreamServerInterceptor {
+	return s.auth.StreamServerInterceptor()
+}
+
+// querier returns the DatabaseQuerier an RPC searches with: the cache if it is enabled for the RPC.
+func (s *server) querier(rpc string) DatabaseQuerier {
+	if s.cache != nil && s.cachedRPCs[rpc] {
+		return s.cached
 	}
+	return s.db
 }
 
 func (s *server) SearchUsers(ctx context.Context, req *pb.SearchUsersRequest) (*pb.SearchUsersResponse, error) {
+	ctx, partial := index.TrackPartial(ctx)
+	db := s.querier("SearchUsers")
+
 	startTime := time.Now()
 	defer func() {
 		endTime := time.Since(startTime)
 		log.Printf("Finished searching in %v", endTime)
 	}()
 
-	searchUserParams := sql.NullString{String: req.GetQuery(), Valid: req.GetQuery() != ""}
+	mode := req.GetMode()
+	if _, ok := pb.UserSearchMode_name[int32(mode)]; !ok {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.InvalidArgument, "unknown search mode - SearchUsers", nil)
+	}
 
-	users, err := s.db.SearchUsers(ctx, searchUserParams)
+	users, err := searchVariants(ctx, s.usernameVariants(req.GetQuery()), db.SearchUsers, userID)
 	if err != nil {
 		return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't get users - SearchUsers", err)
 	}
 
-	responseUsers := make([]*pb.User, len(users))
-	for i, user := range users {
-		responseUsers[i] = &pb.User{
-			Id:               user.ID.String(),
-			CreatedAt:        timestamppb.New(user.CreatedAt),
-			UpdatedAt:        timestamppb.New(user.UpdatedAt),
-			Email:            user.Email,
-			Username:         user.Username,
-			IsPremium:        user.IsPremium,
-			VerificationCode: user.VerificationCode,
-			IsVerified:       user.IsVerified,
+	// Phonetic matches rank below every exact or prefix match.
+	if mode == pb.UserSearchMode_USER_SEARCH_MODE_PHONETIC {
+		if code := s.phoneticCode(req.GetQuery()); code.Valid {
+			soundsLike, err := db.SearchUsersByPhonetic(ctx, code)
+			if err != nil {
+				return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't get users by phonetic code - SearchUsers", err)
+			}
+			users = appendUnseen(users, soundsLike, userID)
 		}
 	}
+
+	responseUsers := make([]*pb.UserSummary, len(users))
+	for i, user := range users {
+		responseUsers[i] = userSummaryToPb(user)
+	}
 	return &pb.SearchUsersResponse{
-		Users: responseUsers,
+		Users:   responseUsers,
+		Partial: partial.Load(),
 	}, nil
 }
 
 func (s *server) SearchUsersByDate(ctx context.Context, req *pb.SearchUsersByDateRequest) (*pb.SearchUsersByDateResponse, error) {
-	searchUsersByDateParams := sql.NullString{String: req.GetQuery(), Valid: req.GetQuery() != ""}
+	ctx, partial := index.TrackPartial(ctx)
+	db := s.querier("SearchUsersByDate")
 
-	users, err := s.db.SearchUsersByDate(ctx, searchUsersByDateParams)
+	users, err := searchVariants(ctx, s.usernameVariants(req.GetQuery()), db.SearchUsersByDate, userID)
 	if err != nil {
 		return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't get users by date", err)
 	}
 
-	responseUsersByDate := make([]*pb.User, len(users))
+	responseUsersByDate := make([]*pb.UserSummary, len(users))
 	for i, user := range users {
-		responseUsersByDate[i] = &pb.User{
-			Id:               user.ID.String(),
-			CreatedAt:        timestamppb.New(user.CreatedAt),
-			UpdatedAt:        timestamppb.New(user.UpdatedAt),
-			Email:            user.Email,
-			Username:         user.Username,
-			IsPremium:        user.IsPremium,
-			VerificationCode: user.VerificationCode,
-			IsVerified:       user.IsVerified,
-		}
+		responseUsersByDate[i] = userSummaryToPb(user)
 	}
 
 	return &pb.SearchUsersByDateResponse{
-		Users: responseUsersByDate,
+		Users:   responseUsersByDate,
+		Partial: partial.Load(),
 	}, nil
 }
 
+// userSummaryToPb converts a user to its public summary, the only form search results return users in.
+func userSummaryToPb(user database.SearchUser) *pb.UserSummary {
+	return &pb.UserSummary{
+		Id:         user.ID.String(),
+		CreatedAt:  timestamppb.New(user.CreatedAt),
+		UpdatedAt:  timestamppb.New(user.UpdatedAt),
+		Username:   user.Username,
+		IsPremium:  user.IsPremium,
+		IsVerified: user.IsVerified,
+	}
+}
+
 func (s *server) SearchPosts(ctx context.Context, req *pb.SearchPostsRequest) (*pb.SearchPostsResponse, error) {
-	searchPostParams := sql.NullString{String: req.GetQuery(), Valid: req.GetQuery() != ""}
+	ctx, partial := index.TrackPartial(ctx)
+	db := s.querier("SearchPosts")
+
+	language := req.GetLanguage()
+	if language != "" && !langdetect.IsSupported(language) {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.InvalidArgument, "unsupported language - SearchPosts", nil)
+	}
 
-	posts, err := s.db.SearchPosts(ctx, searchPostParams)
+	var (
+		posts []database.Post
+		err   error
+	)
+	switch req.GetMode() {
+	case pb.PostSearchMode_POST_SEARCH_MODE_FULL_TEXT:
+		search := db.SearchPosts
+		if language != "" {
+			search = func(ctx context.Context, query sql.NullString) ([]database.Post, error) {
+				return db.SearchPostsByLanguage(ctx, database.SearchPostsByLanguageParams{
+					Language: sql.NullString{String: language, Valid: true},
+					Query:    query,
+				})
+			}
+		}
+		posts, err = searchVariants(ctx, s.fieldVariants(analyzer.FieldPostBody, req.GetQuery()), search, postID)
+	case pb.PostSearchMode_POST_SEARCH_MODE_SUBSTRING:
+		pattern := strings.TrimSpace(req.GetQuery())
+		if err := ngram.Validate(pattern); err != nil {
+			return nil, helper.RespondWithErrorGRPC(ctx, codes.InvalidArgument, err.Error()+" - SearchPosts", err)
+		}
+		posts, err = db.SearchPostsBySubstring(ctx, database.SearchPostsBySubstringParams{
+			Pattern:  pattern,
+			Language: sql.NullString{String: language, Valid: language != ""},
+		})
+	default:
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.InvalidArgument, "unknown search mode - SearchPosts", nil)
+	}
 	if err != nil {
 		return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't find posts by date - SearchPostsByDate", err)
 	}
@@ -122,18 +342,21 @@ func (s *server) SearchPosts(ctx context.Context, req *pb.SearchPostsRequest) (*
 			Likes:     post.Likes,
 			Views:     post.Views,
 			LikedBy:   post.LikedBy,
+			Language:  post.Language.String,
 		}
 	}
 
 	return &pb.SearchPostsResponse{
-		Post: responsePosts,
+		Post:    responsePosts,
+		Partial: partial.Load(),
 	}, nil
 }
 
 func (s *server) SearchPostsByDate(ctx context.Context, req *pb.SearchPostsByDateRequest) (*pb.SearchPostsByDateResponse, error) {
-	searchPostsByDateParams := sql.NullString{String: req.GetQuery(), Valid: req.GetQuery() != ""}
+	ctx, partial := index.TrackPartial(ctx)
+	db := s.querier("SearchPostsByDate")
 
-	posts, err := s.db.SearchPostsByDate(ctx, searchPostsByDateParams)
+	posts, err := searchVariants(ctx, s.fieldVariants(analyzer.FieldPostBody, req.GetQuery()), db.SearchPostsByDate, postID)
 	if err != nil {
 		return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't get posts by date - SearchPostsByDate", err)
 	}
@@ -149,18 +372,21 @@ func (s *server) SearchPostsByDate(ctx context.Context, req *pb.SearchPostsByDat
 			Likes:     post.Likes,
 			Views:     post.Views,
 			LikedBy:   post.LikedBy,
+			Language:  post.Language.String,
 		}
 	}
 
 	return &pb.SearchPostsByDateResponse{
-		Post: responsePostsByDate,
+		Post:    responsePostsByDate,
+		Partial: partial.Load(),
 	}, nil
 }
 
 func (s *server) SearchReports(ctx context.Context, req *pb.SearchReportsRequest) (*pb.SearchReportsResponse, error) {
-	searchReportsParams := sql.NullString{String: req.GetQuery(), Valid: req.GetQuery() != ""}
+	ctx, partial := index.TrackPartial(ctx)
+	db := s.querier("SearchReports")
 
-	reports, err := s.db.SearchReports(ctx, searchReportsParams)
+	reports, err := searchVariants(ctx, s.fieldVariants(analyzer.FieldReportReason, req.GetQuery()), db.SearchReports, reportID)
 	if err != nil {
 		return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't get report - SearchReports", err)
 	}
@@ -176,14 +402,16 @@ func (s *server) SearchReports(ctx context.Context, req *pb.SearchReportsRequest
 	}
 
 	return &pb.SearchReportsResponse{
-		Report: responseReports,
+		Report:  responseReports,
+		Partial: partial.Load(),
 	}, nil
 }
 
 func (s *server) SearchReportsByDate(ctx context.Context, req *pb.SearchReportsByDateRequest) (*pb.SearchReportsByDateResponse, error) {
-	searchReportsByDateParams := sql.NullString{String: req.GetQuery(), Valid: req.GetQuery() != ""}
+	ctx, partial := index.TrackPartial(ctx)
+	db := s.querier("SearchReportsByDate")
 
-	reports, err := s.db.SearchReportsByDate(ctx, searchReportsByDateParams)
+	reports, err := searchVariants(ctx, s.fieldVariants(analyzer.FieldReportReason, req.GetQuery()), db.SearchReportsByDate, reportID)
 	if err != nil {
 		return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't get posts by date - SearchPostsByDate", err)
 	}
@@ -199,6 +427,7 @@ func (s *server) SearchReportsByDate(ctx context.Context, req *pb.SearchReportsB
 	}
 
 	return &pb.SearchReportsByDateResponse{
-		Report: responseReports,
+		Report:  responseReports,
+		Partial: partial.Load(),
 	}, nil
 }
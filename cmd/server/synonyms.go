@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+
+	"github.com/imhasandl/search-service/cmd/helper"
+	pb "github.com/imhasandl/search-service/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *server) ListSynonyms(ctx context.Context, req *pb.ListSynonymsRequest) (*pb.ListSynonymsResponse, error) {
+	if s.synonyms == nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.FailedPrecondition, "synonyms are not configured - ListSynonyms", nil)
+	}
+
+	rules := s.synonyms.Rules()
+	responseRules := make([]*pb.SynonymRule, len(rules))
+	for i, rule := range rules {
+		responseRules[i] = &pb.SynonymRule{
+			Terms:    rule.Terms,
+			Synonyms: rule.Synonyms,
+			OneWay:   rule.OneWay,
+		}
+	}
+
+	var expansions []string
+	if req.GetQuery() != "" {
+		expansions = s.synonyms.Expand(req.GetQuery())
+	}
+
+	return &pb.ListSynonymsResponse{
+		Source:     s.synonyms.Source(),
+		LoadedAt:   timestamppb.New(s.synonyms.LoadedAt()),
+		Rules:      responseRules,
+		Expansions: expansions,
+	}, nil
+}
+
+func (s *server) ReloadSynonyms(ctx context.Context, req *pb.ReloadSynonymsRequest) (*pb.ReloadSynonymsResponse, error) {
+	if s.synonyms == nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.FailedPrecondition, "synonyms are not configured - ReloadSynonyms", nil)
+	}
+
+	if err := s.synonyms.Reload(ctx); err != nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't reload synonyms - ReloadSynonyms", err)
+	}
+
+	return &pb.ReloadSynonymsResponse{
+		Source:    s.synonyms.Source(),
+		LoadedAt:  timestamppb.New(s.synonyms.LoadedAt()),
+		RuleCount: int32(len(s.synonyms.Rules())),
+	}, nil
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/cmd/helper"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/verify"
+	pb "github.com/imhasandl/search-service/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// maxReportedIDs caps the IDs listed per kind of difference in a VerifyIndex response.
+const maxReportedIDs = 100
+
+func (s *server) VerifyIndex(ctx context.Context, req *pb.VerifyIndexRequest) (*pb.VerifyIndexResponse, error) {
+	if s.verifier == nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.FailedPrecondition, "index verification is not configured - VerifyIndex", nil)
+	}
+	if req.GetSampleSize() < 0 {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.InvalidArgument, "sample size can't be negative - VerifyIndex", nil)
+	}
+
+	entities := make([]index.Entity, len(req.GetEntities()))
+	for i, entity := range req.GetEntities() {
+		if !slices.Contains(index.Entities, index.Entity(entity)) {
+			return nil, helper.RespondWithErrorGRPC(ctx, codes.InvalidArgument, "unknown entity - VerifyIndex", nil)
+		}
+		entities[i] = index.Entity(entity)
+	}
+
+	startedAt := time.Now()
+	reports, err := s.verifier.Check(ctx, verify.Options{
+		Entities:   entities,
+		SampleSize: int(req.GetSampleSize()),
+		Repair:     req.GetRepair(),
+	})
+	if err != nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't verify index - VerifyIndex", err)
+	}
+
+	responseEntities := make([]*pb.EntityConsistency, len(reports))
+	for i, report := range reports {
+		responseEntities[i] = &pb.EntityConsistency{
+			Entity:        string(report.Entity),
+			Checked:       int64(report.Checked),
+			MissingCount:  int64(len(report.Missing)),
+			StaleCount:    int64(len(report.Stale)),
+			OrphanedCount: int64(len(report.Orphaned)),
+			Repaired:      int64(report.Repaired),
+			Missing:       reportedIDs(report.Missing),
+			Stale:         reportedIDs(report.Stale),
+			Orphaned:      reportedIDs(report.Orphaned),
+		}
+	}
+
+	return &pb.VerifyIndexResponse{
+		Entities:   responseEntities,
+		StartedAt:  timestamppb.New(startedAt),
+		FinishedAt: timestamppb.Now(),
+	}, nil
+}
+
+func reportedIDs(ids []uuid.UUID) []string {
+	ids = ids[:min(len(ids), maxReportedIDs)]
+	reported := make([]string, len(ids))
+	for i, id := range ids {
+		reported[i] = id.String()
+	}
+	return reported
+}
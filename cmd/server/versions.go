@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/imhasandl/search-service/cmd/helper"
+	"github.com/imhasandl/search-service/internal/alias"
+	pb "github.com/imhasandl/search-service/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *server) ListIndexVersions(ctx context.Context, req *pb.ListIndexVersionsRequest) (*pb.ListIndexVersionsResponse, error) {
+	if s.versions == nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.FailedPrecondition, "index versions are not enabled - ListIndexVersions", nil)
+	}
+
+	versions := s.versions.Versions()
+	responseVersions := make([]*pb.IndexVersion, len(versions))
+	for i, version := range versions {
+		responseVersions[i] = indexVersionToPb(version)
+	}
+
+	return &pb.ListIndexVersionsResponse{
+		Versions: responseVersions,
+	}, nil
+}
+
+func (s *server) BuildIndexVersion(ctx context.Context, req *pb.BuildIndexVersionRequest) (*pb.BuildIndexVersionResponse, error) {
+	if s.versions == nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.FailedPrecondition, "index versions are not enabled - BuildIndexVersion", nil)
+	}
+
+	// The build outlives the request.
+	version, err := s.versions.Build(context.WithoutCancel(ctx), req.GetName(), req.GetAnalyzers())
+	switch {
+	case errors.Is(err, alias.ErrInvalidName):
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.InvalidArgument, "invalid version name - BuildIndexVersion", err)
+	case errors.Is(err, alias.ErrVersionExists):
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.AlreadyExists, "version already exists - BuildIndexVersion", err)
+	case errors.Is(err, alias.ErrInvalidFields):
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.InvalidArgument, "invalid analyzers - BuildIndexVersion", err)
+	case err != nil:
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't build index version - BuildIndexVersion", err)
+	}
+
+	return &pb.BuildIndexVersionResponse{
+		Version: indexVersionToPb(version),
+	}, nil
+}
+
+func (s *server) SwapIndexVersion(ctx context.Context, req *pb.SwapIndexVersionRequest) (*pb.SwapIndexVersionResponse, error) {
+	if s.versions == nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.FailedPrecondition, "index versions are not enabled - SwapIndexVersion", nil)
+	}
+
+	live, previous, err := s.versions.Swap(req.GetName())
+	switch {
+	case errors.Is(err, alias.ErrUnknownVersion):
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.NotFound, "unknown version - SwapIndexVersion", err)
+	case errors.Is(err, alias.ErrNotReady):
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.FailedPrecondition, "version is not ready - SwapIndexVersion", err)
+	case err != nil:
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't swap index version - SwapIndexVersion", err)
+	}
+	s.dropCachedResults(ctx)
+
+	return &pb.SwapIndexVersionResponse{
+		Live:     indexVersionToPb(live),
+		Previous: indexVersionToPb(previous),
+	}, nil
+}
+
+func (s *server) RollbackIndexVersion(ctx context.Context, req *pb.RollbackIndexVersionRequest) (*pb.RollbackIndexVersionResponse, error) {
+	if s.versions == nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.FailedPrecondition, "index versions are not enabled - RollbackIndexVersion", nil)
+	}
+
+	live, previous, err := s.versions.Rollback()
+	if errors.Is(err, alias.ErrNoPrevious) {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.FailedPrecondition, "no previous version - RollbackIndexVersion", err)
+	}
+	if err != nil {
+		return nil, helper.RespondWithErrorGRPC(ctx, codes.Internal, "can't roll back index version - RollbackIndexVersion", err)
+	}
+	s.dropCachedResults(ctx)
+
+	return &pb.RollbackIndexVersionResponse{
+		Live:     indexVersionToPb(live),
+		Previous: indexVersionToPb(previous),
+	}, nil
+}
+
+// dropCachedResults empties the result cache, whose results came from the version that was live before.
+func (s *server) dropCachedResults(ctx context.Context) {
+	if s.cache != nil {
+		s.cache.Resync(ctx)
+	}
+}
+
+func indexVersionToPb(version alias.Version) *pb.IndexVersion {
+	if version.Name == "" {
+		return nil
+	}
+
+	documents := make(map[string]int64, len(version.Documents))
+	for entity, count := range version.Documents {
+		documents[string(entity)] = int64(count)
+	}
+
+	v := &pb.IndexVersion{
+		Name:      version.Name,
+		Status:    string(version.Status),
+		Live:      version.Live,
+		Previous:  version.Previous,
+		Analyzers: version.Analyzers,
+		CreatedAt: timestamppb.New(version.CreatedAt),
+		Documents: documents,
+	}
+	if version.Err != nil {
+		v.Error = version.Err.Error()
+	}
+	if !version.BuiltAt.IsZero() {
+		v.BuiltAt = timestamppb.New(version.BuiltAt)
+	}
+	return v
+}
@@ -0,0 +1,367 @@
+// Package alias serves searches from one of several named in-memory index versions,
+// so a version with new analyzers can be built next to the live one and swapped in
+// without downtime.
+package alias
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/listener"
+)
+
+// Status is the build status of a version.
+type Status string
+
+// Build statuses.
+const (
+	Building Status = "building"
+	Ready    Status = "ready"
+	Failed   Status = "failed"
+)
+
+// Errors returned by Alias.
+var (
+	ErrInvalidName    = errors.New("version names must be lowercase letters, digits, '.', '_' or '-'")
+	ErrInvalidFields  = errors.New("invalid analyzers")
+	ErrVersionExists  = errors.New("version already exists")
+	ErrUnknownVersion = errors.New("unknown version")
+	ErrNotReady       = errors.New("version is not ready")
+	ErrNoPrevious     = errors.New("no previous version to roll back to")
+)
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,62}$`)
+
+// Version describes an index version.
+type Version struct {
+	Name string
+	// Analyzers are the per-field analyzer overrides the version was built with.
+	Analyzers string
+	Status    Status
+	Err       error
+	Live      bool
+	Previous  bool
+	CreatedAt time.Time
+	BuiltAt   time.Time
+	Documents map[index.Entity]int
+}
+
+type version struct {
+	name      string
+	analyzers string
+	index     *index.Index
+	applier   *listener.IndexApplier
+	status    Status
+	err       error
+	createdAt time.Time
+	builtAt   time.Time
+	// pending holds the changes that arrive while the version is loading.
+	// They are applied once loading is done, so no row is left at a version read before the change.
+	pending []listener.Change
+}
+
+// Alias points at the live index version and keeps the previous one for rollback.
+// It implements the search methods of the server's DatabaseQuerier by delegating to
+// the live version, and listener.Applier by applying every change to every version.
+type Alias struct {
+	db           listener.RowStore
+	registry     *analyzer.Registry
+	batchSize    int32
+	indexOptions []index.Option
+
+	mu       sync.Mutex
+	versions map[string]*version
+	live     string
+	previous string
+
+	current atomic.Pointer[index.Index]
+}
+
+// New creates an alias serving ix, which was built with the given analyzer overrides, as version name.
+// New versions are created with opts.
+func New(db listener.RowStore, registry *analyzer.Registry, batchSize int32, name, analyzers string, ix *index.Index, opts ...index.Option) (*Alias, error) {
+	if !validName.MatchString(name) {
+		return nil, ErrInvalidName
+	}
+
+	a := &Alias{
+		db:           db,
+		registry:     registry,
+		batchSize:    batchSize,
+		indexOptions: opts,
+		versions:     make(map[string]*version),
+		live:         name,
+	}
+	now := time.Now()
+	a.versions[name] = &version{
+		name:      name,
+		analyzers: analyzers,
+		index:     ix,
+		applier:   &listener.IndexApplier{DB: db, Index: ix, BatchSize: batchSize},
+		status:    Ready,
+		createdAt: now,
+		builtAt:   now,
+	}
+	a.current.Store(ix)
+	return a, nil
+}
+
+// Live returns the index searches are served from.
+func (a *Alias) Live() *index.Index {
+	return a.current.Load()
+}
+
+// Analyzers returns the analyzers of the live version. They are swapped together with its index,
+// so queries are always analyzed like the postings they are matched against.
+func (a *Alias) Analyzers() *analyzer.Fields {
+	return a.Live().Fields()
+}
+
+// Build starts loading a new version with the given analyzer overrides in the background.
+// The live version keeps serving searches until the new one is swapped in.
+func (a *Alias) Build(ctx context.Context, name, analyzers string) (Version, error) {
+	if !validName.MatchString(name) {
+		return Version{}, ErrInvalidName
+	}
+	fields, err := analyzer.NewFields(a.registry, analyzers)
+	if err != nil {
+		return Version{}, fmt.Errorf("%w: %w", ErrInvalidFields, err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.versions[name]; ok {
+		return Version{}, ErrVersionExists
+	}
+	ix := index.New(fields, a.indexOptions...)
+	v := &version{
+		name:      name,
+		analyzers: analyzers,
+		index:     ix,
+		applier:   &listener.IndexApplier{DB: a.db, Index: ix, BatchSize: a.batchSize},
+		status:    Building,
+		createdAt: time.Now(),
+	}
+	a.versions[name] = v
+
+	go a.build(ctx, v)
+	return a.describe(v), nil
+}
+
+func (a *Alias) build(ctx context.Context, v *version) {
+	log.Printf("Building search index version %s", v.name)
+	err := index.Load(ctx, a.db, v.index, a.batchSize)
+
+	for err == nil {
+		a.mu.Lock()
+		pending := v.pending
+		v.pending = nil
+		if len(pending) == 0 {
+			v.status = Ready
+			v.builtAt = time.Now()
+			a.mu.Unlock()
+			log.Printf("Search index version %s is ready", v.name)
+			return
+		}
+		a.mu.Unlock()
+
+		for _, change := range pending {
+			if err = v.applier.Apply(ctx, change); err != nil {
+				break
+			}
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	v.status = Failed
+	v.err = err
+	v.pending = nil
+	log.Printf("Error building search index version %s: %v", v.name, err)
+}
+
+// Swap atomically makes the named ready version live. The version it replaces becomes the
+// previous version; other versions that are neither live, previous nor building are dropped.
+func (a *Alias) Swap(name string) (live, previous Version, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	v, ok := a.versions[name]
+	if !ok {
+		return Version{}, Version{}, ErrUnknownVersion
+	}
+	if v.status != Ready {
+		return Version{}, Version{}, ErrNotReady
+	}
+	if name != a.live {
+		a.previous, a.live = a.live, name
+		a.current.Store(v.index)
+		a.dropUnused()
+		log.Printf("Search index version %s is live, %s kept for rollback", a.live, a.previous)
+	}
+	return a.describe(v), a.describePrevious(), nil
+}
+
+// Rollback makes the previous version live again.
+func (a *Alias) Rollback() (live, previous Version, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.previous == "" {
+		return Version{}, Version{}, ErrNoPrevious
+	}
+	a.live, a.previous = a.previous, a.live
+	v := a.versions[a.live]
+	a.current.Store(v.index)
+	log.Printf("Rolled search index back to version %s", a.live)
+	return a.describe(v), a.describePrevious(), nil
+}
+
+// Versions lists every version, live first, then by creation time.
+func (a *Alias) Versions() []Version {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	versions := make([]Version, 0, len(a.versions))
+	for _, v := range a.versions {
+		versions = append(versions, a.describe(v))
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if versions[i].Live != versions[j].Live {
+			return versions[i].Live
+		}
+		return versions[i].CreatedAt.Before(versions[j].CreatedAt)
+	})
+	return versions
+}
+
+// Apply applies a change to every version. Versions still loading get it once loading is done.
+func (a *Alias) Apply(ctx context.Context, change listener.Change) error {
+	var errs []error
+	for _, v := range a.readyVersions(&change) {
+		if err := v.applier.Apply(ctx, change); err != nil {
+			errs = append(errs, fmt.Errorf("version %s: %w", v.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Resync rebuilds every ready version from the database.
+func (a *Alias) Resync(ctx context.Context) error {
+	var errs []error
+	for _, v := range a.readyVersions(nil) {
+		if err := v.applier.Resync(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("version %s: %w", v.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readyVersions returns the ready versions. A non-nil change is queued on versions that are building.
+func (a *Alias) readyVersions(change *listener.Change) []*version {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var ready []*version
+	for _, v := range a.versions {
+		switch v.status {
+		case Ready:
+			ready = append(ready, v)
+		case Building:
+			if change != nil {
+				v.pending = append(v.pending, *change)
+			}
+		}
+	}
+	return ready
+}
+
+func (a *Alias) dropUnused() {
+	for name, v := range a.versions {
+		if name != a.live && name != a.previous && v.status != Building {
+			delete(a.versions, name)
+		}
+	}
+}
+
+func (a *Alias) describe(v *version) Version {
+	documents := make(map[index.Entity]int, len(index.Entities))
+	for _, entity := range index.Entities {
+		documents[entity] = v.index.Count(entity)
+	}
+	return Version{
+		Name:      v.name,
+		Analyzers: v.analyzers,
+		Status:    v.status,
+		Err:       v.err,
+		Live:      v.name == a.live,
+		Previous:  v.name == a.previous,
+		CreatedAt: v.createdAt,
+		BuiltAt:   v.builtAt,
+		Documents: documents,
+	}
+}
+
+func (a *Alias) describePrevious() Version {
+	if v, ok := a.versions[a.previous]; ok {
+		return a.describe(v)
+	}
+	return Version{}
+}
+
+// SearchUsers searches the live version.
+func (a *Alias) SearchUsers(ctx context.Context, query sql.NullString) ([]database.SearchUser, error) {
+	return a.Live().SearchUsers(ctx, query)
+}
+
+// SearchUsersByDate searches the live version.
+func (a *Alias) SearchUsersByDate(ctx context.Context, query sql.NullString) ([]database.SearchUser, error) {
+	return a.Live().SearchUsersByDate(ctx, query)
+}
+
+// SearchUsersByPhonetic searches the live version.
+func (a *Alias) SearchUsersByPhonetic(ctx context.Context, code sql.NullString) ([]database.SearchUser, error) {
+	return a.Live().SearchUsersByPhonetic(ctx, code)
+}
+
+// SearchPosts searches the live version.
+func (a *Alias) SearchPosts(ctx context.Context, query sql.NullString) ([]database.Post, error) {
+	return a.Live().SearchPosts(ctx, query)
+}
+
+// SearchPostsByDate searches the live version.
+func (a *Alias) SearchPostsByDate(ctx context.Context, query sql.NullString) ([]database.Post, error) {
+	return a.Live().SearchPostsByDate(ctx, query)
+}
+
+// SearchPostsByLanguage searches the live version.
+func (a *Alias) SearchPostsByLanguage(ctx context.Context, arg database.SearchPostsByLanguageParams) ([]database.Post, error) {
+	return a.Live().SearchPostsByLanguage(ctx, arg)
+}
+
+// SearchPostsBySubstring searches the live version.
+func (a *Alias) SearchPostsBySubstring(ctx context.Context, arg database.SearchPostsBySubstringParams) ([]database.Post, error) {
+	return a.Live().SearchPostsBySubstring(ctx, arg)
+}
+
+// SearchReports searches the live version.
+func (a *Alias) SearchReports(ctx context.Context, query sql.NullString) ([]database.Report, error) {
+	return a.Live().SearchReports(ctx, query)
+}
+
+// SearchReportsByDate searches the live version.
+func (a *Alias) SearchReportsByDate(ctx context.Context, query sql.NullString) ([]database.Report, error) {
+	return a.Live().SearchReportsByDate(ctx, query)
+}
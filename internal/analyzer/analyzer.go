@@ -0,0 +1,185 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Searchable fields. Every field is analyzed by the analyzer configured for it,
+// both when its text is indexed and when a query against it is parsed.
+const (
+	FieldPostBody     = "posts.body"
+	FieldUsername     = "users.username"
+	FieldCommentText  = "comments.comment_text"
+	FieldReportReason = "reports.reason"
+)
+
+// Token is a single term produced by a Tokenizer and rewritten by TokenFilters.
+type Token struct {
+	Term     string
+	Position int
+}
+
+// Tokenizer splits text into tokens.
+type Tokenizer interface {
+	Tokenize(text string) []Token
+}
+
+// TokenFilter rewrites, drops or adds tokens. Filters are chained in order.
+type TokenFilter interface {
+	Filter(tokens []Token) []Token
+}
+
+// Analyzer is a Tokenizer followed by a chain of TokenFilters.
+type Analyzer struct {
+	Name      string
+	Tokenizer Tokenizer
+	Filters   []TokenFilter
+}
+
+// New builds an analyzer from a tokenizer and its filter chain.
+func New(name string, tokenizer Tokenizer, filters ...TokenFilter) *Analyzer {
+	return &Analyzer{Name: name, Tokenizer: tokenizer, Filters: filters}
+}
+
+// Analyze runs text through the tokenizer and every filter.
+func (a *Analyzer) Analyze(text string) []Token {
+	tokens := a.Tokenizer.Tokenize(text)
+	for _, filter := range a.Filters {
+		if len(tokens) == 0 {
+			break
+		}
+		tokens = filter.Filter(tokens)
+	}
+	return tokens
+}
+
+// Terms returns the terms of the analyzed text in order.
+func (a *Analyzer) Terms(text string) []string {
+	tokens := a.Analyze(text)
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = token.Term
+	}
+	return terms
+}
+
+// Normalize returns the analyzed terms joined by single spaces.
+func (a *Analyzer) Normalize(text string) string {
+	return strings.Join(a.Terms(text), " ")
+}
+
+// Registry holds analyzers by name.
+type Registry struct {
+	analyzers map[string]*Analyzer
+}
+
+// NewRegistry returns a registry with the built-in analyzers:
+//
+//	standard  words and emoji, NFKC, lowercase, accent folding, stopwords and stemming for en/ru
+//	standard_no_emoji  like standard with emoji tokens dropped
+//	english   like standard with English stopwords and stemming only
+//	russian   like standard with Russian stopwords and stemming only
+//	uzbek     like standard with Uzbek stopwords and suffix stripping only
+//	simple    words, NFKC and lowercase
+//	keyword   the whole input as one NFKC, lowercased and accent folded term
+func NewRegistry() *Registry {
+	r := &Registry{analyzers: make(map[string]*Analyzer)}
+	r.Register(New("standard", UnicodeTokenizer{}, NFKC{}, Lowercase{}, AccentFold{}, Stopwords{Words: AllStopwords()}, Stemmer{Language: StemByScript}))
+	r.Register(New("standard_no_emoji", UnicodeTokenizer{}, NFKC{}, Lowercase{}, AccentFold{}, EmojiFilter{Drop: true}, Stopwords{Words: AllStopwords()}, Stemmer{Language: StemByScript}))
+	r.Register(New("english", UnicodeTokenizer{}, NFKC{}, Lowercase{}, AccentFold{}, Stopwords{Words: EnglishStopwords}, Stemmer{Language: "en"}))
+	r.Register(New("russian", UnicodeTokenizer{}, NFKC{}, Lowercase{}, Stopwords{Words: RussianStopwords}, Stemmer{Language: "ru"}))
+	r.Register(New("uzbek", UnicodeTokenizer{}, NFKC{}, Lowercase{}, Stopwords{Words: UzbekStopwords}, Stemmer{Language: "uz"}))
+	r.Register(New("simple", UnicodeTokenizer{}, NFKC{}, Lowercase{}))
+	r.Register(New("keyword", KeywordTokenizer{}, NFKC{}, Lowercase{}, AccentFold{}))
+	return r
+}
+
+// Register adds or replaces an analyzer under its name.
+func (r *Registry) Register(a *Analyzer) {
+	r.analyzers[a.Name] = a
+}
+
+// Get returns the analyzer registered under name.
+func (r *Registry) Get(name string) (*Analyzer, bool) {
+	a, ok := r.analyzers[name]
+	return a, ok
+}
+
+// Names returns the registered analyzer names in sorted order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.analyzers))
+	for name := range r.analyzers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultFieldAnalyzers names the analyzer every searchable field uses unless configured otherwise.
+var DefaultFieldAnalyzers = map[string]string{
+	FieldPostBody:     "standard",
+	FieldUsername:     "keyword",
+	FieldCommentText:  "standard",
+	FieldReportReason: "standard",
+}
+
+// Fields maps every searchable field to its analyzer.
+type Fields struct {
+	analyzers map[string]*Analyzer
+}
+
+// NewFields resolves field analyzers from the registry. overrides is a comma separated
+// list of field=analyzer pairs, e.g. "posts.body=english,reports.reason=simple";
+// fields missing from it use DefaultFieldAnalyzers.
+func NewFields(registry *Registry, overrides string) (*Fields, error) {
+	names := make(map[string]string, len(DefaultFieldAnalyzers))
+	for field, name := range DefaultFieldAnalyzers {
+		names[field] = name
+	}
+
+	for _, pair := range strings.Split(overrides, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		field, name, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid field analyzer %q, expected field=analyzer", pair)
+		}
+		field, name = strings.TrimSpace(field), strings.TrimSpace(name)
+		if _, known := DefaultFieldAnalyzers[field]; !known {
+			return nil, fmt.Errorf("unknown searchable field %q", field)
+		}
+		names[field] = name
+	}
+
+	fields := &Fields{analyzers: make(map[string]*Analyzer, len(names))}
+	for field, name := range names {
+		a, ok := registry.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("field %s uses unknown analyzer %q, available: %s", field, name, strings.Join(registry.Names(), ", "))
+		}
+		fields.analyzers[field] = a
+	}
+	return fields, nil
+}
+
+// DefaultFields returns the default field analyzers from the built-in registry.
+func DefaultFields() *Fields {
+	fields, err := NewFields(NewRegistry(), "")
+	if err != nil {
+		panic(err)
+	}
+	return fields
+}
+
+// For returns the analyzer of field. It panics on unknown fields, which are programming errors.
+func (f *Fields) For(field string) *Analyzer {
+	a, ok := f.analyzers[field]
+	if !ok {
+		panic("analyzer: unknown field " + field)
+	}
+	return a
+}
@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Lowercase lowercases every token.
+type Lowercase struct{}
+
+// Filter implements TokenFilter.
+func (Lowercase) Filter(tokens []Token) []Token {
+	for i := range tokens {
+		tokens[i].Term = strings.ToLower(tokens[i].Term)
+	}
+	return tokens
+}
+
+// NFKC applies Unicode NFKC normalisation, folding compatibility forms such as
+// full-width letters and ligatures into their plain equivalents.
+type NFKC struct{}
+
+// Filter implements TokenFilter.
+func (NFKC) Filter(tokens []Token) []Token {
+	for i := range tokens {
+		tokens[i].Term = norm.NFKC.String(tokens[i].Term)
+	}
+	return tokens
+}
+
+// AccentFold strips combining marks from Latin letters, so "café" matches "cafe".
+// Marks on other scripts are kept because they are distinct letters there, e.g. Russian "й".
+type AccentFold struct{}
+
+// Filter implements TokenFilter.
+func (AccentFold) Filter(tokens []Token) []Token {
+	for i := range tokens {
+		tokens[i].Term = foldAccents(tokens[i].Term)
+	}
+	return tokens
+}
+
+func foldAccents(term string) string {
+	decomposed := []rune(norm.NFD.String(term))
+	folded := decomposed[:0]
+	var base rune
+	for _, r := range decomposed {
+		if unicode.Is(unicode.Mn, r) && base < 0x250 {
+			continue
+		}
+		if !unicode.Is(unicode.Mn, r) {
+			base = r
+		}
+		folded = append(folded, r)
+	}
+	return norm.NFC.String(string(folded))
+}
+
+// EmojiFilter drops emoji tokens when Drop is set. It is a no-op otherwise,
+// which keeps emoji searchable as terms of their own.
+type EmojiFilter struct {
+	Drop bool
+}
+
+// Filter implements TokenFilter.
+func (f EmojiFilter) Filter(tokens []Token) []Token {
+	if !f.Drop {
+		return tokens
+	}
+	kept := tokens[:0]
+	for _, token := range tokens {
+		if r := []rune(token.Term); len(r) > 0 && IsEmoji(r[0]) {
+			continue
+		}
+		kept = append(kept, token)
+	}
+	return kept
+}
+
+// Stopwords removes tokens found in Words. Token positions are left untouched
+// so phrase distances still reflect the original text.
+type Stopwords struct {
+	Words map[string]bool
+}
+
+// Filter implements TokenFilter.
+func (f Stopwords) Filter(tokens []Token) []Token {
+	kept := tokens[:0]
+	for _, token := range tokens {
+		if f.Words[token.Term] {
+			continue
+		}
+		kept = append(kept, token)
+	}
+	return kept
+}
+
+// MapFilter rewrites every token with Fn, dropping tokens it maps to "".
+type MapFilter struct {
+	Fn func(string) string
+}
+
+// Filter implements TokenFilter.
+func (f MapFilter) Filter(tokens []Token) []Token {
+	kept := tokens[:0]
+	for _, token := range tokens {
+		if token.Term = f.Fn(token.Term); token.Term != "" {
+			kept = append(kept, token)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,136 @@
+package analyzer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// StemByScript picks the stemmer per token: Cyrillic tokens are stemmed as Russian,
+// Latin tokens as English. Used by analyzers for fields that mix languages.
+const StemByScript = "auto"
+
+// Stemmer reduces tokens to their stems with light, rule based suffix stripping
+// for "en", "ru", "uz" or StemByScript. Unknown languages leave tokens unchanged.
+type Stemmer struct {
+	Language string
+}
+
+// Filter implements TokenFilter.
+func (s Stemmer) Filter(tokens []Token) []Token {
+	for i := range tokens {
+		tokens[i].Term = Stem(s.Language, tokens[i].Term)
+	}
+	return tokens
+}
+
+// Stem returns the stem of a lowercase term in language.
+func Stem(language, term string) string {
+	if language == StemByScript {
+		language = "en"
+		if isCyrillic(term) {
+			language = "ru"
+		}
+	}
+
+	switch language {
+	case "en":
+		return stemEnglish(term)
+	case "ru":
+		return stripSuffix(term, russianEndings, 3)
+	case "uz":
+		return stripSuffix(term, uzbekSuffixes, 3)
+	}
+	return term
+}
+
+func isCyrillic(term string) bool {
+	for _, r := range term {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// stemEnglish implements the plural and -ed/-ing steps of the Porter stemmer,
+// which cover most of what users type into a search box.
+func stemEnglish(term string) string {
+	if len(term) <= 3 || !isASCIIWord(term) {
+		return term
+	}
+
+	switch {
+	case strings.HasSuffix(term, "sses"):
+		term = term[:len(term)-2]
+	case strings.HasSuffix(term, "ies"):
+		term = term[:len(term)-3] + "y"
+	case strings.HasSuffix(term, "ss"), strings.HasSuffix(term, "us"):
+	case strings.HasSuffix(term, "s"):
+		term = term[:len(term)-1]
+	}
+
+	for _, suffix := range []string{"ing", "ed"} {
+		stem, ok := strings.CutSuffix(term, suffix)
+		if !ok || len(stem) < 2 || !hasVowel(stem) {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(stem, "at"), strings.HasSuffix(stem, "bl"), strings.HasSuffix(stem, "iz"):
+			return stem + "e"
+		case endsWithDoubleConsonant(stem):
+			return stem[:len(stem)-1]
+		}
+		return stem
+	}
+
+	if stem, ok := strings.CutSuffix(term, "ly"); ok && len(stem) >= 3 {
+		return stem
+	}
+	return term
+}
+
+func isASCIIWord(term string) bool {
+	for i := 0; i < len(term); i++ {
+		if term[i] < 'a' || term[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func hasVowel(s string) bool {
+	return strings.ContainsAny(s, "aeiouy")
+}
+
+func endsWithDoubleConsonant(s string) bool {
+	n := len(s)
+	if n < 2 || s[n-1] != s[n-2] {
+		return false
+	}
+	return !strings.ContainsRune("aeiouyslz", rune(s[n-1]))
+}
+
+// russianEndings are noun, adjective and verb endings, longest first.
+var russianEndings = []string{
+	"ость", "ями", "ами", "ого", "его", "ому", "ему", "ыми", "ими", "иях", "ать", "ять", "ить",
+	"ах", "ях", "ов", "ев", "ей", "ий", "ый", "ой", "ая", "яя", "ое", "ее", "ую", "юю", "ом", "ем",
+	"ам", "ям", "ие", "ые", "ия", "ию", "ть",
+	"ы", "и", "а", "я", "о", "е", "у", "ю", "ь", "й",
+}
+
+// uzbekSuffixes are the common plural, possessive and case suffixes, longest first.
+var uzbekSuffixes = []string{
+	"larning", "laridan", "larimiz", "lardan", "larga", "larda", "larni", "lari",
+	"ning", "dagi", "imiz", "ingiz", "lar", "dan", "ni", "ga", "da", "im", "ing", "si",
+}
+
+// stripSuffix removes the first matching suffix while at least minStem letters remain.
+func stripSuffix(term string, suffixes []string, minStem int) string {
+	for _, suffix := range suffixes {
+		if stem, ok := strings.CutSuffix(term, suffix); ok && utf8.RuneCountInString(stem) >= minStem {
+			return stem
+		}
+	}
+	return term
+}
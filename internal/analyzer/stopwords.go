@@ -0,0 +1,40 @@
+package analyzer
+
+// EnglishStopwords are dropped by the english and standard analyzers.
+var EnglishStopwords = wordSet(
+	"a", "an", "and", "are", "as", "at", "be", "but", "by", "for", "if", "in", "into", "is", "it",
+	"no", "not", "of", "on", "or", "such", "that", "the", "their", "then", "there", "these",
+	"they", "this", "to", "was", "will", "with",
+)
+
+// RussianStopwords are dropped by the russian and standard analyzers.
+var RussianStopwords = wordSet(
+	"и", "в", "во", "не", "что", "он", "на", "я", "с", "со", "как", "а", "то", "все", "она", "так",
+	"его", "но", "да", "ты", "к", "у", "же", "вы", "за", "бы", "по", "только", "ее", "мне", "было",
+	"вот", "от", "меня", "еще", "нет", "о", "из", "ему", "ли", "если", "или", "это",
+)
+
+// UzbekStopwords are dropped by the uzbek and standard analyzers.
+var UzbekStopwords = wordSet(
+	"va", "bu", "u", "bilan", "uchun", "ham", "esa", "lekin", "yoki", "bir", "har", "shu", "men",
+	"sen", "biz", "siz", "ular", "edi", "emas", "deb", "ва", "бу", "билан", "учун", "ҳам", "эса",
+)
+
+// AllStopwords merges the stopwords of every supported language.
+func AllStopwords() map[string]bool {
+	all := make(map[string]bool)
+	for _, set := range []map[string]bool{EnglishStopwords, RussianStopwords, UzbekStopwords} {
+		for word := range set {
+			all[word] = true
+		}
+	}
+	return all
+}
+
+func wordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
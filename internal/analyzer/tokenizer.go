@@ -0,0 +1,74 @@
+package analyzer
+
+import "unicode"
+
+// UnicodeTokenizer splits text into runs of letters, digits and in-word apostrophes.
+// Every emoji (including ZWJ sequences, skin tones and variation selectors) becomes a token of its own,
+// everything else separates tokens.
+type UnicodeTokenizer struct{}
+
+// Tokenize implements Tokenizer.
+func (UnicodeTokenizer) Tokenize(text string) []Token {
+	var tokens []Token
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case isWordRune(r):
+			start := i
+			for i < len(runes) && (isWordRune(runes[i]) || isInnerApostrophe(runes, i)) {
+				i++
+			}
+			tokens = append(tokens, Token{Term: string(runes[start:i]), Position: len(tokens)})
+		case IsEmoji(r):
+			start := i
+			i++
+			for i < len(runes) && (isEmojiModifier(runes[i]) || (runes[i] == zeroWidthJoiner && i+1 < len(runes) && IsEmoji(runes[i+1]))) {
+				if runes[i] == zeroWidthJoiner {
+					i++
+				}
+				i++
+			}
+			tokens = append(tokens, Token{Term: string(runes[start:i]), Position: len(tokens)})
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+// KeywordTokenizer emits the whole input as a single token, for fields like usernames
+// that are matched as a whole rather than word by word.
+type KeywordTokenizer struct{}
+
+// Tokenize implements Tokenizer.
+func (KeywordTokenizer) Tokenize(text string) []Token {
+	if text == "" {
+		return nil
+	}
+	return []Token{{Term: text}}
+}
+
+const zeroWidthJoiner = '\u200d'
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || r == '_'
+}
+
+// isInnerApostrophe keeps "o'zbek" and "don't" together while still splitting quoted words.
+func isInnerApostrophe(runes []rune, i int) bool {
+	switch runes[i] {
+	case '\'', 'ʻ', 'ʼ', '’':
+		return i > 0 && i+1 < len(runes) && isWordRune(runes[i-1]) && isWordRune(runes[i+1])
+	}
+	return false
+}
+
+// IsEmoji reports whether r is a pictographic symbol.
+func IsEmoji(r rune) bool {
+	return unicode.Is(unicode.So, r) || (r >= 0x1F1E6 && r <= 0x1F1FF)
+}
+
+func isEmojiModifier(r rune) bool {
+	return (r >= 0x1F3FB && r <= 0x1F3FF) || r == '\ufe0f' || r == '\ufe0e' || (r >= 0x1F1E6 && r <= 0x1F1FF)
+}
@@ -0,0 +1,251 @@
+// Package audit records who ran sensitive searches. Entries are queued by an interceptor without
+// blocking the RPC and written to the append-only search_audit_log table in the background.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"path"
+	"sync/atomic"
+	"time"
+
+	"github.com/imhasandl/search-service/internal/auth"
+	"github.com/imhasandl/search-service/internal/database"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// DefaultBufferSize is how many entries can wait to be written before new ones are dropped.
+	DefaultBufferSize = 1024
+	// Anonymous is the actor of calls without a principal.
+	Anonymous = "anonymous"
+
+	// flushTimeout bounds how long entries still queued at shutdown are written for.
+	flushTimeout = 5 * time.Second
+)
+
+// DefaultRPCs are the RPCs audited unless others are configured: the ones reading reports or private user data.
+var DefaultRPCs = []string{"SearchReports", "SearchReportsByDate", "GetUserProfile"}
+
+// Entry is one audited call.
+type Entry struct {
+	Time  time.Time
+	Actor string
+	RPC   string
+	Query string
+	// Filters holds the other non-empty fields of the request, such as a language or search mode.
+	Filters     map[string]string
+	ResultCount int
+	// Status is the gRPC status code of the call, such as "OK" or "PermissionDenied".
+	Status string
+}
+
+// Store is where entries are written.
+type Store interface {
+	InsertAuditEntry(ctx context.Context, arg database.InsertAuditEntryParams) error
+}
+
+// Option configures a Writer.
+type Option func(*Writer)
+
+// WithBufferSize replaces DefaultBufferSize. Sizes below one are ignored: without a buffer every entry
+// would be dropped.
+func WithBufferSize(n int) Option {
+	return func(w *Writer) {
+		if n > 0 {
+			w.entries = make(chan Entry, n)
+		}
+	}
+}
+
+// Writer writes entries to a Store from a buffer, so recording never waits for the database.
+type Writer struct {
+	store   Store
+	entries chan Entry
+
+	dropped atomic.Int64
+	failed  atomic.Int64
+}
+
+// NewWriter creates a Writer to store. Nothing is written until Run is called.
+func NewWriter(store Store, opts ...Option) *Writer {
+	w := &Writer{store: store, entries: make(chan Entry, DefaultBufferSize)}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Record queues entry to be written. It never blocks: when the buffer is full the entry is dropped
+// and counted.
+func (w *Writer) Record(entry Entry) {
+	select {
+	case w.entries <- entry:
+	default:
+		if w.dropped.Add(1)%100 == 1 {
+			log.Printf("Audit log buffer is full, %d entries dropped so far", w.dropped.Load())
+		}
+	}
+}
+
+// Dropped returns the number of entries dropped because the buffer was full.
+func (w *Writer) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Failed returns the number of entries the store failed to write.
+func (w *Writer) Failed() int64 {
+	return w.failed.Load()
+}
+
+// Run writes queued entries until ctx is cancelled, then writes the entries still queued.
+func (w *Writer) Run(ctx context.Context) {
+	for {
+		select {
+		case entry := <-w.entries:
+			w.write(ctx, entry)
+		case <-ctx.Done():
+			w.flush()
+			return
+		}
+	}
+}
+
+// flush writes the entries queued when Run is stopped, giving up after flushTimeout.
+func (w *Writer) flush() {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	for {
+		select {
+		case entry := <-w.entries:
+			w.write(ctx, entry)
+		default:
+			return
+		}
+	}
+}
+
+func (w *Writer) write(ctx context.Context, entry Entry) {
+	filters, err := json.Marshal(entry.Filters)
+	if err != nil || entry.Filters == nil {
+		filters = []byte("{}")
+	}
+	err = w.store.InsertAuditEntry(ctx, database.InsertAuditEntryParams{
+		CreatedAt:   entry.Time,
+		Actor:       entry.Actor,
+		Rpc:         entry.RPC,
+		Query:       entry.Query,
+		Filters:     string(filters),
+		ResultCount: int32(entry.ResultCount),
+		Status:      entry.Status,
+	})
+	if err != nil {
+		w.failed.Add(1)
+		log.Printf("Error writing audit log entry of %s by %s: %v", entry.RPC, entry.Actor, err)
+	}
+}
+
+// UnaryServerInterceptor records the calls of the given RPCs, such as "SearchReports", to w.
+// It must run before authentication, so calls refused there are recorded too. The actor is the
+// subject of the caller's token as soon as it is valid, even if the policy then refuses the call.
+func (w *Writer) UnaryServerInterceptor(rpcs ...string) grpc.UnaryServerInterceptor {
+	audited := make(map[string]bool, len(rpcs))
+	for _, rpc := range rpcs {
+		audited[rpc] = true
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		rpc := path.Base(info.FullMethod)
+		if !audited[rpc] {
+			return handler(ctx, req)
+		}
+
+		started := time.Now()
+		ctx, tracked := auth.TrackPrincipal(ctx)
+		resp, err := handler(ctx, req)
+
+		entry := Entry{
+			Time:   started,
+			Actor:  Anonymous,
+			RPC:    rpc,
+			Status: status.Code(err).String(),
+		}
+		if principal := tracked.Load(); principal != nil {
+			entry.Actor = principal.Subject
+		} else if principal, ok := auth.FromContext(ctx); ok {
+			entry.Actor = principal.Subject
+		}
+		if msg, ok := req.(proto.Message); ok {
+			entry.Query, entry.Filters = requestFields(msg)
+		}
+		if msg, ok := resp.(proto.Message); ok && err == nil {
+			entry.ResultCount = resultCount(msg)
+		}
+		w.Record(entry)
+		return resp, err
+	}
+}
+
+// requestFields returns the query of a request, its "query" or "id" field, and its other
+// non-empty scalar fields as filters.
+func requestFields(msg proto.Message) (query string, filters map[string]string) {
+	msg.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		name := string(fd.Name())
+		if (name == "query" || name == "id") && fd.Kind() == protoreflect.StringKind && !fd.IsList() {
+			query = value.String()
+			return true
+		}
+		if fd.IsMap() || fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+			return true
+		}
+		if filters == nil {
+			filters = make(map[string]string)
+		}
+		if fd.IsList() {
+			list, values := value.List(), make([]string, value.List().Len())
+			for i := range values {
+				values[i] = scalarString(fd, list.Get(i))
+			}
+			b, _ := json.Marshal(values)
+			filters[name] = string(b)
+			return true
+		}
+		filters[name] = scalarString(fd, value)
+		return true
+	})
+	return query, filters
+}
+
+func scalarString(fd protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	if fd.Kind() == protoreflect.EnumKind {
+		if enum := fd.Enum().Values().ByNumber(value.Enum()); enum != nil {
+			return string(enum.Name())
+		}
+	}
+	return value.String()
+}
+
+// resultCount returns the number of results of a response: the entries of its repeated message
+// fields, or one for a response holding a single message such as a profile.
+func resultCount(msg proto.Message) int {
+	count, single := 0, false
+	msg.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if fd.Kind() != protoreflect.MessageKind || fd.IsMap() {
+			return true
+		}
+		if fd.IsList() {
+			count += value.List().Len()
+		} else {
+			single = true
+		}
+		return true
+	})
+	if count == 0 && single {
+		return 1
+	}
+	return count
+}
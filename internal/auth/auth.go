@@ -0,0 +1,63 @@
+// Package auth carries the authenticated caller of an RPC in its context.
+package auth
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Roles, from the least to the most privileged. Every role is granted the rights of the roles before it.
+const (
+	// RoleUser is the role of every authenticated caller.
+	RoleUser = "user"
+	// RoleModerator is the role of moderators allowed to search reports.
+	RoleModerator = "moderator"
+	// RoleAdmin is the role of operators allowed to read private user data and run admin RPCs.
+	RoleAdmin = "admin"
+)
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Subject string
+	Roles   []string
+}
+
+// HasRole reports whether the principal has role.
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewContext returns a context carrying the authenticated principal.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the authenticated principal of ctx; ok is false for unauthenticated calls.
+func FromContext(ctx context.Context) (p Principal, ok bool) {
+	p, ok = ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+type trackedKey struct{}
+
+// TrackPrincipal returns a context in which an Authenticator records the principal of a valid token,
+// even when the policy then refuses the call, and the pointer it records into. It lets interceptors
+// that run before authentication know who made a call.
+func TrackPrincipal(ctx context.Context) (context.Context, *atomic.Pointer[Principal]) {
+	tracked := new(atomic.Pointer[Principal])
+	return context.WithValue(ctx, trackedKey{}, tracked), tracked
+}
+
+// track records p in the pointer of TrackPrincipal, if ctx has one.
+func track(ctx context.Context, p Principal) {
+	if tracked, ok := ctx.Value(trackedKey{}).(*atomic.Pointer[Principal]); ok {
+		tracked.Store(&p)
+	}
+}
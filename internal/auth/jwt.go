@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Claims are the claims of the tokens the service accepts: the user ID is the subject.
+type Claims struct {
+	Roles []string `json:"roles,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Authenticator validates the HS256 bearer tokens sent in the "authorization" metadata of RPCs,
+// puts their Principal in the RPC context and checks it against a Policy.
+type Authenticator struct {
+	secret []byte
+	public map[string]bool
+	policy Policy
+}
+
+// Option configures an Authenticator.
+type Option func(*Authenticator)
+
+// WithPublicMethods lets the given RPCs, full method names such as "/search.SearchService/SearchUsers",
+// be called without a token. Public methods aren't subject to the policy, except that methods it reserves
+// for admins are never public.
+func WithPublicMethods(methods ...string) Option {
+	return func(a *Authenticator) {
+		for _, method := range methods {
+			a.public[method] = true
+		}
+	}
+}
+
+// WithPolicy sets the roles required to call each method. Without one, only public methods can be called.
+func WithPolicy(policy Policy) Option {
+	return func(a *Authenticator) {
+		a.policy = policy
+	}
+}
+
+// NewAuthenticator creates an Authenticator of tokens signed with secret. An empty secret accepts no token.
+func NewAuthenticator(secret string, opts ...Option) *Authenticator {
+	a := &Authenticator{secret: []byte(secret), public: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(a)
+	}
+	for method := range a.public {
+		if a.policy[method] == RoleAdmin {
+			log.Printf("Refusing to make admin-only RPC %s public", method)
+			delete(a.public, method)
+		}
+	}
+	return a
+}
+
+// Authenticate returns ctx with the principal of its bearer token, once the policy allows it to call
+// fullMethod. Calls of public methods without a token are let through unauthenticated; an invalid token
+// is refused everywhere.
+func (a *Authenticator) Authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	token, ok := bearerToken(ctx)
+	if !ok {
+		if a.public[fullMethod] {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+
+	principal, err := a.parse(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid token: "+err.Error())
+	}
+	track(ctx, principal)
+	if !a.public[fullMethod] {
+		if err := a.policy.Authorize(principal, fullMethod); err != nil {
+			return nil, err
+		}
+	}
+	return NewContext(ctx, principal), nil
+}
+
+func (a *Authenticator) parse(token string) (Principal, error) {
+	if len(a.secret) == 0 {
+		return Principal{}, errors.New("no token secret configured")
+	}
+
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return Principal{}, err
+	}
+	if claims.Subject == "" {
+		return Principal{}, errors.New("token has no subject")
+	}
+	return Principal{Subject: claims.Subject, Roles: claims.Roles}, nil
+}
+
+// bearerToken returns the token of the "authorization: Bearer <token>" metadata of ctx.
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, value := range md.Get("authorization") {
+		scheme, token, found := strings.Cut(value, " ")
+		if found && strings.EqualFold(scheme, "bearer") && strings.TrimSpace(token) != "" {
+			return strings.TrimSpace(token), true
+		}
+	}
+	return "", false
+}
+
+// UnaryServerInterceptor authenticates unary RPCs.
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.Authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authenticates streaming RPCs.
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.Authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticatedStream is a ServerStream whose context carries the caller's principal.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
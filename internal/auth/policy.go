@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var roleRanks = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// Satisfies reports whether the principal may act with role: it has role or a more privileged one.
+// Every principal is a user.
+func (p Principal) Satisfies(role string) bool {
+	required, ok := roleRanks[role]
+	if !ok {
+		return false
+	}
+	rank := roleRanks[RoleUser]
+	for _, r := range p.Roles {
+		rank = max(rank, roleRanks[r])
+	}
+	return rank >= required
+}
+
+// Policy maps full method names to the role required to call them.
+type Policy map[string]string
+
+// Authorize returns a PermissionDenied error unless the policy lets principal call fullMethod.
+// Methods missing from the policy can't be called at all.
+func (p Policy) Authorize(principal Principal, fullMethod string) error {
+	role, ok := p[fullMethod]
+	if !ok {
+		return status.Error(codes.PermissionDenied, "no access rule for "+fullMethod)
+	}
+	if !principal.Satisfies(role) {
+		return status.Error(codes.PermissionDenied, fullMethod+" requires the "+role+" role")
+	}
+	return nil
+}
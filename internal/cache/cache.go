@@ -0,0 +1,289 @@
+// Package cache caches search results in front of a search backend. Results are kept in a
+// size-bounded LRU for a per-entity TTL and dropped as soon as a write to their entity is observed
+// on the change feed.
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/listener"
+)
+
+// DefaultTTL is how long results are kept when no TTL is configured for their entity.
+const DefaultTTL = 30 * time.Second
+
+// Backend is the search backend being cached. It has the search methods of the server's DatabaseQuerier.
+type Backend interface {
+	SearchUsers(ctx context.Context, query sql.NullString) ([]database.SearchUser, error)
+	SearchUsersByDate(ctx context.Context, query sql.NullString) ([]database.SearchUser, error)
+	SearchUsersByPhonetic(ctx context.Context, code sql.NullString) ([]database.SearchUser, error)
+	SearchPosts(ctx context.Context, query sql.NullString) ([]database.Post, error)
+	SearchPostsByDate(ctx context.Context, query sql.NullString) ([]database.Post, error)
+	SearchPostsByLanguage(ctx context.Context, arg database.SearchPostsByLanguageParams) ([]database.Post, error)
+	SearchPostsBySubstring(ctx context.Context, arg database.SearchPostsBySubstringParams) ([]database.Post, error)
+	SearchReports(ctx context.Context, query sql.NullString) ([]database.Report, error)
+	SearchReportsByDate(ctx context.Context, query sql.NullString) ([]database.Report, error)
+}
+
+// Option configures a Cache.
+type Option func(*Cache)
+
+// WithTTL sets how long results of searches on entity are kept.
+func WithTTL(entity index.Entity, ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.ttls[entity] = ttl
+	}
+}
+
+// WithClock replaces time.Now, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(c *Cache) {
+		c.now = now
+	}
+}
+
+// Cache holds search results. It implements listener.Applier by invalidating the results of
+// an entity whenever it changes; Wrap puts it in front of a Backend.
+type Cache struct {
+	lru  *lru
+	ttls map[index.Entity]time.Duration
+	now  func() time.Time
+
+	// generations counts the changes of every entity. Results are stored with the generation
+	// read before the search started, so a result that may predate a change is never served.
+	generations map[index.Entity]*atomic.Uint64
+
+	statsMu       sync.Mutex
+	methods       map[string]*counters
+	evictions     atomic.Int64
+	invalidations atomic.Int64
+}
+
+type counters struct {
+	hits, misses atomic.Int64
+}
+
+// MethodStats are the counters of one cached method.
+type MethodStats struct {
+	Method string
+	Hits   int64
+	Misses int64
+}
+
+// Stats are the counters of a Cache.
+type Stats struct {
+	Methods []MethodStats
+	Entries int
+	// Evictions counts results dropped to make room for newer ones.
+	Evictions int64
+	// Invalidations counts the changes and resyncs that invalidated results.
+	Invalidations int64
+}
+
+// New creates a cache of up to size search results.
+func New(size int, opts ...Option) *Cache {
+	c := &Cache{
+		lru:         newLRU(size),
+		ttls:        make(map[index.Entity]time.Duration),
+		now:         time.Now,
+		generations: make(map[index.Entity]*atomic.Uint64, len(index.Entities)),
+		methods:     make(map[string]*counters),
+	}
+	for _, entity := range index.Entities {
+		c.generations[entity] = new(atomic.Uint64)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Querier is a caching decorator of a Backend. It serves repeated searches from its Cache.
+//
+// Results are keyed by the method, which stands for the search mode, and its exact arguments:
+// backends match raw prefixes case-sensitively, so queries that only normalise alike may match
+// different rows. The server also searches the analyzed form of every query, and that search is
+// shared by every query analyzing alike.
+//
+// Cached slices are shared between callers and must not be modified.
+type Querier struct {
+	cache *Cache
+	next  Backend
+}
+
+// Wrap returns a Querier serving the searches of next from c.
+func (c *Cache) Wrap(next Backend) *Querier {
+	return &Querier{cache: c, next: next}
+}
+
+// Apply invalidates every cached result of the changed entity.
+func (c *Cache) Apply(_ context.Context, change listener.Change) error {
+	c.Invalidate(change.Entity)
+	return nil
+}
+
+// Resync drops every cached result, for when changes may have been missed.
+func (c *Cache) Resync(context.Context) error {
+	for _, generation := range c.generations {
+		generation.Add(1)
+	}
+	c.invalidations.Add(1)
+	c.lru.clear()
+	return nil
+}
+
+// Invalidate drops every cached result of entity.
+func (c *Cache) Invalidate(entity index.Entity) {
+	if generation, ok := c.generations[entity]; ok {
+		generation.Add(1)
+		c.invalidations.Add(1)
+	}
+}
+
+// Stats returns the hit and miss counters of every method that was called, by method name,
+// and the counters of the whole cache. Entries includes results that are expired or invalidated
+// but not evicted yet.
+func (c *Cache) Stats() Stats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	stats := Stats{
+		Methods:       make([]MethodStats, 0, len(c.methods)),
+		Entries:       c.lru.len(),
+		Evictions:     c.evictions.Load(),
+		Invalidations: c.invalidations.Load(),
+	}
+	for method, counters := range c.methods {
+		stats.Methods = append(stats.Methods, MethodStats{
+			Method: method,
+			Hits:   counters.hits.Load(),
+			Misses: counters.misses.Load(),
+		})
+	}
+	sort.Slice(stats.Methods, func(i, j int) bool { return stats.Methods[i].Method < stats.Methods[j].Method })
+	return stats
+}
+
+func (c *Cache) counters(method string) *counters {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	cs, ok := c.methods[method]
+	if !ok {
+		cs = new(counters)
+		c.methods[method] = cs
+	}
+	return cs
+}
+
+// cached serves a search from the cache, or runs it and caches its result. Results of searches
+// some index shards didn't answer are passed on but not cached.
+func cached[T any](ctx context.Context, c *Cache, entity index.Entity, method string, key []string, search func(context.Context) ([]T, error)) ([]T, error) {
+	stats := c.counters(method)
+	cacheKey := method + "\x00" + strings.Join(key, "\x00")
+	generation := c.generations[entity].Load()
+
+	if value, ok := c.lru.get(cacheKey, generation, c.now()); ok {
+		stats.hits.Add(1)
+		return value.([]T), nil
+	}
+	stats.misses.Add(1)
+
+	searchCtx, partial := index.TrackPartial(ctx)
+	rows, err := search(searchCtx)
+	if err != nil {
+		return nil, err
+	}
+	if partial.Load() {
+		index.MarkPartial(ctx)
+		return rows, nil
+	}
+
+	ttl, ok := c.ttls[entity]
+	if !ok {
+		ttl = DefaultTTL
+	}
+	if ttl > 0 {
+		c.evictions.Add(int64(c.lru.put(cacheKey, rows, generation, c.now().Add(ttl))))
+	}
+	return rows, nil
+}
+
+// queryKey distinguishes a NULL query from an empty one.
+func queryKey(query sql.NullString) string {
+	return strconv.FormatBool(query.Valid) + ":" + query.String
+}
+
+// SearchUsers is cached.
+func (q *Querier) SearchUsers(ctx context.Context, query sql.NullString) ([]database.SearchUser, error) {
+	return cached(ctx, q.cache, index.Users, "SearchUsers", []string{queryKey(query)}, func(ctx context.Context) ([]database.SearchUser, error) {
+		return q.next.SearchUsers(ctx, query)
+	})
+}
+
+// SearchUsersByDate is cached.
+func (q *Querier) SearchUsersByDate(ctx context.Context, query sql.NullString) ([]database.SearchUser, error) {
+	return cached(ctx, q.cache, index.Users, "SearchUsersByDate", []string{queryKey(query)}, func(ctx context.Context) ([]database.SearchUser, error) {
+		return q.next.SearchUsersByDate(ctx, query)
+	})
+}
+
+// SearchUsersByPhonetic is cached.
+func (q *Querier) SearchUsersByPhonetic(ctx context.Context, code sql.NullString) ([]database.SearchUser, error) {
+	return cached(ctx, q.cache, index.Users, "SearchUsersByPhonetic", []string{queryKey(code)}, func(ctx context.Context) ([]database.SearchUser, error) {
+		return q.next.SearchUsersByPhonetic(ctx, code)
+	})
+}
+
+// SearchPosts is cached.
+func (q *Querier) SearchPosts(ctx context.Context, query sql.NullString) ([]database.Post, error) {
+	return cached(ctx, q.cache, index.Posts, "SearchPosts", []string{queryKey(query)}, func(ctx context.Context) ([]database.Post, error) {
+		return q.next.SearchPosts(ctx, query)
+	})
+}
+
+// SearchPostsByDate is cached.
+func (q *Querier) SearchPostsByDate(ctx context.Context, query sql.NullString) ([]database.Post, error) {
+	return cached(ctx, q.cache, index.Posts, "SearchPostsByDate", []string{queryKey(query)}, func(ctx context.Context) ([]database.Post, error) {
+		return q.next.SearchPostsByDate(ctx, query)
+	})
+}
+
+// SearchPostsByLanguage is cached per query and language.
+func (q *Querier) SearchPostsByLanguage(ctx context.Context, arg database.SearchPostsByLanguageParams) ([]database.Post, error) {
+	key := []string{queryKey(arg.Query), queryKey(arg.Language)}
+	return cached(ctx, q.cache, index.Posts, "SearchPostsByLanguage", key, func(ctx context.Context) ([]database.Post, error) {
+		return q.next.SearchPostsByLanguage(ctx, arg)
+	})
+}
+
+// SearchPostsBySubstring is cached per pattern and language.
+func (q *Querier) SearchPostsBySubstring(ctx context.Context, arg database.SearchPostsBySubstringParams) ([]database.Post, error) {
+	key := []string{arg.Pattern, queryKey(arg.Language)}
+	return cached(ctx, q.cache, index.Posts, "SearchPostsBySubstring", key, func(ctx context.Context) ([]database.Post, error) {
+		return q.next.SearchPostsBySubstring(ctx, arg)
+	})
+}
+
+// SearchReports is cached.
+func (q *Querier) SearchReports(ctx context.Context, query sql.NullString) ([]database.Report, error) {
+	return cached(ctx, q.cache, index.Reports, "SearchReports", []string{queryKey(query)}, func(ctx context.Context) ([]database.Report, error) {
+		return q.next.SearchReports(ctx, query)
+	})
+}
+
+// SearchReportsByDate is cached.
+func (q *Querier) SearchReportsByDate(ctx context.Context, query sql.NullString) ([]database.Report, error) {
+	return cached(ctx, q.cache, index.Reports, "SearchReportsByDate", []string{queryKey(query)}, func(ctx context.Context) ([]database.Report, error) {
+		return q.next.SearchReportsByDate(ctx, query)
+	})
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lru is a size-bounded least recently used map of search results.
+type lru struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List // front is most recently used
+}
+
+type entry struct {
+	key        string
+	value      any
+	generation uint64
+	expiresAt  time.Time
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:    size,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+// get returns the value stored under key if it was stored at the given generation and hasn't expired.
+// Entries that fail either check are dropped.
+func (c *lru) get(key string, generation uint64, now time.Time) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := element.Value.(*entry)
+	if e.generation != generation || !now.Before(e.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return e.value, true
+}
+
+// put stores value under key and reports how many entries were evicted to make room.
+func (c *lru) put(key string, value any, generation uint64, expiresAt time.Time) (evicted int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = &entry{key: key, value: value, generation: generation, expiresAt: expiresAt}
+		c.order.MoveToFront(element)
+		return 0
+	}
+
+	c.entries[key] = c.order.PushFront(&entry{key: key, value: value, generation: generation, expiresAt: expiresAt})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+		evicted++
+	}
+	return evicted
+}
+
+func (c *lru) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *lru) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element, c.size)
+	c.order.Init()
+}
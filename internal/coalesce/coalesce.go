@@ -0,0 +1,111 @@
+// Package coalesce lets concurrent identical calls share one execution.
+//
+// Unlike a plain singleflight, the shared call doesn't run with the context of the caller
+// that started it: a caller that gives up only stops waiting, and the call is cancelled
+// when every caller waiting for it has given up.
+package coalesce
+
+import (
+	"context"
+	"sync"
+
+	"github.com/imhasandl/search-service/internal/index"
+)
+
+// Group coalesces calls by key. The zero value is ready to use.
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+
+	value   any
+	err     error
+	partial bool
+}
+
+// Do runs fn once for all concurrent calls with the same key and returns its result to each of them;
+// shared reports whether the result went to more than one caller. fn runs with a context that keeps
+// the values of the first caller's context but none of its deadline or cancellation; it is cancelled
+// once no caller is waiting any more. A caller whose ctx ends stops waiting and gets ctx.Err().
+//
+// If fn's search leaves out index shards, every caller's partial results flag is set.
+func Do[T any](ctx context.Context, g *Group, key string, fn func(ctx context.Context) (T, error)) (result T, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	c, ok := g.calls[key]
+	if !ok {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &call{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = c
+		go g.run(callCtx, key, c, func(ctx context.Context) (any, error) { return fn(ctx) })
+	}
+	c.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		g.mu.Lock()
+		shared = c.waiters > 1
+		g.mu.Unlock()
+
+		if c.partial {
+			index.MarkPartial(ctx)
+		}
+		if c.err != nil {
+			return result, shared, c.err
+		}
+		return c.value.(T), shared, nil
+	case <-ctx.Done():
+		g.leave(key, c)
+		return result, false, ctx.Err()
+	}
+}
+
+func (g *Group) run(ctx context.Context, key string, c *call, fn func(ctx context.Context) (any, error)) {
+	ctx, partial := index.TrackPartial(ctx)
+	c.value, c.err = fn(ctx)
+	c.partial = partial.Load()
+
+	g.mu.Lock()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+	c.cancel()
+	close(c.done)
+}
+
+// leave removes a waiter of c, cancelling the call when it was the last one. A cancelled call is
+// forgotten at once, so later callers start a new one instead of sharing its cancellation error.
+func (g *Group) leave(key string, c *call) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c.waiters--
+	if c.waiters > 0 {
+		return
+	}
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	c.cancel()
+}
+
+// Waiters returns the number of callers waiting for a call to finish, over every key.
+func (g *Group) Waiters() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	waiters := 0
+	for _, c := range g.calls {
+		waiters += c.waiters
+	}
+	return waiters
+}
@@ -5,6 +5,8 @@
 package database
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -44,6 +46,7 @@ type Post struct {
 	Likes     int32
 	Views     int32
 	LikedBy   []string
+	Language  sql.NullString
 }
 
 type RefreshToken struct {
@@ -60,6 +63,53 @@ type Report struct {
 	Reason     string
 }
 
+type SearchAuditLog struct {
+	ID          int64
+	CreatedAt   time.Time
+	Actor       string
+	Rpc         string
+	Query       string
+	Filters     json.RawMessage
+	ResultCount int32
+	Status      string
+}
+
+type SearchOutbox struct {
+	ID        int64
+	Txid      int64
+	TableName string
+	Op        string
+	RowID     uuid.UUID
+	CreatedAt time.Time
+}
+
+type SearchOutboxCheckpoint struct {
+	Consumer  string
+	LastTxid  int64
+	LastID    int64
+	UpdatedAt time.Time
+}
+
+type SearchSynonym struct {
+	ID        int32
+	Terms     []string
+	Synonyms  []string
+	OneWay    bool
+	CreatedAt time.Time
+}
+
+type SearchUser struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Username         string
+	IsPremium        bool
+	IsVerified       bool
+	UsernameLatin    sql.NullString
+	UsernameCyrillic sql.NullString
+	UsernamePhonetic sql.NullString
+}
+
 type User struct {
 	ID                     uuid.UUID
 	CreatedAt              time.Time
@@ -73,4 +123,7 @@ type User struct {
 	VerificationCode       int32
 	VerificationExpireTime time.Time
 	IsVerified             bool
+	UsernameLatin          sql.NullString
+	UsernameCyrillic       sql.NullString
+	UsernamePhonetic       sql.NullString
 }
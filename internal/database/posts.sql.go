@@ -9,12 +9,127 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 
+const countPostsAfter = `-- name: CountPostsAfter :one
+SELECT COUNT(*) FROM posts
+WHERE id > $1
+`
+
+func (q *Queries) CountPostsAfter(ctx context.Context, id uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostsAfter, id)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getPostByID = `-- name: GetPostByID :one
+SELECT id, created_at, updated_at, posted_by, body, likes, views, liked_by, language FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) GetPostByID(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByID, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.PostedBy,
+		&i.Body,
+		&i.Likes,
+		&i.Views,
+		pq.Array(&i.LikedBy),
+		&i.Language,
+	)
+	return i, err
+}
+
+const listPostsAfter = `-- name: ListPostsAfter :many
+SELECT id, created_at, updated_at, posted_by, body, likes, views, liked_by, language FROM posts
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListPostsAfterParams struct {
+	ID    uuid.UUID
+	Limit int32
+}
+
+func (q *Queries) ListPostsAfter(ctx context.Context, arg ListPostsAfterParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, listPostsAfter, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.PostedBy,
+			&i.Body,
+			&i.Likes,
+			&i.Views,
+			pq.Array(&i.LikedBy),
+			&i.Language,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostsWithoutLanguage = `-- name: ListPostsWithoutLanguage :many
+SELECT id, body FROM posts
+WHERE language IS NULL
+LIMIT $1
+`
+
+type ListPostsWithoutLanguageRow struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) ListPostsWithoutLanguage(ctx context.Context, limit int32) ([]ListPostsWithoutLanguageRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPostsWithoutLanguage, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPostsWithoutLanguageRow
+	for rows.Next() {
+		var i ListPostsWithoutLanguageRow
+		if err := rows.Scan(&i.ID, &i.Body); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const searchPosts = `-- name: SearchPosts :many
-SELECT id, created_at, updated_at, posted_by, body, likes, views, liked_by FROM posts
-WHERE body LIKE $1 || '%'
+SELECT id, created_at, updated_at, posted_by, body, likes, views, liked_by, language FROM posts
+WHERE body LIKE like_escape($1) || '%'
+   OR to_tsvector(search_text_config(language), body) @@ search_tsquery($1)
 `
 
 func (q *Queries) SearchPosts(ctx context.Context, dollar_1 sql.NullString) ([]Post, error) {
@@ -35,6 +150,7 @@ func (q *Queries) SearchPosts(ctx context.Context, dollar_1 sql.NullString) ([]P
 			&i.Likes,
 			&i.Views,
 			pq.Array(&i.LikedBy),
+			&i.Language,
 		); err != nil {
 			return nil, err
 		}
@@ -50,8 +166,9 @@ func (q *Queries) SearchPosts(ctx context.Context, dollar_1 sql.NullString) ([]P
 }
 
 const searchPostsByDate = `-- name: SearchPostsByDate :many
-SELECT id, created_at, updated_at, posted_by, body, likes, views, liked_by FROM posts
-WHERE body LIKE $1 || '%'
+SELECT id, created_at, updated_at, posted_by, body, likes, views, liked_by, language FROM posts
+WHERE body LIKE like_escape($1) || '%'
+   OR to_tsvector(search_text_config(language), body) @@ search_tsquery($1)
 ORDER BY created_at
 `
 
@@ -73,6 +190,52 @@ func (q *Queries) SearchPostsByDate(ctx context.Context, dollar_1 sql.NullString
 			&i.Likes,
 			&i.Views,
 			pq.Array(&i.LikedBy),
+			&i.Language,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchPostsByLanguage = `-- name: SearchPostsByLanguage :many
+SELECT id, created_at, updated_at, posted_by, body, likes, views, liked_by, language FROM posts
+WHERE language = $1
+  AND (body LIKE like_escape($2::text) || '%'
+   OR to_tsvector(search_text_config(language), body) @@ search_tsquery($2::text))
+`
+
+type SearchPostsByLanguageParams struct {
+	Language sql.NullString
+	Query    sql.NullString
+}
+
+func (q *Queries) SearchPostsByLanguage(ctx context.Context, arg SearchPostsByLanguageParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, searchPostsByLanguage, arg.Language, arg.Query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.PostedBy,
+			&i.Body,
+			&i.Likes,
+			&i.Views,
+			pq.Array(&i.LikedBy),
+			&i.Language,
 		); err != nil {
 			return nil, err
 		}
@@ -86,3 +249,64 @@ func (q *Queries) SearchPostsByDate(ctx context.Context, dollar_1 sql.NullString
 	}
 	return items, nil
 }
+
+const searchPostsBySubstring = `-- name: SearchPostsBySubstring :many
+SELECT id, created_at, updated_at, posted_by, body, likes, views, liked_by, language FROM posts
+WHERE body ILIKE '%' || like_escape($1::text) || '%'
+  AND ($2::text IS NULL OR language = $2)
+ORDER BY created_at DESC, id
+`
+
+type SearchPostsBySubstringParams struct {
+	Pattern  string
+	Language sql.NullString
+}
+
+func (q *Queries) SearchPostsBySubstring(ctx context.Context, arg SearchPostsBySubstringParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, searchPostsBySubstring, arg.Pattern, arg.Language)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.PostedBy,
+			&i.Body,
+			&i.Likes,
+			&i.Views,
+			pq.Array(&i.LikedBy),
+			&i.Language,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPostLanguage = `-- name: SetPostLanguage :exec
+UPDATE posts
+SET language = $2
+WHERE id = $1
+`
+
+type SetPostLanguageParams struct {
+	ID       uuid.UUID
+	Language sql.NullString
+}
+
+func (q *Queries) SetPostLanguage(ctx context.Context, arg SetPostLanguageParams) error {
+	_, err := q.db.ExecContext(ctx, setPostLanguage, arg.ID, arg.Language)
+	return err
+}
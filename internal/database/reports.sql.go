@@ -8,11 +8,84 @@ package database
 import (
 	"context"
 	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countReportsAfter = `-- name: CountReportsAfter :one
+SELECT COUNT(*) FROM reports
+WHERE id > $1
+`
+
+func (q *Queries) CountReportsAfter(ctx context.Context, id uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countReportsAfter, id)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getReportByID = `-- name: GetReportByID :one
+SELECT id, reported_at, reported_by, reason FROM reports
+WHERE id = $1
+`
+
+func (q *Queries) GetReportByID(ctx context.Context, id uuid.UUID) (Report, error) {
+	row := q.db.QueryRowContext(ctx, getReportByID, id)
+	var i Report
+	err := row.Scan(
+		&i.ID,
+		&i.ReportedAt,
+		&i.ReportedBy,
+		&i.Reason,
+	)
+	return i, err
+}
+
+const listReportsAfter = `-- name: ListReportsAfter :many
+SELECT id, reported_at, reported_by, reason FROM reports
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListReportsAfterParams struct {
+	ID    uuid.UUID
+	Limit int32
+}
+
+func (q *Queries) ListReportsAfter(ctx context.Context, arg ListReportsAfterParams) ([]Report, error) {
+	rows, err := q.db.QueryContext(ctx, listReportsAfter, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Report
+	for rows.Next() {
+		var i Report
+		if err := rows.Scan(
+			&i.ID,
+			&i.ReportedAt,
+			&i.ReportedBy,
+			&i.Reason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const searchReports = `-- name: SearchReports :many
 SELECT id, reported_at, reported_by, reason FROM reports
-WHERE reported_by LIKE $1 || '%'
+WHERE reported_by::text LIKE like_escape($1) || '%'
+   OR to_tsvector('simple', reason) @@ plainto_tsquery('simple', $1)
 `
 
 func (q *Queries) SearchReports(ctx context.Context, dollar_1 sql.NullString) ([]Report, error) {
@@ -45,7 +118,8 @@ func (q *Queries) SearchReports(ctx context.Context, dollar_1 sql.NullString) ([
 
 const searchReportsByDate = `-- name: SearchReportsByDate :many
 SELECT id, reported_at, reported_by, reason FROM reports
-WHERE reported_by LIKE $1 || '%'
+WHERE reported_by::text LIKE like_escape($1) || '%'
+   OR to_tsvector('simple', reason) @@ plainto_tsquery('simple', $1)
 ORDER BY reported_at
 `
 
@@ -76,3 +150,14 @@ func (q *Queries) SearchReportsByDate(ctx context.Context, dollar_1 sql.NullStri
 	}
 	return items, nil
 }
+
+const touchReports = `-- name: TouchReports :exec
+UPDATE reports
+SET id = id
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) TouchReports(ctx context.Context, ids []uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, touchReports, pq.Array(ids))
+	return err
+}
@@ -8,37 +8,195 @@ package database
 import (
 	"context"
 	"database/sql"
+	"time"
 
-	"github.com/lib/pq"
+	"github.com/google/uuid"
 )
 
+const countUsersAfter = `-- name: CountUsersAfter :one
+SELECT COUNT(*) FROM users
+WHERE id > $1
+`
+
+func (q *Queries) CountUsersAfter(ctx context.Context, id uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsersAfter, id)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getSearchUserByID = `-- name: GetSearchUserByID :one
+SELECT id, created_at, updated_at, username, is_premium, is_verified, username_latin, username_cyrillic, username_phonetic FROM search_users
+WHERE id = $1
+`
+
+func (q *Queries) GetSearchUserByID(ctx context.Context, id uuid.UUID) (SearchUser, error) {
+	row := q.db.QueryRowContext(ctx, getSearchUserByID, id)
+	var i SearchUser
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Username,
+		&i.IsPremium,
+		&i.IsVerified,
+		&i.UsernameLatin,
+		&i.UsernameCyrillic,
+		&i.UsernamePhonetic,
+	)
+	return i, err
+}
+
+const getUserProfile = `-- name: GetUserProfile :one
+SELECT id, created_at, updated_at, email, username, is_premium, verification_code, is_verified
+FROM users
+WHERE id = $1
+`
+
+type GetUserProfileRow struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Email            string
+	Username         string
+	IsPremium        bool
+	VerificationCode int32
+	IsVerified       bool
+}
+
+func (q *Queries) GetUserProfile(ctx context.Context, id uuid.UUID) (GetUserProfileRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserProfile, id)
+	var i GetUserProfileRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.Username,
+		&i.IsPremium,
+		&i.VerificationCode,
+		&i.IsVerified,
+	)
+	return i, err
+}
+
+const isUserPremium = `-- name: IsUserPremium :one
+SELECT is_premium FROM users
+WHERE id = $1
+`
+
+func (q *Queries) IsUserPremium(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isUserPremium, id)
+	var is_premium bool
+	err := row.Scan(&is_premium)
+	return is_premium, err
+}
+
+const listSearchUsersAfter = `-- name: ListSearchUsersAfter :many
+SELECT id, created_at, updated_at, username, is_premium, is_verified, username_latin, username_cyrillic, username_phonetic FROM search_users
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListSearchUsersAfterParams struct {
+	ID    uuid.UUID
+	Limit int32
+}
+
+func (q *Queries) ListSearchUsersAfter(ctx context.Context, arg ListSearchUsersAfterParams) ([]SearchUser, error) {
+	rows, err := q.db.QueryContext(ctx, listSearchUsersAfter, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchUser
+	for rows.Next() {
+		var i SearchUser
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Username,
+			&i.IsPremium,
+			&i.IsVerified,
+			&i.UsernameLatin,
+			&i.UsernameCyrillic,
+			&i.UsernamePhonetic,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersWithoutTransliteration = `-- name: ListUsersWithoutTransliteration :many
+SELECT id, username FROM users
+WHERE username_latin IS NULL OR username_phonetic IS NULL
+LIMIT $1
+`
+
+type ListUsersWithoutTransliterationRow struct {
+	ID       uuid.UUID
+	Username string
+}
+
+func (q *Queries) ListUsersWithoutTransliteration(ctx context.Context, limit int32) ([]ListUsersWithoutTransliterationRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersWithoutTransliteration, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersWithoutTransliterationRow
+	for rows.Next() {
+		var i ListUsersWithoutTransliterationRow
+		if err := rows.Scan(&i.ID, &i.Username); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const searchUsers = `-- name: SearchUsers :many
-SELECT id, created_at, updated_at, email, password, username, subscribers, subscribed_to, is_premium, verification_code, verification_expire_time, is_verified FROM users
-WHERE username LIKE $1 || '%'
+SELECT id, created_at, updated_at, username, is_premium, is_verified, username_latin, username_cyrillic, username_phonetic FROM search_users
+WHERE username LIKE like_escape($1) || '%'
+   OR username_latin LIKE like_escape($1) || '%'
+   OR username_cyrillic LIKE like_escape($1) || '%'
 `
 
-func (q *Queries) SearchUsers(ctx context.Context, dollar_1 sql.NullString) ([]User, error) {
+func (q *Queries) SearchUsers(ctx context.Context, dollar_1 sql.NullString) ([]SearchUser, error) {
 	rows, err := q.db.QueryContext(ctx, searchUsers, dollar_1)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []User
+	var items []SearchUser
 	for rows.Next() {
-		var i User
+		var i SearchUser
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.Email,
-			&i.Password,
 			&i.Username,
-			pq.Array(&i.Subscribers),
-			pq.Array(&i.SubscribedTo),
 			&i.IsPremium,
-			&i.VerificationCode,
-			&i.VerificationExpireTime,
 			&i.IsVerified,
+			&i.UsernameLatin,
+			&i.UsernameCyrillic,
+			&i.UsernamePhonetic,
 		); err != nil {
 			return nil, err
 		}
@@ -54,33 +212,71 @@ func (q *Queries) SearchUsers(ctx context.Context, dollar_1 sql.NullString) ([]U
 }
 
 const searchUsersByDate = `-- name: SearchUsersByDate :many
-SELECT id, created_at, updated_at, email, password, username, subscribers, subscribed_to, is_premium, verification_code, verification_expire_time, is_verified FROM users
-WHERE username LIKE $1 || '%'
+SELECT id, created_at, updated_at, username, is_premium, is_verified, username_latin, username_cyrillic, username_phonetic FROM search_users
+WHERE username LIKE like_escape($1) || '%'
+   OR username_latin LIKE like_escape($1) || '%'
+   OR username_cyrillic LIKE like_escape($1) || '%'
 ORDER BY created_at
 `
 
-func (q *Queries) SearchUsersByDate(ctx context.Context, dollar_1 sql.NullString) ([]User, error) {
+func (q *Queries) SearchUsersByDate(ctx context.Context, dollar_1 sql.NullString) ([]SearchUser, error) {
 	rows, err := q.db.QueryContext(ctx, searchUsersByDate, dollar_1)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []User
+	var items []SearchUser
+	for rows.Next() {
+		var i SearchUser
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Username,
+			&i.IsPremium,
+			&i.IsVerified,
+			&i.UsernameLatin,
+			&i.UsernameCyrillic,
+			&i.UsernamePhonetic,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchUsersByPhonetic = `-- name: SearchUsersByPhonetic :many
+SELECT id, created_at, updated_at, username, is_premium, is_verified, username_latin, username_cyrillic, username_phonetic FROM search_users
+WHERE username_phonetic = $1
+ORDER BY username, id
+`
+
+func (q *Queries) SearchUsersByPhonetic(ctx context.Context, usernamePhonetic sql.NullString) ([]SearchUser, error) {
+	rows, err := q.db.QueryContext(ctx, searchUsersByPhonetic, usernamePhonetic)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchUser
 	for rows.Next() {
-		var i User
+		var i SearchUser
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.Email,
-			&i.Password,
 			&i.Username,
-			pq.Array(&i.Subscribers),
-			pq.Array(&i.SubscribedTo),
 			&i.IsPremium,
-			&i.VerificationCode,
-			&i.VerificationExpireTime,
 			&i.IsVerified,
+			&i.UsernameLatin,
+			&i.UsernameCyrillic,
+			&i.UsernamePhonetic,
 		); err != nil {
 			return nil, err
 		}
@@ -94,3 +290,21 @@ func (q *Queries) SearchUsersByDate(ctx context.Context, dollar_1 sql.NullString
 	}
 	return items, nil
 }
+
+const setUsernameTransliteration = `-- name: SetUsernameTransliteration :exec
+UPDATE users
+SET username_latin = $2, username_cyrillic = $3, username_phonetic = $4
+WHERE id = $1
+`
+
+type SetUsernameTransliterationParams struct {
+	ID               uuid.UUID
+	UsernameLatin    sql.NullString
+	UsernameCyrillic sql.NullString
+	UsernamePhonetic sql.NullString
+}
+
+func (q *Queries) SetUsernameTransliteration(ctx context.Context, arg SetUsernameTransliterationParams) error {
+	_, err := q.db.ExecContext(ctx, setUsernameTransliteration, arg.ID, arg.UsernameLatin, arg.UsernameCyrillic, arg.UsernamePhonetic)
+	return err
+}
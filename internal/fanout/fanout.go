@@ -0,0 +1,122 @@
+// Package fanout runs one call against several partitions concurrently and merges their ordered results.
+package fanout
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Run calls call(i) for every partition i in [0, n) concurrently and gives each call until timeout to answer;
+// a zero timeout waits for every call. Each call gets a context that is done once its deadline passes,
+// so it can stop working on an answer that is no longer waited for. Results are returned in partition
+// order without those of the calls that missed their deadline, which are counted in timedOut. Answers
+// returned after the deadline passed may have been cut short, so they count as timed out too.
+// Run fails if ctx is done or no call answered.
+func Run[T any](ctx context.Context, n int, timeout time.Duration, call func(ctx context.Context, i int) T) (results []T, timedOut int, err error) {
+	if n == 1 && timeout <= 0 {
+		result := call(ctx, 0)
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		return []T{result}, 0, nil
+	}
+
+	answers := make([]chan answer[T], n)
+	deadlines := make([]context.Context, n)
+	for i := range answers {
+		// Buffered, so calls that answer after their deadline don't block forever.
+		answers[i] = make(chan answer[T], 1)
+		deadlines[i] = ctx
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			deadlines[i], cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		go func(i int) {
+			result := call(deadlines[i], i)
+			answers[i] <- answer[T]{result: result, complete: deadlines[i].Err() == nil}
+		}(i)
+	}
+
+	results = make([]T, 0, n)
+	for i := range answers {
+		var a answer[T]
+		select {
+		case a = <-answers[i]:
+		case <-deadlines[i].Done():
+			// The deadlines expire together, so later calls may have answered while waiting for this one.
+			select {
+			case a = <-answers[i]:
+			default:
+			}
+		}
+		if !a.complete {
+			timedOut++
+			continue
+		}
+		results = append(results, a.result)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, timedOut, err
+	}
+	if len(results) == 0 {
+		return nil, timedOut, fmt.Errorf("all %d partitions timed out: %w", n, context.DeadlineExceeded)
+	}
+	return results, timedOut, nil
+}
+
+// answer is the result of one call. It is complete unless the call's deadline passed before it returned.
+type answer[T any] struct {
+	result   T
+	complete bool
+}
+
+// Merge merges lists that are each ordered by less into one ordered list of at most limit items.
+// A limit of zero or less keeps every item.
+func Merge[T any](lists [][]T, less func(a, b T) bool, limit int) []T {
+	h := &mergeHeap[T]{less: less}
+	total := 0
+	for _, list := range lists {
+		if len(list) > 0 {
+			h.lists = append(h.lists, list)
+			total += len(list)
+		}
+	}
+	if limit > 0 && total > limit {
+		total = limit
+	}
+	heap.Init(h)
+
+	merged := make([]T, 0, total)
+	for len(merged) < total {
+		head := h.lists[0]
+		merged = append(merged, head[0])
+		if len(head) > 1 {
+			h.lists[0] = head[1:]
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return merged
+}
+
+// mergeHeap orders the remaining part of every list by its first item.
+type mergeHeap[T any] struct {
+	lists [][]T
+	less  func(a, b T) bool
+}
+
+func (h *mergeHeap[T]) Len() int           { return len(h.lists) }
+func (h *mergeHeap[T]) Less(i, j int) bool { return h.less(h.lists[i][0], h.lists[j][0]) }
+func (h *mergeHeap[T]) Swap(i, j int)      { h.lists[i], h.lists[j] = h.lists[j], h.lists[i] }
+func (h *mergeHeap[T]) Push(x any)         { h.lists = append(h.lists, x.([]T)) }
+
+func (h *mergeHeap[T]) Pop() any {
+	last := h.lists[len(h.lists)-1]
+	h.lists = h.lists[:len(h.lists)-1]
+	return last
+}
@@ -0,0 +1,233 @@
+package index
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/levenshtein"
+)
+
+// BM25 parameters.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Weights applied to terms that only matched the query term by prefix or by edit distance,
+// so exact matches rank first.
+const (
+	prefixWeight = 0.8
+	fuzzyWeight  = 0.5
+)
+
+// fieldIndex is the inverted index of one text field.
+type fieldIndex struct {
+	analyzer *analyzer.Analyzer
+
+	postings map[string]map[uuid.UUID]int // term -> document -> term frequency
+	docTerms map[uuid.UUID][]string       // distinct terms of every document, for removal
+	docLen   map[uuid.UUID]int
+	totalLen int
+
+	// terms is the sorted term dictionary used for prefix and fuzzy lookups.
+	// Writers only mark it dirty, readers rebuild it under dictMu when they need it.
+	dictMu sync.Mutex
+	terms  []string
+	dirty  bool
+}
+
+func newFieldIndex(a *analyzer.Analyzer) *fieldIndex {
+	return &fieldIndex{
+		analyzer: a,
+		postings: make(map[string]map[uuid.UUID]int),
+		docTerms: make(map[uuid.UUID][]string),
+		docLen:   make(map[uuid.UUID]int),
+	}
+}
+
+// add indexes the analyzed text of a document, replacing what was indexed for it before.
+// extra holds already normalised terms to index verbatim, such as transliterated usernames.
+func (f *fieldIndex) add(id uuid.UUID, text string, extra ...string) {
+	f.remove(id)
+
+	terms := append(f.analyzer.Terms(text), extra...)
+	if len(terms) == 0 {
+		return
+	}
+
+	frequencies := make(map[string]int, len(terms))
+	for _, term := range terms {
+		if term != "" {
+			frequencies[term]++
+		}
+	}
+
+	distinct := make([]string, 0, len(frequencies))
+	for term, tf := range frequencies {
+		docs, ok := f.postings[term]
+		if !ok {
+			docs = make(map[uuid.UUID]int)
+			f.postings[term] = docs
+			f.dirty = true
+		}
+		docs[id] = tf
+		distinct = append(distinct, term)
+	}
+
+	f.docTerms[id] = distinct
+	f.docLen[id] = len(terms)
+	f.totalLen += len(terms)
+}
+
+func (f *fieldIndex) remove(id uuid.UUID) {
+	terms, ok := f.docTerms[id]
+	if !ok {
+		return
+	}
+
+	for _, term := range terms {
+		docs := f.postings[term]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(f.postings, term)
+			f.dirty = true
+		}
+	}
+	f.totalLen -= f.docLen[id]
+	delete(f.docTerms, id)
+	delete(f.docLen, id)
+}
+
+// dictionary returns the sorted term dictionary, rebuilding it if terms were added or removed.
+func (f *fieldIndex) dictionary() []string {
+	f.dictMu.Lock()
+	defer f.dictMu.Unlock()
+
+	if f.dirty || f.terms == nil {
+		f.terms = make([]string, 0, len(f.postings))
+		for term := range f.postings {
+			f.terms = append(f.terms, term)
+		}
+		sort.Strings(f.terms)
+		f.dirty = false
+	}
+	return f.terms
+}
+
+// withPrefix returns every dictionary term starting with prefix.
+func (f *fieldIndex) withPrefix(prefix string) []string {
+	terms := f.dictionary()
+	var matches []string
+	for i := sort.SearchStrings(terms, prefix); i < len(terms) && strings.HasPrefix(terms[i], prefix); i++ {
+		matches = append(matches, terms[i])
+	}
+	return matches
+}
+
+// fuzzy returns every dictionary term within the edit distance allowed for term.
+func (f *fieldIndex) fuzzy(term string) []string {
+	maxEdits := MaxEdits(term)
+	if maxEdits == 0 {
+		return nil
+	}
+
+	var matches []string
+	for _, match := range levenshtein.New(term, maxEdits).Intersect(f.dictionary()) {
+		if match.Term != term {
+			matches = append(matches, match.Term)
+		}
+	}
+	return matches
+}
+
+// search scores documents that contain every query term. The last term also matches
+// as a prefix so results show up while the user is still typing. With fuzzy set,
+// terms also match dictionary terms within a small edit distance.
+// It stops scoring once ctx is done; what it returns then is incomplete and dropped by fanout.Run.
+func (f *fieldIndex) search(ctx context.Context, terms []string, fuzzy bool) map[uuid.UUID]float64 {
+	var scores map[uuid.UUID]float64
+	for i, term := range terms {
+		candidates := map[string]float64{term: 1}
+		if i == len(terms)-1 {
+			for _, t := range f.withPrefix(term) {
+				if _, ok := candidates[t]; !ok {
+					candidates[t] = prefixWeight
+				}
+			}
+		}
+		if fuzzy {
+			for _, t := range f.fuzzy(term) {
+				if _, ok := candidates[t]; !ok {
+					candidates[t] = fuzzyWeight
+				}
+			}
+		}
+
+		termScores := make(map[uuid.UUID]float64)
+		for candidate, weight := range candidates {
+			if ctx.Err() != nil {
+				return scores
+			}
+			for id, score := range f.bm25(candidate) {
+				if s := score * weight; s > termScores[id] {
+					termScores[id] = s
+				}
+			}
+		}
+
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for id := range scores {
+			if s, ok := termScores[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+		if len(scores) == 0 {
+			return scores
+		}
+	}
+	return scores
+}
+
+// bm25 scores every document containing term.
+func (f *fieldIndex) bm25(term string) map[uuid.UUID]float64 {
+	docs := f.postings[term]
+	if len(docs) == 0 {
+		return nil
+	}
+
+	n := float64(len(f.docLen))
+	df := float64(len(docs))
+	idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+	avgLen := float64(f.totalLen) / n
+
+	scores := make(map[uuid.UUID]float64, len(docs))
+	for id, tf := range docs {
+		freq := float64(tf)
+		norm := freq + bm25K1*(1-bm25B+bm25B*float64(f.docLen[id])/avgLen)
+		scores[id] = idf * freq * (bm25K1 + 1) / norm
+	}
+	return scores
+}
+
+// MaxEdits scales the allowed edit distance with the term length:
+// short terms must match exactly, longer ones tolerate one or two typos.
+func MaxEdits(term string) int {
+	switch n := len([]rune(term)); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
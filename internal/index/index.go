@@ -0,0 +1,384 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/fanout"
+)
+
+// Entity names a table whose rows the index holds.
+type Entity string
+
+// Indexed entities, named after their tables.
+const (
+	Users    Entity = "users"
+	Posts    Entity = "posts"
+	Comments Entity = "comments"
+	Reports  Entity = "reports"
+)
+
+// Entities lists every indexed entity.
+var Entities = []Entity{Users, Posts, Comments, Reports}
+
+// Index is an in-process inverted index over users, posts, comments and reports.
+// It implements the same search methods as database.Queries, so the server can
+// use it in place of Postgres.
+//
+// Documents are partitioned across shards by a hash of their ID. A search runs on every
+// shard concurrently and merges the ranked results of each; shards that miss the shard
+// timeout are left out and the search is marked partial (see TrackPartial).
+type Index struct {
+	fields     *analyzer.Fields
+	shardCount int
+	timeout    time.Duration
+	topK       int
+
+	mu     sync.RWMutex
+	shards []*shard
+}
+
+// Option configures an Index.
+type Option func(*Index)
+
+// WithShards partitions the index into n shards. The default is a single shard.
+func WithShards(n int) Option {
+	return func(ix *Index) {
+		ix.shardCount = max(n, 1)
+	}
+}
+
+// WithShardTimeout gives every shard at most d to answer a search. Zero, the default, waits for every shard.
+func WithShardTimeout(d time.Duration) Option {
+	return func(ix *Index) {
+		ix.timeout = d
+	}
+}
+
+// WithTopK returns at most k results per search. Zero, the default, returns every match.
+func WithTopK(k int) Option {
+	return func(ix *Index) {
+		ix.topK = k
+	}
+}
+
+// New creates an empty index that analyzes every field with its configured analyzer.
+func New(fields *analyzer.Fields, opts ...Option) *Index {
+	ix := &Index{fields: fields, shardCount: 1}
+	for _, opt := range opts {
+		opt(ix)
+	}
+	ix.shards = ix.newShards()
+	return ix
+}
+
+// Fields returns the analyzers the index analyzes documents and queries with.
+func (ix *Index) Fields() *analyzer.Fields {
+	return ix.fields
+}
+
+// empty returns an empty index with the same configuration as ix.
+func (ix *Index) empty() *Index {
+	return &Index{
+		fields:     ix.fields,
+		shardCount: ix.shardCount,
+		timeout:    ix.timeout,
+		topK:       ix.topK,
+		shards:     ix.newShards(),
+	}
+}
+
+func (ix *Index) newShards() []*shard {
+	shards := make([]*shard, ix.shardCount)
+	for i := range shards {
+		shards[i] = newShard(ix.fields)
+	}
+	return shards
+}
+
+// current returns the shards searches and writes go to.
+func (ix *Index) current() []*shard {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return ix.shards
+}
+
+// shardFor returns the shard that holds the document with the given ID.
+func (ix *Index) shardFor(id uuid.UUID) *shard {
+	shards := ix.current()
+	h := fnv.New32a()
+	h.Write(id[:])
+	return shards[h.Sum32()%uint32(len(shards))]
+}
+
+// PutUser adds or replaces a user.
+func (ix *Index) PutUser(user database.SearchUser) {
+	ix.shardFor(user.ID).putUser(user)
+}
+
+// PutPost adds or replaces a post.
+func (ix *Index) PutPost(post database.Post) {
+	ix.shardFor(post.ID).putPost(post)
+}
+
+// PutComment adds or replaces a comment.
+func (ix *Index) PutComment(comment database.Comment) {
+	ix.shardFor(comment.ID).putComment(comment)
+}
+
+// PutReport adds or replaces a report.
+func (ix *Index) PutReport(report database.Report) {
+	ix.shardFor(report.ID).putReport(report)
+}
+
+// Delete removes a document. Deleting a missing document is a no-op.
+func (ix *Index) Delete(entity Entity, id uuid.UUID) {
+	ix.shardFor(id).delete(entity, id)
+}
+
+// Count returns how many documents of entity the index holds.
+func (ix *Index) Count(entity Entity) int {
+	n := 0
+	for _, sh := range ix.current() {
+		n += sh.count(entity)
+	}
+	return n
+}
+
+// Lookup returns the stored row of a document: a database.SearchUser, Post, Comment or Report.
+func (ix *Index) Lookup(entity Entity, id uuid.UUID) (any, bool) {
+	return ix.shardFor(id).lookup(entity, id)
+}
+
+// IDs returns the IDs of every document of entity in ascending order, the order Postgres sorts UUIDs in.
+func (ix *Index) IDs(entity Entity) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, sh := range ix.current() {
+		ids = sh.appendIDs(ids, entity)
+	}
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+	return ids
+}
+
+// SearchUsers returns users whose username, or its transliteration, starts with the query.
+// Exact matches rank first, then prefix matches, then usernames within a typo or two.
+func (ix *Index) SearchUsers(ctx context.Context, query sql.NullString) ([]database.SearchUser, error) {
+	less := byScore(userKey)
+	return search(ctx, ix, query, less, func(ctx context.Context, sh *shard, fuzzy bool) ([]hit[database.SearchUser], bool) {
+		scores, fallback := sh.match(ctx, sh.usernames, query.String, fuzzy)
+		return rank(sh.users, scores, less, ix.topK), fallback
+	})
+}
+
+// SearchUsersByDate returns the users SearchUsers matches, oldest first.
+func (ix *Index) SearchUsersByDate(ctx context.Context, query sql.NullString) ([]database.SearchUser, error) {
+	less := byTime(userCreatedAt, userKey)
+	return search(ctx, ix, query, less, func(ctx context.Context, sh *shard, fuzzy bool) ([]hit[database.SearchUser], bool) {
+		scores, fallback := sh.match(ctx, sh.usernames, query.String, fuzzy)
+		return rank(sh.users, scores, less, ix.topK), fallback
+	})
+}
+
+// SearchPosts returns posts containing every query word, ranked by BM25.
+func (ix *Index) SearchPosts(ctx context.Context, query sql.NullString) ([]database.Post, error) {
+	less := byScore(postKey)
+	return search(ctx, ix, query, less, func(ctx context.Context, sh *shard, fuzzy bool) ([]hit[database.Post], bool) {
+		scores, fallback := sh.matchText(ctx, sh.postBodies, query.String, fuzzy, sh.postBodyPrefix)
+		return rank(sh.posts, scores, less, ix.topK), fallback
+	})
+}
+
+// SearchPostsByDate returns the posts SearchPosts matches, oldest first.
+func (ix *Index) SearchPostsByDate(ctx context.Context, query sql.NullString) ([]database.Post, error) {
+	less := byTime(postCreatedAt, postKey)
+	return search(ctx, ix, query, less, func(ctx context.Context, sh *shard, fuzzy bool) ([]hit[database.Post], bool) {
+		scores, fallback := sh.matchText(ctx, sh.postBodies, query.String, fuzzy, sh.postBodyPrefix)
+		return rank(sh.posts, scores, less, ix.topK), fallback
+	})
+}
+
+// SearchPostsByLanguage returns the posts SearchPosts matches that are in the given language.
+func (ix *Index) SearchPostsByLanguage(ctx context.Context, arg database.SearchPostsByLanguageParams) ([]database.Post, error) {
+	less := byScore(postKey)
+	return search(ctx, ix, arg.Query, less, func(ctx context.Context, sh *shard, fuzzy bool) ([]hit[database.Post], bool) {
+		scores, fallback := sh.matchText(ctx, sh.postBodies, arg.Query.String, fuzzy, sh.postBodyPrefix)
+		for id := range scores {
+			if sh.posts[id].Language != arg.Language {
+				delete(scores, id)
+			}
+		}
+		return rank(sh.posts, scores, less, ix.topK), fallback
+	})
+}
+
+// SearchReports returns reports filed by a user whose ID starts with the query,
+// or whose reason contains every query word.
+func (ix *Index) SearchReports(ctx context.Context, query sql.NullString) ([]database.Report, error) {
+	less := byScore(reportKey)
+	return search(ctx, ix, query, less, func(ctx context.Context, sh *shard, fuzzy bool) ([]hit[database.Report], bool) {
+		scores, fallback := sh.matchText(ctx, sh.reasons, query.String, fuzzy, sh.reportedByPrefix)
+		return rank(sh.reports, scores, less, ix.topK), fallback
+	})
+}
+
+// SearchReportsByDate returns the reports SearchReports matches, oldest first.
+func (ix *Index) SearchReportsByDate(ctx context.Context, query sql.NullString) ([]database.Report, error) {
+	less := byTime(reportReportedAt, reportKey)
+	return search(ctx, ix, query, less, func(ctx context.Context, sh *shard, fuzzy bool) ([]hit[database.Report], bool) {
+		scores, fallback := sh.matchText(ctx, sh.reasons, query.String, fuzzy, sh.reportedByPrefix)
+		return rank(sh.reports, scores, less, ix.topK), fallback
+	})
+}
+
+// shardSearch searches one shard, which it holds a read lock on. It returns the shard's best
+// hits in order, and whether nothing matched exactly so fuzzy matching should be tried.
+type shardSearch[T any] func(ctx context.Context, sh *shard, fuzzy bool) (hits []hit[T], fallback bool)
+
+// shardResult is the answer of one shard.
+type shardResult[T any] struct {
+	hits     []hit[T]
+	fallback bool
+}
+
+// search runs a search on every shard and merges the results. Fuzzy matching is only tried,
+// on every shard, when no shard found an exact match, just like on a single index.
+func search[T any](ctx context.Context, ix *Index, query sql.NullString, less func(a, b hit[T]) bool, run shardSearch[T]) ([]T, error) {
+	if !query.Valid {
+		return []T{}, nil
+	}
+
+	shards := ix.current()
+	results, err := fanOut(ctx, ix, shards, run, false)
+	if err != nil {
+		return nil, err
+	}
+
+	fallback := true
+	for _, result := range results {
+		fallback = fallback && result.fallback
+	}
+	if fallback {
+		if results, err = fanOut(ctx, ix, shards, run, true); err != nil {
+			return nil, err
+		}
+	}
+
+	lists := make([][]hit[T], len(results))
+	for i, result := range results {
+		lists[i] = result.hits
+	}
+	hits := fanout.Merge(lists, less, ix.topK)
+
+	docs := make([]T, len(hits))
+	for i, h := range hits {
+		docs[i] = h.doc
+	}
+	return docs, nil
+}
+
+func fanOut[T any](ctx context.Context, ix *Index, shards []*shard, run shardSearch[T], fuzzy bool) ([]shardResult[T], error) {
+	results, timedOut, err := fanout.Run(ctx, len(shards), ix.timeout, func(ctx context.Context, i int) shardResult[T] {
+		sh := shards[i]
+		sh.mu.RLock()
+		defer sh.mu.RUnlock()
+
+		hits, fallback := run(ctx, sh, fuzzy)
+		return shardResult[T]{hits: hits, fallback: fallback}
+	})
+	if timedOut > 0 {
+		MarkPartial(ctx)
+	}
+	return results, err
+}
+
+// partialKey is the context key of the flag set by searches with partial results.
+type partialKey struct{}
+
+// TrackPartial returns a context that records whether a search run with it left out shards
+// that timed out, and the flag it records into.
+func TrackPartial(ctx context.Context) (context.Context, *atomic.Bool) {
+	partial := new(atomic.Bool)
+	return context.WithValue(ctx, partialKey{}, partial), partial
+}
+
+// MarkPartial records in the flag of TrackPartial, if ctx has one, that results are partial.
+// It lets wrappers that track a search themselves pass the flag on.
+func MarkPartial(ctx context.Context) {
+	if partial, ok := ctx.Value(partialKey{}).(*atomic.Bool); ok {
+		partial.Store(true)
+	}
+}
+
+// hit is a matched document with its relevance score.
+type hit[T any] struct {
+	doc   T
+	score float64
+}
+
+// rank returns the documents with the given scores ordered by less, keeping the first limit if limit is positive.
+func rank[T any](docs map[uuid.UUID]T, scores map[uuid.UUID]float64, less func(a, b hit[T]) bool, limit int) []hit[T] {
+	hits := make([]hit[T], 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, hit[T]{doc: docs[id], score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool { return less(hits[i], hits[j]) })
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// byScore orders hits by descending score, breaking ties by key so results are stable.
+func byScore[T any](key func(T) string) func(a, b hit[T]) bool {
+	return func(a, b hit[T]) bool {
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return key(a.doc) < key(b.doc)
+	}
+}
+
+// byTime orders hits from oldest to newest, breaking ties by key.
+func byTime[T any](at func(T) time.Time, key func(T) string) func(a, b hit[T]) bool {
+	return func(a, b hit[T]) bool {
+		ta, tb := at(a.doc), at(b.doc)
+		if !ta.Equal(tb) {
+			return ta.Before(tb)
+		}
+		return key(a.doc) < key(b.doc)
+	}
+}
+
+// byTimeDesc orders hits from newest to oldest, breaking ties by key.
+func byTimeDesc[T any](at func(T) time.Time, key func(T) string) func(a, b hit[T]) bool {
+	return func(a, b hit[T]) bool {
+		ta, tb := at(a.doc), at(b.doc)
+		if !ta.Equal(tb) {
+			return ta.After(tb)
+		}
+		return key(a.doc) < key(b.doc)
+	}
+}
+
+func userKey(u database.SearchUser) string { return u.Username + u.ID.String() }
+
+func postKey(p database.Post) string { return p.ID.String() }
+
+func reportKey(r database.Report) string { return r.ID.String() }
+
+func userCreatedAt(u database.SearchUser) time.Time { return u.CreatedAt }
+
+func postCreatedAt(p database.Post) time.Time { return p.CreatedAt }
+
+func reportReportedAt(r database.Report) time.Time { return r.ReportedAt }
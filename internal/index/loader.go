@@ -0,0 +1,109 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/database"
+)
+
+// Source is the subset of database queries needed to load the index from Postgres.
+type Source interface {
+	ListSearchUsersAfter(ctx context.Context, arg database.ListSearchUsersAfterParams) ([]database.SearchUser, error)
+	ListPostsAfter(ctx context.Context, arg database.ListPostsAfterParams) ([]database.Post, error)
+	ListCommentsAfter(ctx context.Context, arg database.ListCommentsAfterParams) ([]database.Comment, error)
+	ListReportsAfter(ctx context.Context, arg database.ListReportsAfterParams) ([]database.Report, error)
+}
+
+// Load streams every row of every entity from src into ix, batchSize rows at a time in ID order.
+func Load(ctx context.Context, src Source, ix *Index, batchSize int32) error {
+	startTime := time.Now()
+	for _, entity := range Entities {
+		if err := LoadEntity(ctx, src, ix, entity, batchSize); err != nil {
+			return fmt.Errorf("load %s: %w", entity, err)
+		}
+	}
+	log.Printf("Loaded search index in %v: %d users, %d posts, %d comments, %d reports",
+		time.Since(startTime), ix.Count(Users), ix.Count(Posts), ix.Count(Comments), ix.Count(Reports))
+	return nil
+}
+
+// LoadEntity streams every row of one entity from src into ix.
+func LoadEntity(ctx context.Context, src Source, ix *Index, entity Entity, batchSize int32) error {
+	after := uuid.Nil
+	for {
+		var (
+			n    int
+			last uuid.UUID
+			err  error
+		)
+
+		switch entity {
+		case Users:
+			var users []database.SearchUser
+			users, err = src.ListSearchUsersAfter(ctx, database.ListSearchUsersAfterParams{ID: after, Limit: batchSize})
+			for _, user := range users {
+				ix.PutUser(user)
+				last = user.ID
+			}
+			n = len(users)
+		case Posts:
+			var posts []database.Post
+			posts, err = src.ListPostsAfter(ctx, database.ListPostsAfterParams{ID: after, Limit: batchSize})
+			for _, post := range posts {
+				ix.PutPost(post)
+				last = post.ID
+			}
+			n = len(posts)
+		case Comments:
+			var comments []database.Comment
+			comments, err = src.ListCommentsAfter(ctx, database.ListCommentsAfterParams{ID: after, Limit: batchSize})
+			for _, comment := range comments {
+				ix.PutComment(comment)
+				last = comment.ID
+			}
+			n = len(comments)
+		case Reports:
+			var reports []database.Report
+			reports, err = src.ListReportsAfter(ctx, database.ListReportsAfterParams{ID: after, Limit: batchSize})
+			for _, report := range reports {
+				ix.PutReport(report)
+				last = report.ID
+			}
+			n = len(reports)
+		default:
+			return fmt.Errorf("unknown entity %q", entity)
+		}
+
+		if err != nil {
+			return err
+		}
+		if n < int(batchSize) {
+			return nil
+		}
+		after = last
+	}
+}
+
+// Rebuild reloads every entity from src into a fresh index and swaps it in once loading is done,
+// so searches keep being served from the old contents in the meantime.
+func (ix *Index) Rebuild(ctx context.Context, src Source, batchSize int32) error {
+	fresh := ix.empty()
+	if err := Load(ctx, src, fresh, batchSize); err != nil {
+		return err
+	}
+
+	ix.swap(fresh)
+	return nil
+}
+
+// swap replaces the contents of ix with those of fresh.
+func (ix *Index) swap(fresh *Index) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.shards = fresh.shards
+}
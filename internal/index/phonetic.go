@@ -0,0 +1,50 @@
+package index
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/database"
+)
+
+// phoneticIndex maps phonetic codes of usernames to the users having them.
+type phoneticIndex map[string]map[uuid.UUID]struct{}
+
+func (p phoneticIndex) add(id uuid.UUID, code sql.NullString) {
+	if !code.Valid || code.String == "" {
+		return
+	}
+	ids, ok := p[code.String]
+	if !ok {
+		ids = make(map[uuid.UUID]struct{})
+		p[code.String] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func (p phoneticIndex) remove(id uuid.UUID, code sql.NullString) {
+	ids, ok := p[code.String]
+	if !ok {
+		return
+	}
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(p, code.String)
+	}
+}
+
+// SearchUsersByPhonetic returns the users whose username has the given phonetic code, by username.
+func (ix *Index) SearchUsersByPhonetic(ctx context.Context, code sql.NullString) ([]database.SearchUser, error) {
+	if code.String == "" {
+		return []database.SearchUser{}, nil
+	}
+	less := byScore(userKey)
+	return search(ctx, ix, code, less, func(ctx context.Context, sh *shard, fuzzy bool) ([]hit[database.SearchUser], bool) {
+		matches := make(map[uuid.UUID]float64)
+		for id := range sh.phonetics[code.String] {
+			matches[id] = 0
+		}
+		return rank(sh.users, matches, less, ix.topK), false
+	})
+}
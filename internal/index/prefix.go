@@ -0,0 +1,66 @@
+package index
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// prefixIndex finds documents by a prefix of a raw, unanalyzed value, such as the start of a post body.
+// Like the term dictionary, the sorted values are only marked dirty by writers and rebuilt by readers.
+type prefixIndex struct {
+	values map[uuid.UUID]string
+
+	mu     sync.Mutex
+	sorted []prefixEntry
+	dirty  bool
+}
+
+type prefixEntry struct {
+	value string
+	id    uuid.UUID
+}
+
+func newPrefixIndex() *prefixIndex {
+	return &prefixIndex{values: make(map[uuid.UUID]string)}
+}
+
+func (p *prefixIndex) add(id uuid.UUID, value string) {
+	p.values[id] = value
+	p.dirty = true
+}
+
+func (p *prefixIndex) remove(id uuid.UUID) {
+	if _, ok := p.values[id]; ok {
+		delete(p.values, id)
+		p.dirty = true
+	}
+}
+
+// entries returns the values sorted, rebuilding them if documents were added or removed.
+func (p *prefixIndex) entries() []prefixEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.dirty || p.sorted == nil {
+		p.sorted = make([]prefixEntry, 0, len(p.values))
+		for id, value := range p.values {
+			p.sorted = append(p.sorted, prefixEntry{value: value, id: id})
+		}
+		sort.Slice(p.sorted, func(i, j int) bool { return p.sorted[i].value < p.sorted[j].value })
+		p.dirty = false
+	}
+	return p.sorted
+}
+
+// withPrefix returns the documents whose value starts with prefix.
+func (p *prefixIndex) withPrefix(prefix string) []uuid.UUID {
+	entries := p.entries()
+	var ids []uuid.UUID
+	for i := sort.Search(len(entries), func(i int) bool { return entries[i].value >= prefix }); i < len(entries) && strings.HasPrefix(entries[i].value, prefix); i++ {
+		ids = append(ids, entries[i].id)
+	}
+	return ids
+}
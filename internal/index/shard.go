@@ -0,0 +1,216 @@
+package index
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/database"
+)
+
+// shard holds the documents whose IDs hash to it, with the inverted indexes of their fields.
+// Relevance statistics such as document frequencies are per shard.
+type shard struct {
+	mu sync.RWMutex
+
+	users    map[uuid.UUID]database.SearchUser
+	posts    map[uuid.UUID]database.Post
+	comments map[uuid.UUID]database.Comment
+	reports  map[uuid.UUID]database.Report
+
+	usernames   *fieldIndex
+	postBodies  *fieldIndex
+	commentText *fieldIndex
+	reasons     *fieldIndex
+
+	// bodyPrefixes and reporters serve the raw prefix matches of post and report searches.
+	bodyPrefixes *prefixIndex
+	reporters    *prefixIndex
+	// bodyGrams serves substring searches on post bodies.
+	bodyGrams *trigramIndex
+	// phonetics serves phonetic searches on usernames.
+	phonetics phoneticIndex
+}
+
+func newShard(fields *analyzer.Fields) *shard {
+	return &shard{
+		users:        make(map[uuid.UUID]database.SearchUser),
+		posts:        make(map[uuid.UUID]database.Post),
+		comments:     make(map[uuid.UUID]database.Comment),
+		reports:      make(map[uuid.UUID]database.Report),
+		usernames:    newFieldIndex(fields.For(analyzer.FieldUsername)),
+		postBodies:   newFieldIndex(fields.For(analyzer.FieldPostBody)),
+		commentText:  newFieldIndex(fields.For(analyzer.FieldCommentText)),
+		reasons:      newFieldIndex(fields.For(analyzer.FieldReportReason)),
+		bodyPrefixes: newPrefixIndex(),
+		reporters:    newPrefixIndex(),
+		bodyGrams:    newTrigramIndex(),
+		phonetics:    make(phoneticIndex),
+	}
+}
+
+func (sh *shard) putUser(user database.SearchUser) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if old, ok := sh.users[user.ID]; ok {
+		sh.phonetics.remove(old.ID, old.UsernamePhonetic)
+	}
+	sh.users[user.ID] = user
+	sh.phonetics.add(user.ID, user.UsernamePhonetic)
+	sh.usernames.add(user.ID, user.Username, user.UsernameLatin.String, user.UsernameCyrillic.String)
+}
+
+func (sh *shard) putPost(post database.Post) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.posts[post.ID] = post
+	sh.postBodies.add(post.ID, post.Body)
+	sh.bodyPrefixes.add(post.ID, post.Body)
+	sh.bodyGrams.add(post.ID, post.Body)
+}
+
+func (sh *shard) putComment(comment database.Comment) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.comments[comment.ID] = comment
+	sh.commentText.add(comment.ID, comment.CommentText)
+}
+
+func (sh *shard) putReport(report database.Report) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.reports[report.ID] = report
+	sh.reasons.add(report.ID, report.Reason)
+	sh.reporters.add(report.ID, report.ReportedBy.String())
+}
+
+func (sh *shard) delete(entity Entity, id uuid.UUID) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	switch entity {
+	case Users:
+		if old, ok := sh.users[id]; ok {
+			sh.phonetics.remove(id, old.UsernamePhonetic)
+		}
+		delete(sh.users, id)
+		sh.usernames.remove(id)
+	case Posts:
+		delete(sh.posts, id)
+		sh.postBodies.remove(id)
+		sh.bodyPrefixes.remove(id)
+		sh.bodyGrams.remove(id)
+	case Comments:
+		delete(sh.comments, id)
+		sh.commentText.remove(id)
+	case Reports:
+		delete(sh.reports, id)
+		sh.reasons.remove(id)
+		sh.reporters.remove(id)
+	}
+}
+
+func (sh *shard) count(entity Entity) int {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	switch entity {
+	case Users:
+		return len(sh.users)
+	case Posts:
+		return len(sh.posts)
+	case Comments:
+		return len(sh.comments)
+	case Reports:
+		return len(sh.reports)
+	}
+	return 0
+}
+
+func (sh *shard) lookup(entity Entity, id uuid.UUID) (doc any, ok bool) {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	switch entity {
+	case Users:
+		doc, ok = sh.users[id]
+	case Posts:
+		doc, ok = sh.posts[id]
+	case Comments:
+		doc, ok = sh.comments[id]
+	case Reports:
+		doc, ok = sh.reports[id]
+	}
+	return doc, ok
+}
+
+func (sh *shard) appendIDs(ids []uuid.UUID, entity Entity) []uuid.UUID {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	switch entity {
+	case Users:
+		for id := range sh.users {
+			ids = append(ids, id)
+		}
+	case Posts:
+		for id := range sh.posts {
+			ids = append(ids, id)
+		}
+	case Comments:
+		for id := range sh.comments {
+			ids = append(ids, id)
+		}
+	case Reports:
+		for id := range sh.reports {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// match scores documents of a keyword field. The whole normalised query is one term,
+// matched exactly and as a prefix, or by edit distance if fuzzy is set. fallback reports
+// that nothing matched exactly, so a fuzzy search could find more.
+func (sh *shard) match(ctx context.Context, field *fieldIndex, query string, fuzzy bool) (scores map[uuid.UUID]float64, fallback bool) {
+	terms := field.analyzer.Terms(query)
+	if len(terms) == 0 {
+		return nil, false
+	}
+
+	scores = field.search(ctx, terms, fuzzy)
+	return scores, !fuzzy && len(scores) == 0
+}
+
+// matchText scores documents of a full text field. Documents whose raw text starts with the query
+// always match, like the Postgres LIKE search; analyzed matching adds stemmed and out of order
+// matches, or fuzzy matches if fuzzy is set. fallback reports that no analyzed term matched exactly.
+func (sh *shard) matchText(ctx context.Context, field *fieldIndex, query string, fuzzy bool, rawPrefix func(prefix string) []uuid.UUID) (scores map[uuid.UUID]float64, fallback bool) {
+	if terms := field.analyzer.Terms(query); len(terms) > 0 {
+		scores = field.search(ctx, terms, fuzzy)
+		fallback = !fuzzy && len(scores) == 0
+	}
+	if scores == nil {
+		scores = make(map[uuid.UUID]float64)
+	}
+
+	for _, id := range rawPrefix(query) {
+		if _, ok := scores[id]; !ok {
+			scores[id] = 0
+		}
+	}
+	return scores, fallback
+}
+
+func (sh *shard) postBodyPrefix(prefix string) []uuid.UUID {
+	return sh.bodyPrefixes.withPrefix(prefix)
+}
+
+func (sh *shard) reportedByPrefix(prefix string) []uuid.UUID {
+	return sh.reporters.withPrefix(prefix)
+}
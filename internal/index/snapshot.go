@@ -0,0 +1,339 @@
+package index
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/imhasandl/search-service/internal/database"
+)
+
+// SnapshotVersion is the snapshot format version written by this code.
+// Snapshots of other versions are skipped when loading, and those of older versions deleted.
+//
+// Version 2 stores users as database.SearchUser. Version 1 stored full user rows,
+// password hashes and verification codes included, which must not stay on disk.
+const SnapshotVersion = 2
+
+// snapshotMagic starts every snapshot file.
+var snapshotMagic = [8]byte{'S', 'R', 'C', 'H', 'S', 'N', 'A', 'P'}
+
+const snapshotExt = ".snap"
+
+// ErrNoSnapshot is returned when a directory holds no valid snapshot.
+var ErrNoSnapshot = errors.New("no valid snapshot")
+
+// errOldSnapshot is returned for snapshots written in an older format version.
+var errOldSnapshot = errors.New("old snapshot format")
+
+// HighWater marks how far the change feed had been applied to the index.
+// Changes after it must be replayed on top of a snapshot.
+type HighWater struct {
+	Txid int64
+	ID   int64
+}
+
+// SnapshotInfo describes a snapshot.
+type SnapshotInfo struct {
+	Path      string
+	CreatedAt time.Time
+	HighWater HighWater
+	Documents int
+}
+
+// snapshotHeader precedes the payload: magic, format version, payload length and its SHA-256.
+type snapshotHeader struct {
+	Magic    [8]byte
+	Version  uint32
+	Length   uint64
+	Checksum [sha256.Size]byte
+}
+
+// snapshotPayload holds the documents only; postings are rebuilt on load with the
+// configured analyzers, so a snapshot stays valid when analyzers change.
+type snapshotPayload struct {
+	CreatedAt time.Time
+	HighWater HighWater
+	Users     []database.SearchUser
+	Posts     []database.Post
+	Comments  []database.Comment
+	Reports   []database.Report
+}
+
+// WriteSnapshot serialises every document of the index to w.
+func (ix *Index) WriteSnapshot(w io.Writer, highWater HighWater) (SnapshotInfo, error) {
+	payload := ix.snapshotPayload(highWater)
+
+	var body bytes.Buffer
+	if err := gob.NewEncoder(&body).Encode(payload); err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	header := snapshotHeader{
+		Magic:    snapshotMagic,
+		Version:  SnapshotVersion,
+		Length:   uint64(body.Len()),
+		Checksum: sha256.Sum256(body.Bytes()),
+	}
+	if err := binary.Write(w, binary.BigEndian, header); err != nil {
+		return SnapshotInfo{}, err
+	}
+	if _, err := w.Write(body.Bytes()); err != nil {
+		return SnapshotInfo{}, err
+	}
+	return payload.info(), nil
+}
+
+// ReadSnapshot replaces the contents of the index with a snapshot read from r.
+// The index is left untouched if the snapshot is invalid.
+func (ix *Index) ReadSnapshot(r io.Reader) (SnapshotInfo, error) {
+	var header snapshotHeader
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("read header: %w", err)
+	}
+	if header.Magic != snapshotMagic {
+		return SnapshotInfo{}, errors.New("not a snapshot")
+	}
+	if header.Version < SnapshotVersion {
+		return SnapshotInfo{}, fmt.Errorf("%w: version %d", errOldSnapshot, header.Version)
+	}
+	if header.Version != SnapshotVersion {
+		return SnapshotInfo{}, fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+
+	var body bytes.Buffer
+	if _, err := io.Copy(&body, io.LimitReader(r, int64(header.Length))); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("read payload: %w", err)
+	}
+	if uint64(body.Len()) != header.Length {
+		return SnapshotInfo{}, errors.New("truncated snapshot")
+	}
+	if sha256.Sum256(body.Bytes()) != header.Checksum {
+		return SnapshotInfo{}, errors.New("checksum mismatch")
+	}
+
+	var payload snapshotPayload
+	if err := gob.NewDecoder(&body).Decode(&payload); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("decode payload: %w", err)
+	}
+
+	fresh := ix.empty()
+	for _, user := range payload.Users {
+		fresh.PutUser(user)
+	}
+	for _, post := range payload.Posts {
+		fresh.PutPost(post)
+	}
+	for _, comment := range payload.Comments {
+		fresh.PutComment(comment)
+	}
+	for _, report := range payload.Reports {
+		fresh.PutReport(report)
+	}
+	ix.swap(fresh)
+
+	return payload.info(), nil
+}
+
+// SaveSnapshot writes a snapshot file to dir. The file appears atomically,
+// so a crash mid-write never leaves a partial snapshot behind.
+func (ix *Index) SaveSnapshot(dir string, highWater HighWater) (SnapshotInfo, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	tmp, err := os.CreateTemp(dir, "snapshot-*.tmp")
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	info, err := ix.WriteSnapshot(w, highWater)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	info.Path = filepath.Join(dir, fmt.Sprintf("index-%020d%s", info.CreatedAt.UnixNano(), snapshotExt))
+	if err := os.Rename(tmp.Name(), info.Path); err != nil {
+		return SnapshotInfo{}, err
+	}
+	return info, nil
+}
+
+// LoadLatestSnapshot loads the newest valid snapshot in dir. Corrupt snapshots and
+// snapshots of other format versions are logged and skipped in favour of older ones;
+// snapshots of older format versions are deleted.
+func (ix *Index) LoadLatestSnapshot(dir string) (SnapshotInfo, error) {
+	paths, err := snapshotFiles(dir)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	for i := len(paths) - 1; i >= 0; i-- {
+		info, err := ix.loadSnapshotFile(paths[i])
+		if errors.Is(err, errOldSnapshot) {
+			log.Printf("Deleting snapshot %s: %v", paths[i], err)
+			if err := os.Remove(paths[i]); err != nil {
+				log.Printf("Error deleting snapshot %s: %v", paths[i], err)
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("Skipping snapshot %s: %v", paths[i], err)
+			continue
+		}
+		return info, nil
+	}
+	return SnapshotInfo{}, ErrNoSnapshot
+}
+
+func (ix *Index) loadSnapshotFile(path string) (SnapshotInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	defer file.Close()
+
+	info, err := ix.ReadSnapshot(bufio.NewReader(file))
+	info.Path = path
+	return info, err
+}
+
+// PruneSnapshots deletes all but the newest keep snapshots in dir.
+func PruneSnapshots(dir string, keep int) error {
+	paths, err := snapshotFiles(dir)
+	if err != nil {
+		return err
+	}
+	for len(paths) > keep {
+		if err := os.Remove(paths[0]); err != nil {
+			return err
+		}
+		paths = paths[1:]
+	}
+	return nil
+}
+
+// snapshotFiles lists the snapshot files in dir, oldest first.
+func snapshotFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), snapshotExt) {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (ix *Index) snapshotPayload(highWater HighWater) snapshotPayload {
+	payload := snapshotPayload{
+		CreatedAt: time.Now().UTC(),
+		HighWater: highWater,
+	}
+	for _, sh := range ix.current() {
+		sh.appendTo(&payload)
+	}
+	return payload
+}
+
+func (sh *shard) appendTo(payload *snapshotPayload) {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	for _, user := range sh.users {
+		payload.Users = append(payload.Users, user)
+	}
+	for _, post := range sh.posts {
+		payload.Posts = append(payload.Posts, post)
+	}
+	for _, comment := range sh.comments {
+		payload.Comments = append(payload.Comments, comment)
+	}
+	for _, report := range sh.reports {
+		payload.Reports = append(payload.Reports, report)
+	}
+}
+
+func (p snapshotPayload) info() SnapshotInfo {
+	return SnapshotInfo{
+		CreatedAt: p.CreatedAt,
+		HighWater: p.HighWater,
+		Documents: len(p.Users) + len(p.Posts) + len(p.Comments) + len(p.Reports),
+	}
+}
+
+// Snapshotter saves a snapshot of the index returned by Index to Dir every Interval, keeping the newest Keep.
+type Snapshotter struct {
+	Index    func() *Index
+	Dir      string
+	Interval time.Duration
+	Keep     int
+	// HighWater reports how far the change feed has been applied. It is read
+	// before the documents are copied, so replaying from it never misses a change.
+	HighWater func() HighWater
+}
+
+// Run saves snapshots until ctx is cancelled.
+func (s *Snapshotter) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.RunOnce(); err != nil {
+			log.Printf("Error saving search index snapshot: %v", err)
+		}
+	}
+}
+
+// RunOnce saves one snapshot and prunes old ones.
+func (s *Snapshotter) RunOnce() error {
+	var highWater HighWater
+	if s.HighWater != nil {
+		highWater = s.HighWater()
+	}
+
+	startTime := time.Now()
+	info, err := s.Index().SaveSnapshot(s.Dir, highWater)
+	if err != nil {
+		return err
+	}
+	log.Printf("Saved search index snapshot %s with %d documents in %v", info.Path, info.Documents, time.Since(startTime))
+	return PruneSnapshots(s.Dir, s.Keep)
+}
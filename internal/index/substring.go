@@ -0,0 +1,115 @@
+package index
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/ngram"
+)
+
+// trigramIndex maps every trigram of a lowercased text to the documents containing it.
+// It narrows a substring search down to the documents holding every trigram of the pattern.
+type trigramIndex struct {
+	postings map[string]map[uuid.UUID]struct{}
+	docGrams map[uuid.UUID][]string
+}
+
+func newTrigramIndex() *trigramIndex {
+	return &trigramIndex{
+		postings: make(map[string]map[uuid.UUID]struct{}),
+		docGrams: make(map[uuid.UUID][]string),
+	}
+}
+
+func (t *trigramIndex) add(id uuid.UUID, text string) {
+	t.remove(id)
+
+	grams := ngram.Trigrams(text)
+	for _, gram := range grams {
+		docs, ok := t.postings[gram]
+		if !ok {
+			docs = make(map[uuid.UUID]struct{})
+			t.postings[gram] = docs
+		}
+		docs[id] = struct{}{}
+	}
+	t.docGrams[id] = grams
+}
+
+func (t *trigramIndex) remove(id uuid.UUID) {
+	for _, gram := range t.docGrams[id] {
+		docs := t.postings[gram]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(t.postings, gram)
+		}
+	}
+	delete(t.docGrams, id)
+}
+
+// candidates returns the documents holding every trigram of pattern, and false if the pattern
+// has no trigrams, so every document is a candidate.
+func (t *trigramIndex) candidates(pattern string) (map[uuid.UUID]struct{}, bool) {
+	grams := ngram.Trigrams(pattern)
+	if len(grams) == 0 {
+		return nil, false
+	}
+
+	// Intersect starting from the rarest trigram.
+	smallest := t.postings[grams[0]]
+	for _, gram := range grams[1:] {
+		if docs := t.postings[gram]; len(docs) < len(smallest) {
+			smallest = docs
+		}
+	}
+
+	candidates := make(map[uuid.UUID]struct{}, len(smallest))
+	for id := range smallest {
+		candidates[id] = struct{}{}
+	}
+	for _, gram := range grams {
+		docs := t.postings[gram]
+		for id := range candidates {
+			if _, ok := docs[id]; !ok {
+				delete(candidates, id)
+			}
+		}
+	}
+	return candidates, true
+}
+
+// SearchPostsBySubstring returns posts whose body contains the pattern, ignoring case, newest first.
+// A language, if given, restricts the posts searched.
+func (ix *Index) SearchPostsBySubstring(ctx context.Context, arg database.SearchPostsBySubstringParams) ([]database.Post, error) {
+	less := byTimeDesc(postCreatedAt, postKey)
+	pattern := strings.ToLower(arg.Pattern)
+	return search(ctx, ix, sql.NullString{String: arg.Pattern, Valid: true}, less, func(ctx context.Context, sh *shard, fuzzy bool) ([]hit[database.Post], bool) {
+		matches := make(map[uuid.UUID]float64)
+		match := func(id uuid.UUID) {
+			post := sh.posts[id]
+			if arg.Language.Valid && post.Language != arg.Language {
+				return
+			}
+			if strings.Contains(strings.ToLower(post.Body), pattern) {
+				matches[id] = 0
+			}
+		}
+
+		if candidates, ok := sh.bodyGrams.candidates(pattern); ok {
+			for id := range candidates {
+				match(id)
+			}
+		} else {
+			for id := range sh.posts {
+				if ctx.Err() != nil {
+					break
+				}
+				match(id)
+			}
+		}
+		return rank(sh.posts, matches, less, ix.topK), false
+	})
+}
@@ -0,0 +1,73 @@
+package langdetect
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/database"
+)
+
+// PostLanguageStore is the subset of database queries the backfill needs.
+type PostLanguageStore interface {
+	ListPostsWithoutLanguage(ctx context.Context, limit int32) ([]database.ListPostsWithoutLanguageRow, error)
+	SetPostLanguage(ctx context.Context, arg database.SetPostLanguageParams) error
+}
+
+// Backfill tags posts that have no language yet. Posts are written by other services,
+// so new rows show up untagged and are picked up on the next tick.
+type Backfill struct {
+	DB        PostLanguageStore
+	BatchSize int32
+	Interval  time.Duration
+}
+
+// Run tags posts every Interval until ctx is cancelled.
+func (b *Backfill) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := b.RunOnce(ctx); err != nil {
+			log.Printf("Error detecting post languages: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce tags untagged posts batch by batch until none are left and returns how many were tagged.
+func (b *Backfill) RunOnce(ctx context.Context) (int, error) {
+	tagged := 0
+	for {
+		posts, err := b.DB.ListPostsWithoutLanguage(ctx, b.BatchSize)
+		if err != nil {
+			return tagged, err
+		}
+
+		for _, post := range posts {
+			if err := b.DB.SetPostLanguage(ctx, PostLanguage(post.ID, post.Body)); err != nil {
+				return tagged, err
+			}
+			tagged++
+		}
+
+		if int32(len(posts)) < b.BatchSize {
+			return tagged, nil
+		}
+	}
+}
+
+// PostLanguage computes the language column of a post.
+func PostLanguage(id uuid.UUID, body string) database.SetPostLanguageParams {
+	return database.SetPostLanguageParams{
+		ID:       id,
+		Language: sql.NullString{String: Detect(body), Valid: true},
+	}
+}
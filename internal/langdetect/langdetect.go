@@ -0,0 +1,163 @@
+package langdetect
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Supported language codes.
+const (
+	English = "en"
+	Russian = "ru"
+	Uzbek   = "uz"
+	// Undetermined marks text that is too short or too ambiguous to classify.
+	Undetermined = "und"
+)
+
+const (
+	maxNGram    = 3
+	profileSize = 300
+	minLetters  = 8
+)
+
+// Languages lists the codes Detect can return, excluding Undetermined.
+var Languages = []string{English, Russian, Uzbek}
+
+// IsSupported reports whether code is a language Detect can return.
+func IsSupported(code string) bool {
+	for _, lang := range Languages {
+		if lang == code {
+			return true
+		}
+	}
+	return false
+}
+
+type profile struct {
+	language string
+	ranks    map[string]int
+}
+
+// profiles are built once from the embedded training samples.
+// Uzbek gets one profile per script because it is written in both Latin and Cyrillic.
+var profiles = []profile{
+	newProfile(English, englishSample),
+	newProfile(Russian, russianSample),
+	newProfile(Uzbek, uzbekLatinSample),
+	newProfile(Uzbek, uzbekCyrillicSample),
+}
+
+func newProfile(language, sample string) profile {
+	counts := countNGrams(sample)
+	grams := make([]string, 0, len(counts))
+	for gram := range counts {
+		grams = append(grams, gram)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > profileSize {
+		grams = grams[:profileSize]
+	}
+
+	ranks := make(map[string]int, len(grams))
+	for i, gram := range grams {
+		ranks[gram] = i
+	}
+	return profile{language: language, ranks: ranks}
+}
+
+// Detect returns the language code of text using the Cavnar-Trenkle n-gram
+// "out of place" distance against local profiles. No external service is involved.
+// Short or letterless text returns Undetermined.
+func Detect(text string) string {
+	if language, ok := detectByScript(text); ok {
+		return language
+	}
+
+	counts := countNGrams(text)
+	letters := 0
+	for gram, count := range counts {
+		if len([]rune(gram)) == 1 {
+			letters += count
+		}
+	}
+	if letters < minLetters {
+		return Undetermined
+	}
+
+	grams := make([]string, 0, len(counts))
+	for gram := range counts {
+		grams = append(grams, gram)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > profileSize {
+		grams = grams[:profileSize]
+	}
+
+	best, bestDistance := Undetermined, -1
+	for _, p := range profiles {
+		distance := 0
+		for rank, gram := range grams {
+			if profileRank, ok := p.ranks[gram]; ok {
+				distance += abs(rank - profileRank)
+			} else {
+				distance += profileSize
+			}
+		}
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = p.language, distance
+		}
+	}
+	return best
+}
+
+// detectByScript short-circuits on Cyrillic letters that only Uzbek uses.
+func detectByScript(text string) (string, bool) {
+	for _, r := range strings.ToLower(text) {
+		switch r {
+		case 'ў', 'қ', 'ғ', 'ҳ':
+			return Uzbek, true
+		}
+	}
+	return "", false
+}
+
+// countNGrams counts 1..maxNGram character grams of every word, padding words with spaces
+// so that prefixes and suffixes get their own grams.
+func countNGrams(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+		runes := []rune(" " + word + " ")
+		for n := 1; n <= maxNGram; n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				gram := string(runes[i : i+n])
+				if gram == " " {
+					continue
+				}
+				counts[gram]++
+			}
+		}
+	}
+	return counts
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && r != '\'' && r != 'ʻ' && r != 'ʼ'
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
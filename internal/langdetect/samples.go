@@ -0,0 +1,60 @@
+package langdetect
+
+// Training samples the n-gram profiles are built from. They are everyday social
+// media style sentences, which is what post bodies look like.
+
+const englishSample = `
+Today I went to the market with my friends and we bought fresh bread, cheese and some fruit.
+The weather was beautiful, so we walked through the park and talked about our plans for the weekend.
+I think this is one of the best movies I have ever seen, the story is really interesting.
+Does anyone know where I can find a good place to eat in the city center?
+Thank you all for the birthday wishes, I am so happy to have such wonderful people in my life.
+We are looking for new members who want to join our running club every Sunday morning.
+The new phone has a great camera, but the battery does not last the whole day.
+Please share this post with your family and friends, it would mean a lot to us.
+Learning a new language takes time, patience and practice, but it is worth the effort.
+My little brother started school this year and he already loves reading books.
+What are you doing this evening? Let us meet after work and have some coffee together.
+`
+
+const russianSample = `
+Сегодня я ходил на рынок с друзьями, и мы купили свежий хлеб, сыр и немного фруктов.
+Погода была прекрасная, поэтому мы гуляли по парку и говорили о планах на выходные.
+Я думаю, что это один из лучших фильмов, которые я когда-либо видел, история очень интересная.
+Кто-нибудь знает, где можно хорошо поесть в центре города?
+Спасибо всем за поздравления с днём рождения, я так счастлив, что в моей жизни есть такие люди.
+Мы ищем новых участников, которые хотят присоединиться к нашему клубу бега каждое воскресенье утром.
+У нового телефона отличная камера, но батарея не держит целый день.
+Пожалуйста, поделитесь этой записью с семьёй и друзьями, это очень важно для нас.
+Изучение нового языка требует времени, терпения и практики, но оно того стоит.
+Мой младший брат пошёл в школу в этом году и уже любит читать книги.
+Что ты делаешь сегодня вечером? Давай встретимся после работы и выпьем кофе вместе.
+`
+
+const uzbekLatinSample = `
+Bugun men do'stlarim bilan bozorga bordim va biz yangi non, pishloq va meva sotib oldik.
+Havo juda chiroyli edi, shuning uchun biz bog'da sayr qildik va dam olish kunlari rejalarimiz haqida gaplashdik.
+Menimcha bu men ko'rgan eng yaxshi filmlardan biri, voqea juda qiziqarli.
+Shahar markazida qayerda yaxshi ovqatlanish mumkinligini kimdir biladimi?
+Tug'ilgan kunim bilan tabriklaganingiz uchun barchangizga rahmat, hayotimda shunday ajoyib odamlar borligidan xursandman.
+Biz har yakshanba ertalab yugurish klubimizga qo'shilishni xohlaydigan yangi a'zolarni qidiryapmiz.
+Yangi telefonning kamerasi zo'r, lekin batareyasi kun bo'yi yetmaydi.
+Iltimos, bu postni oilangiz va do'stlaringiz bilan ulashing, bu biz uchun juda muhim.
+Yangi tilni o'rganish vaqt, sabr va mashq talab qiladi, lekin bunga arziydi.
+Ukam bu yil maktabga bordi va u allaqachon kitob o'qishni yaxshi ko'radi.
+Bugun kechqurun nima qilyapsan? Ishdan keyin uchrashib, birga qahva ichaylik.
+`
+
+const uzbekCyrillicSample = `
+Бугун мен дўстларим билан бозорга бордим ва биз янги нон, пишлоқ ва мева сотиб олдик.
+Ҳаво жуда чиройли эди, шунинг учун биз боғда сайр қилдик ва дам олиш кунлари режаларимиз ҳақида гаплашдик.
+Менимча бу мен кўрган энг яхши фильмлардан бири, воқеа жуда қизиқарли.
+Шаҳар марказида қаерда яхши овқатланиш мумкинлигини кимдир биладими?
+Туғилган куним билан табриклаганингиз учун барчангизга раҳмат, ҳаётимда шундай ажойиб одамлар борлигидан хурсандман.
+Биз ҳар якшанба эрталаб югуриш клубимизга қўшилишни хоҳлайдиган янги аъзоларни қидиряпмиз.
+Янги телефоннинг камераси зўр, лекин батареяси кун бўйи етмайди.
+Илтимос, бу постни оилангиз ва дўстларингиз билан улашинг, бу биз учун жуда муҳим.
+Янги тилни ўрганиш вақт, сабр ва машқ талаб қилади, лекин бунга арзийди.
+Укам бу йил мактабга борди ва у аллақачон китоб ўқишни яхши кўради.
+Бугун кечқурун нима қиляпсан? Ишдан кейин учрашиб, бирга қаҳва ичайлик.
+`
@@ -0,0 +1,219 @@
+// Package levenshtein matches terms within a small edit distance of a query. An Automaton
+// accepts the strings within its distance of the query, counting insertions, deletions,
+// substitutions and transpositions of adjacent letters as one edit each (optimal string alignment).
+// Intersecting it with a sorted dictionary visits every shared prefix once and skips whole
+// ranges of terms as soon as their prefix can no longer match.
+package levenshtein
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Automaton accepts the strings within MaxEdits edits of a query.
+type Automaton struct {
+	query    []rune
+	maxEdits int
+}
+
+// State is the state of an Automaton after reading some input. States are immutable,
+// so a state can be stepped with several runes, as done when walking a dictionary.
+type State struct {
+	// row holds the distance between the input read so far and every prefix of the query,
+	// capped at maxEdits+1. prev is the row before the last rune, for transpositions.
+	row  []int
+	prev []int
+	last rune
+}
+
+// Match is a dictionary term accepted by an Automaton.
+type Match struct {
+	Term     string
+	Distance int
+}
+
+// New builds the automaton of query with the given maximum edit distance.
+func New(query string, maxEdits int) *Automaton {
+	return &Automaton{query: []rune(query), maxEdits: maxEdits}
+}
+
+// MaxEdits returns the maximum edit distance of the automaton.
+func (a *Automaton) MaxEdits() int {
+	return a.maxEdits
+}
+
+// Start returns the state before any input is read.
+func (a *Automaton) Start() State {
+	row := make([]int, len(a.query)+1)
+	for j := range row {
+		row[j] = min(j, a.maxEdits+1)
+	}
+	return State{row: row}
+}
+
+// Step returns the state after reading r in state s.
+func (a *Automaton) Step(s State, r rune) State {
+	row := make([]int, len(s.row))
+	a.step(row, s.row, s.prev, s.last, r)
+	return State{row: row, prev: s.row, last: r}
+}
+
+// step writes to row the row after reading r, given the current row, the row before it
+// (nil at the start) and the rune read last.
+func (a *Automaton) step(row, current, before []int, last, r rune) {
+	limit := a.maxEdits + 1
+	row[0] = min(current[0]+1, limit)
+	for j := 1; j < len(row); j++ {
+		cost := 1
+		if a.query[j-1] == r {
+			cost = 0
+		}
+		d := min(current[j]+1, row[j-1]+1, current[j-1]+cost)
+		if before != nil && j > 1 && a.query[j-1] == last && a.query[j-2] == r {
+			d = min(d, before[j-2]+1)
+		}
+		row[j] = min(d, limit)
+	}
+}
+
+func (a *Automaton) alive(row []int) bool {
+	for _, d := range row {
+		if d <= a.maxEdits {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMatch reports whether the input read to reach s is accepted.
+func (a *Automaton) IsMatch(s State) bool {
+	return s.row[len(s.row)-1] <= a.maxEdits
+}
+
+// CanMatch reports whether some continuation of the input read to reach s can still be accepted.
+func (a *Automaton) CanMatch(s State) bool {
+	return a.alive(s.row)
+}
+
+// Distance returns the edit distance between the input read to reach s and the query,
+// or MaxEdits+1 if it is larger than MaxEdits.
+func (a *Automaton) Distance(s State) int {
+	return s.row[len(s.row)-1]
+}
+
+// Intersect returns the terms of the sorted dictionary accepted by the automaton, in dictionary order.
+// Rows are shared between terms with a common prefix, and terms whose prefix can't match are skipped
+// with a binary search, so only a small part of a large dictionary is read.
+func (a *Automaton) Intersect(dictionary []string) []Match {
+	var matches []Match
+	w := walker{automaton: a, rows: [][]int{a.Start().row}}
+
+	for i := 0; i < len(dictionary); {
+		term := dictionary[i]
+		if end, ok := w.walk(term); !ok {
+			// No term starting with term[:end] can match: skip them all.
+			prefix, rest := term[:end], dictionary[i+1:]
+			i += 1 + sort.Search(len(rest), func(k int) bool {
+				return !strings.HasPrefix(rest[k], prefix)
+			})
+			continue
+		}
+		if d := w.distance(); d <= a.maxEdits {
+			matches = append(matches, Match{Term: term, Distance: d})
+		}
+		i++
+	}
+	return matches
+}
+
+// walker steps an automaton through a sorted dictionary, keeping the rows of the last term read.
+type walker struct {
+	automaton *Automaton
+	// rows[d] is the row after the first d runes of path; rows[0] is the start row.
+	rows  [][]int
+	path  []rune
+	depth int
+}
+
+// walk reads term, reusing the rows of the prefix it shares with the previous term. It stops at the
+// first rune after which no match is possible and returns the byte offset just past it and false.
+func (w *walker) walk(term string) (end int, ok bool) {
+	shared := true
+	d := 0
+	for offset, r := range term {
+		d++
+		if shared && d <= len(w.path) && w.path[d-1] == r {
+			continue
+		}
+		shared = false
+
+		if d > len(w.path) {
+			w.path = append(w.path, r)
+		}
+		w.path[d-1] = r
+		if d >= len(w.rows) {
+			w.rows = append(w.rows, make([]int, len(w.rows[0])))
+		}
+
+		var before []int
+		var last rune
+		if d > 1 {
+			before, last = w.rows[d-2], w.path[d-2]
+		}
+		w.automaton.step(w.rows[d], w.rows[d-1], before, last, r)
+		if !w.automaton.alive(w.rows[d]) {
+			w.path = w.path[:d]
+			w.depth = d
+			return offset + utf8.RuneLen(r), false
+		}
+	}
+	if !shared || d < len(w.path) {
+		w.path = w.path[:d]
+	}
+	w.depth = d
+	return len(term), true
+}
+
+// distance returns the distance of the last term walked.
+func (w *walker) distance() int {
+	row := w.rows[w.depth]
+	return row[len(row)-1]
+}
+
+// Distance returns the optimal string alignment distance between a and b
+// (Levenshtein plus transpositions of adjacent letters), stopping early once it exceeds max.
+func Distance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
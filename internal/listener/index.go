@@ -0,0 +1,76 @@
+package listener
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+)
+
+// RowStore is the subset of database queries IndexApplier needs.
+type RowStore interface {
+	index.Source
+	GetSearchUserByID(ctx context.Context, id uuid.UUID) (database.SearchUser, error)
+	GetPostByID(ctx context.Context, id uuid.UUID) (database.Post, error)
+	GetCommentByID(ctx context.Context, id uuid.UUID) (database.Comment, error)
+	GetReportByID(ctx context.Context, id uuid.UUID) (database.Report, error)
+}
+
+// IndexApplier applies changes to an in-memory index. Inserted and updated rows are
+// read back from the database, so the index always gets the row's latest version
+// no matter how late or out of order the notification arrives.
+type IndexApplier struct {
+	DB        RowStore
+	Index     *index.Index
+	BatchSize int32
+}
+
+// Apply updates or removes the changed document.
+func (a *IndexApplier) Apply(ctx context.Context, change Change) error {
+	if change.Op == Delete {
+		a.Index.Delete(change.Entity, change.ID)
+		return nil
+	}
+
+	var err error
+	switch change.Entity {
+	case index.Users:
+		var user database.SearchUser
+		if user, err = a.DB.GetSearchUserByID(ctx, change.ID); err == nil {
+			a.Index.PutUser(user)
+		}
+	case index.Posts:
+		var post database.Post
+		if post, err = a.DB.GetPostByID(ctx, change.ID); err == nil {
+			a.Index.PutPost(post)
+		}
+	case index.Comments:
+		var comment database.Comment
+		if comment, err = a.DB.GetCommentByID(ctx, change.ID); err == nil {
+			a.Index.PutComment(comment)
+		}
+	case index.Reports:
+		var report database.Report
+		if report, err = a.DB.GetReportByID(ctx, change.ID); err == nil {
+			a.Index.PutReport(report)
+		}
+	default:
+		return fmt.Errorf("unknown entity %q", change.Entity)
+	}
+
+	// The row was deleted again before we read it; its delete notification follows.
+	if errors.Is(err, sql.ErrNoRows) {
+		a.Index.Delete(change.Entity, change.ID)
+		return nil
+	}
+	return err
+}
+
+// Resync rebuilds the whole index from the database.
+func (a *IndexApplier) Resync(ctx context.Context) error {
+	return a.Index.Rebuild(ctx, a.DB, a.BatchSize)
+}
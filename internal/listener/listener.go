@@ -0,0 +1,196 @@
+// Package listener keeps in-process search state up to date with Postgres
+// by listening for the notifications the search_changes triggers send.
+package listener
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/lib/pq"
+)
+
+// Channel is the notification channel the triggers publish changes on.
+const Channel = "search_changes"
+
+// Op is the kind of change a notification reports, as in the trigger's TG_OP.
+type Op string
+
+// Change operations.
+const (
+	Insert Op = "INSERT"
+	Update Op = "UPDATE"
+	Delete Op = "DELETE"
+)
+
+// Change is one changed row.
+type Change struct {
+	Entity index.Entity `json:"table"`
+	Op     Op           `json:"op"`
+	ID     uuid.UUID    `json:"id"`
+}
+
+// ParseChange decodes a notification payload.
+func ParseChange(payload string) (Change, error) {
+	var change Change
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		return Change{}, fmt.Errorf("invalid change %q: %w", payload, err)
+	}
+	if err := validateChange(change); err != nil {
+		return Change{}, fmt.Errorf("invalid change %q: %w", payload, err)
+	}
+	return change, nil
+}
+
+func validateChange(change Change) error {
+	switch change.Op {
+	case Insert, Update, Delete:
+	default:
+		return fmt.Errorf("unknown op %q", change.Op)
+	}
+	for _, entity := range index.Entities {
+		if change.Entity == entity {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown table %q", change.Entity)
+}
+
+// Applier applies changes to whatever the listener keeps up to date.
+type Applier interface {
+	// Apply applies a single change.
+	Apply(ctx context.Context, change Change) error
+	// Resync reloads everything, for when changes may have been missed.
+	Resync(ctx context.Context) error
+}
+
+// Chain applies every change to each of its appliers in order, such as an index and then
+// a cache of its results. Every applier is called even if an earlier one fails.
+type Chain []Applier
+
+// Apply applies change to every applier of the chain.
+func (c Chain) Apply(ctx context.Context, change Change) error {
+	var errs []error
+	for _, applier := range c {
+		if err := applier.Apply(ctx, change); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Resync resyncs every applier of the chain.
+func (c Chain) Resync(ctx context.Context) error {
+	var errs []error
+	for _, applier := range c {
+		if err := applier.Resync(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Listener applies changes announced on Channel as they happen.
+//
+// The connection is re-established with exponential backoff between MinReconnectInterval
+// and MaxReconnectInterval. Notifications sent while it was down are lost, so after every
+// reconnect, and whenever a change fails to apply, the Applier does a full resync.
+// A failed resync is retried every PingInterval until it succeeds.
+type Listener struct {
+	DSN                  string
+	Applier              Applier
+	MinReconnectInterval time.Duration
+	MaxReconnectInterval time.Duration
+	PingInterval         time.Duration
+
+	pq    *pq.Listener
+	stale bool
+}
+
+// Start connects to the database and subscribes to Channel. It blocks until the
+// subscription is acknowledged, so changes made after Start returns are not missed.
+func (l *Listener) Start() error {
+	l.pq = pq.NewListener(l.DSN, l.MinReconnectInterval, l.MaxReconnectInterval, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			log.Printf("Search change listener disconnected: %v", err)
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Printf("Search change listener failed to reconnect: %v", err)
+		case pq.ListenerEventReconnected:
+			log.Print("Search change listener reconnected")
+		}
+	})
+	if err := l.pq.Listen(Channel); err != nil {
+		l.pq.Close()
+		return fmt.Errorf("listen on %s: %w", Channel, err)
+	}
+	return nil
+}
+
+// Run applies notifications until ctx is cancelled, then closes the connection.
+func (l *Listener) Run(ctx context.Context) {
+	defer l.pq.Close()
+
+	ticker := time.NewTicker(l.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification, ok := <-l.pq.Notify:
+			if !ok {
+				return
+			}
+			l.Handle(ctx, notification)
+		case <-ticker.C:
+			// A ping notices a silently dropped connection, which then reconnects.
+			if err := l.pq.Ping(); err != nil {
+				log.Printf("Error pinging search change listener: %v", err)
+			}
+			if l.stale {
+				l.resync(ctx)
+			}
+		}
+	}
+}
+
+// Handle applies a single notification. A nil notification means the connection
+// was re-established and changes may have been missed, which triggers a resync.
+func (l *Listener) Handle(ctx context.Context, notification *pq.Notification) {
+	if notification == nil {
+		l.resync(ctx)
+		return
+	}
+
+	change, err := ParseChange(notification.Extra)
+	if err != nil {
+		log.Printf("Error handling search change: %v", err)
+		return
+	}
+	if err := l.Applier.Apply(ctx, change); err != nil {
+		log.Printf("Error applying %s of %s %s: %v", change.Op, change.Entity, change.ID, err)
+		l.resync(ctx)
+	}
+}
+
+// Stale reports whether a resync is still pending after a failure.
+func (l *Listener) Stale() bool {
+	return l.stale
+}
+
+func (l *Listener) resync(ctx context.Context) {
+	startTime := time.Now()
+	if err := l.Applier.Resync(ctx); err != nil {
+		log.Printf("Error resyncing search index: %v", err)
+		l.stale = true
+		return
+	}
+	l.stale = false
+	log.Printf("Resynced search index in %v", time.Since(startTime))
+}
@@ -0,0 +1,246 @@
+package listener
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+)
+
+// OutboxStore is the subset of database queries the outbox consumer needs.
+type OutboxStore interface {
+	EnsureOutboxCheckpoint(ctx context.Context, consumer string) error
+	LockOutboxCheckpoint(ctx context.Context, consumer string) (database.SearchOutboxCheckpoint, error)
+	SetOutboxCheckpoint(ctx context.Context, arg database.SetOutboxCheckpointParams) error
+	ListOutboxAfter(ctx context.Context, arg database.ListOutboxAfterParams) ([]database.SearchOutbox, error)
+	GetOutboxHead(ctx context.Context) (database.GetOutboxHeadRow, error)
+	DeleteOutboxBefore(ctx context.Context, createdAt time.Time) error
+	DeleteOutboxCheckpointsBefore(ctx context.Context, arg database.DeleteOutboxCheckpointsBeforeParams) error
+}
+
+// Outbox runs functions against the outbox inside a transaction.
+type Outbox interface {
+	InTx(ctx context.Context, fn func(q OutboxStore) error) error
+}
+
+// SQLOutbox is the Postgres Outbox.
+type SQLOutbox struct {
+	DB      *sql.DB
+	Queries *database.Queries
+}
+
+// InTx runs fn in a transaction, committing if it returns nil and rolling back otherwise.
+func (o SQLOutbox) InTx(ctx context.Context, fn func(q OutboxStore) error) error {
+	tx, err := o.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(o.Queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Consumer applies the changes recorded in search_outbox, resuming from a checkpoint
+// stored in search_outbox_checkpoints under Name.
+//
+// Each batch runs in one transaction that locks the consumer's checkpoint row with
+// FOR UPDATE SKIP LOCKED, applies the entries after it and moves it forward, so a batch
+// is either fully recorded or retried. Replicas that share a Name take turns on the same
+// checkpoint; replicas with their own in-memory index need their own Name to see every change.
+//
+// Entries are read in (txid, id) order and only once the transaction that wrote them
+// can no longer be running, so a transaction that commits late is never skipped.
+//
+// Entries older than Retention are deleted. A checkpoint that hasn't moved for longer
+// than that may have lost entries, so the consumer resyncs and starts again from the head.
+// Checkpoints of other consumers that haven't moved for that long, such as those of replicas
+// that were replaced, are deleted too; a consumer positions its checkpoint again when it starts.
+type Consumer struct {
+	Name      string
+	Outbox    Outbox
+	Applier   Applier
+	BatchSize int32
+	Interval  time.Duration
+	Retention time.Duration
+
+	mu      sync.Mutex
+	applied index.HighWater
+}
+
+// Run applies outbox entries every Interval until ctx is cancelled.
+func (c *Consumer) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.RunOnce(ctx); err != nil {
+			log.Printf("Error consuming search outbox: %v", err)
+		}
+		if err := c.Prune(ctx); err != nil {
+			log.Printf("Error pruning search outbox: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce applies pending entries batch by batch until none are left and returns how many were applied.
+func (c *Consumer) RunOnce(ctx context.Context) (int, error) {
+	applied := 0
+	for {
+		n, err := c.consumeBatch(ctx)
+		applied += n
+		if err != nil || n < int(c.BatchSize) {
+			return applied, err
+		}
+	}
+}
+
+// Seek moves the checkpoint to the newest finished entry. Call it before a full load
+// so that only changes made after the load started are applied again.
+func (c *Consumer) Seek(ctx context.Context) error {
+	return c.Outbox.InTx(ctx, func(q OutboxStore) error {
+		head, err := c.head(ctx, q)
+		if err != nil {
+			return err
+		}
+		return c.seekTo(ctx, q, head)
+	})
+}
+
+// SeekTo moves the checkpoint to position, such as the high-water mark of a snapshot
+// the index was loaded from, so every change after it is replayed.
+func (c *Consumer) SeekTo(ctx context.Context, position index.HighWater) error {
+	return c.Outbox.InTx(ctx, func(q OutboxStore) error {
+		return c.seekTo(ctx, q, position)
+	})
+}
+
+// Applied returns the position of the last entry applied to the index.
+func (c *Consumer) Applied() index.HighWater {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.applied
+}
+
+// Prune deletes entries, and the checkpoints of other consumers, older than Retention.
+func (c *Consumer) Prune(ctx context.Context) error {
+	return c.Outbox.InTx(ctx, func(q OutboxStore) error {
+		before := time.Now().Add(-c.Retention)
+		if err := q.DeleteOutboxBefore(ctx, before); err != nil {
+			return err
+		}
+		return q.DeleteOutboxCheckpointsBefore(ctx, database.DeleteOutboxCheckpointsBeforeParams{UpdatedAt: before, Consumer: c.Name})
+	})
+}
+
+func (c *Consumer) consumeBatch(ctx context.Context) (int, error) {
+	applied := 0
+	err := c.Outbox.InTx(ctx, func(q OutboxStore) error {
+		if err := q.EnsureOutboxCheckpoint(ctx, c.Name); err != nil {
+			return err
+		}
+		checkpoint, err := q.LockOutboxCheckpoint(ctx, c.Name)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Another replica is consuming under the same name.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if checkpoint.UpdatedAt.Before(time.Now().Add(-c.Retention)) {
+			log.Printf("Search outbox checkpoint %q is older than the retention period, resyncing", c.Name)
+			// The head is read first, so changes made during the resync are applied after it.
+			head, err := c.head(ctx, q)
+			if err != nil {
+				return err
+			}
+			if err := c.Applier.Resync(ctx); err != nil {
+				return err
+			}
+			c.setApplied(head)
+			return c.setCheckpoint(ctx, q, head)
+		}
+
+		entries, err := q.ListOutboxAfter(ctx, database.ListOutboxAfterParams{
+			LastTxid:  checkpoint.LastTxid,
+			LastID:    checkpoint.LastID,
+			BatchSize: c.BatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			change := Change{Entity: index.Entity(entry.TableName), Op: Op(entry.Op), ID: entry.RowID}
+			if err := validateChange(change); err != nil {
+				log.Printf("Skipping search outbox entry %d: %v", entry.ID, err)
+			} else if err := c.Applier.Apply(ctx, change); err != nil {
+				return err
+			}
+			checkpoint.LastTxid, checkpoint.LastID = entry.Txid, entry.ID
+			c.setApplied(index.HighWater{Txid: entry.Txid, ID: entry.ID})
+		}
+		applied = len(entries)
+
+		// Written even when nothing changed, so an idle checkpoint doesn't look stale.
+		return c.setCheckpoint(ctx, q, index.HighWater{Txid: checkpoint.LastTxid, ID: checkpoint.LastID})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return applied, nil
+}
+
+// seekTo moves the checkpoint to position unless another replica is consuming under the same name.
+func (c *Consumer) seekTo(ctx context.Context, q OutboxStore, position index.HighWater) error {
+	if err := q.EnsureOutboxCheckpoint(ctx, c.Name); err != nil {
+		return err
+	}
+	_, err := q.LockOutboxCheckpoint(ctx, c.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Another replica is consuming under the same name and keeps the checkpoint current.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.setApplied(position)
+	return c.setCheckpoint(ctx, q, position)
+}
+
+// head returns the position of the newest finished entry.
+func (c *Consumer) head(ctx context.Context, q OutboxStore) (index.HighWater, error) {
+	head, err := q.GetOutboxHead(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return index.HighWater{}, err
+	}
+	return index.HighWater{Txid: head.Txid, ID: head.ID}, nil
+}
+
+func (c *Consumer) setCheckpoint(ctx context.Context, q OutboxStore, position index.HighWater) error {
+	return q.SetOutboxCheckpoint(ctx, database.SetOutboxCheckpointParams{
+		Consumer: c.Name,
+		LastTxid: position.Txid,
+		LastID:   position.ID,
+	})
+}
+
+func (c *Consumer) setApplied(position index.HighWater) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.applied = position
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/google/uuid"
 	"github.com/imhasandl/search-service/internal/database"
 	"github.com/stretchr/testify/mock"
 )
@@ -21,16 +22,23 @@ func NewMockQueries() *MockQueries {
 
 // SearchUsers mocks the SearchUsers method of the database interface.
 // It returns users matching the provided query string.
-func (m *MockQueries) SearchUsers(ctx context.Context, query sql.NullString) ([]database.User, error) {
+func (m *MockQueries) SearchUsers(ctx context.Context, query sql.NullString) ([]database.SearchUser, error) {
 	args := m.Called(ctx, query)
-	return args.Get(0).([]database.User), args.Error(1)
+	return args.Get(0).([]database.SearchUser), args.Error(1)
 }
 
 // SearchUsersByDate mocks the SearchUsersByDate method of the database interface.
 // It returns users matching the provided query string ordered by created_at timestamp.
-func (m *MockQueries) SearchUsersByDate(ctx context.Context, query sql.NullString) ([]database.User, error) {
+func (m *MockQueries) SearchUsersByDate(ctx context.Context, query sql.NullString) ([]database.SearchUser, error) {
 	args := m.Called(ctx, query)
-	return args.Get(0).([]database.User), args.Error(1)
+	return args.Get(0).([]database.SearchUser), args.Error(1)
+}
+
+// SearchUsersByPhonetic mocks the SearchUsersByPhonetic method of the database interface.
+// It returns users whose username has the given phonetic code.
+func (m *MockQueries) SearchUsersByPhonetic(ctx context.Context, code sql.NullString) ([]database.SearchUser, error) {
+	args := m.Called(ctx, code)
+	return args.Get(0).([]database.SearchUser), args.Error(1)
 }
 
 // SearchPosts mocks the SearchPosts method of the database interface.
@@ -47,6 +55,20 @@ func (m *MockQueries) SearchPostsByDate(ctx context.Context, query sql.NullStrin
 	return args.Get(0).([]database.Post), args.Error(1)
 }
 
+// SearchPostsByLanguage mocks the SearchPostsByLanguage method of the database interface.
+// It returns posts in the given language that match the provided query string.
+func (m *MockQueries) SearchPostsByLanguage(ctx context.Context, arg database.SearchPostsByLanguageParams) ([]database.Post, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]database.Post), args.Error(1)
+}
+
+// SearchPostsBySubstring mocks the SearchPostsBySubstring method of the database interface.
+// It returns posts whose body contains the given pattern.
+func (m *MockQueries) SearchPostsBySubstring(ctx context.Context, arg database.SearchPostsBySubstringParams) ([]database.Post, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]database.Post), args.Error(1)
+}
+
 // SearchReports mocks the SearchReports method of the database interface.
 // It returns reports that match the provided query string.
 func (m *MockQueries) SearchReports(ctx context.Context, query sql.NullString) ([]database.Report, error) {
@@ -60,3 +82,24 @@ func (m *MockQueries) SearchReportsByDate(ctx context.Context, query sql.NullStr
 	args := m.Called(ctx, query)
 	return args.Get(0).([]database.Report), args.Error(1)
 }
+
+// GetUserProfile mocks the GetUserProfile method of the database interface.
+// It returns the full profile of the user with the given id.
+func (m *MockQueries) GetUserProfile(ctx context.Context, id uuid.UUID) (database.GetUserProfileRow, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(database.GetUserProfileRow), args.Error(1)
+}
+
+// IsUserPremium mocks the IsUserPremium method of the database interface.
+// It returns whether the user with the given id has a premium account.
+func (m *MockQueries) IsUserPremium(ctx context.Context, id uuid.UUID) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+// ListAuditEntries mocks the ListAuditEntries method of the database interface.
+// It returns the audit log entries matching the given actor and time range.
+func (m *MockQueries) ListAuditEntries(ctx context.Context, arg database.ListAuditEntriesParams) ([]database.SearchAuditLog, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]database.SearchAuditLog), args.Error(1)
+}
@@ -0,0 +1,71 @@
+// Package ngram validates substring search patterns and splits text into trigrams.
+package ngram
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pattern length limits, in characters. Postgres can only use a trigram index for patterns
+// of at least three characters; shorter ones would scan every post.
+const (
+	MinLength = 3
+	MaxLength = 256
+)
+
+// Errors returned by Validate.
+var (
+	ErrTooShort       = errors.New("substring pattern must be at least 3 characters")
+	ErrTooLong        = errors.New("substring pattern must be at most 256 characters")
+	ErrNoAlphanumeric = errors.New("substring pattern must contain a letter or digit")
+	ErrRepetitive     = errors.New("substring pattern must contain at least two different characters")
+)
+
+// Validate rejects patterns that are too short or too long to search for, and pathological patterns
+// such as "...." or "aaaa" whose trigrams are too common or too few to narrow the search down.
+func Validate(pattern string) error {
+	pattern = strings.TrimSpace(pattern)
+	n := utf8.RuneCountInString(pattern)
+	if n < MinLength {
+		return ErrTooShort
+	}
+	if n > MaxLength {
+		return ErrTooLong
+	}
+
+	alphanumeric := false
+	distinct := make(map[rune]bool)
+	for _, r := range strings.ToLower(pattern) {
+		alphanumeric = alphanumeric || unicode.IsLetter(r) || unicode.IsDigit(r)
+		distinct[r] = true
+	}
+	if !alphanumeric {
+		return ErrNoAlphanumeric
+	}
+	if len(distinct) < 2 {
+		return ErrRepetitive
+	}
+	return nil
+}
+
+// Trigrams returns the distinct trigrams of the lowercased text, in order of appearance.
+// Text shorter than three characters has none.
+func Trigrams(text string) []string {
+	runes := []rune(strings.ToLower(text))
+	if len(runes) < 3 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(runes)-2)
+	grams := make([]string, 0, len(runes)-2)
+	for i := 0; i+3 <= len(runes); i++ {
+		gram := string(runes[i : i+3])
+		if !seen[gram] {
+			seen[gram] = true
+			grams = append(grams, gram)
+		}
+	}
+	return grams
+}
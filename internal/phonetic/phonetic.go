@@ -0,0 +1,101 @@
+// Package phonetic encodes names by how they sound, so spellings of a name that was only heard,
+// such as "Mohammed", "Muhammad" and "Mohamed", share one code.
+//
+// The encoding is a Metaphone-style rule set for names in Uzbek or Russian transliteration. It reads
+// the script independent key of translit.Key, so Cyrillic and Latin spellings of a name agree too.
+package phonetic
+
+import "strings"
+
+// MaxLength is the maximum number of codes in an encoding.
+const MaxLength = 6
+
+// digraphs are rewritten before single letters are classified; uppercase letters are placeholders
+// for sounds written with two letters. Russian "kh" and Uzbek "x" are one sound, as are "ts" and "s",
+// which transliterations mix freely.
+var digraphs = strings.NewReplacer(
+	"sch", "X",
+	"sh", "X",
+	"ch", "C",
+	"dzh", "j",
+	"dj", "j",
+	"zh", "j",
+	"kh", "x",
+	"gh", "g",
+	"ph", "f",
+	"th", "t",
+	"ck", "k",
+	"ts", "s",
+	"tz", "s",
+)
+
+// codes maps consonants to their class. Voiced and voiceless pairs share a class, because final
+// consonants are devoiced in Russian and spelt either way in transliteration.
+var codes = map[rune]byte{
+	'b': 'P', 'p': 'P',
+	'd': 'T', 't': 'T',
+	'g': 'K', 'k': 'K', 'q': 'K',
+	'v': 'F', 'f': 'F', 'w': 'F',
+	'z': 'S', 's': 'S',
+	'j': 'J',
+	'l': 'L',
+	'm': 'M',
+	'n': 'N',
+	'r': 'R',
+	'X': 'X',
+	'C': 'C',
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiouy", r)
+}
+
+// Encode returns the phonetic code of a script independent name key. A leading vowel becomes "A",
+// other vowels are dropped, "h" and "x" only count before a vowel, and repeated codes collapse,
+// so "mohammed", "muhammad" and "mohamed" all become "MHMT". Characters other than Latin letters
+// are ignored; a key without letters has an empty code.
+func Encode(key string) string {
+	var letters strings.Builder
+	for _, r := range strings.ToLower(key) {
+		if r >= 'a' && r <= 'z' {
+			letters.WriteRune(r)
+		}
+	}
+	word := []rune(digraphs.Replace(letters.String()))
+
+	var code []byte
+	var last byte
+	for i, r := range word {
+		var c byte
+		switch {
+		case isVowel(r) && i == 0:
+			c = 'A'
+		case isVowel(r):
+			// Vowels separate repeated consonants: "mama" keeps both M's.
+			last = 0
+			continue
+		case r == 'h' || r == 'x':
+			if i+1 == len(word) || !isVowel(word[i+1]) {
+				continue
+			}
+			c = 'H'
+		case r == 'c':
+			c = 'K'
+			if i+1 < len(word) && strings.ContainsRune("eiy", word[i+1]) {
+				c = 'S'
+			}
+		default:
+			c = codes[r]
+		}
+
+		if c == last {
+			continue
+		}
+		code = append(code, c)
+		last = c
+		if len(code) == MaxLength {
+			break
+		}
+	}
+	return string(code)
+}
@@ -0,0 +1,332 @@
+// Package ratelimit throttles RPCs with token buckets per caller and method. Authenticated callers
+// are limited by user ID and anonymous ones by peer IP; premium users get a larger quota.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/auth"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultLimit applies to RPCs without a limit of their own.
+var DefaultLimit = Limit{Rate: 10, Burst: 20}
+
+const (
+	// DefaultPremiumFactor multiplies the limits of premium users.
+	DefaultPremiumFactor = 5
+	// RetryAfterKey is the header metadata telling throttled callers how many seconds to wait.
+	RetryAfterKey = "retry-after"
+
+	// premiumTTL is how long whether a user is premium is remembered.
+	premiumTTL = time.Minute
+	// idleTimeout is how long unused buckets and premium lookups are kept.
+	idleTimeout = 10 * time.Minute
+)
+
+// Limit is a token bucket refilled with Rate tokens per second, holding up to Burst tokens.
+// A zero Limit doesn't limit.
+type Limit struct {
+	Rate  rate.Limit
+	Burst int
+}
+
+func (l Limit) unlimited() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+func (l Limit) scale(factor float64) Limit {
+	return Limit{Rate: l.Rate * rate.Limit(factor), Burst: int(math.Ceil(float64(l.Burst) * factor))}
+}
+
+// ParseLimit parses a limit written as "rate/unit[:burst]", such as "10/s", "100/m:20" or "off".
+// The burst defaults to the number of requests per unit, at least one.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" {
+		return Limit{}, nil
+	}
+	spec, burstSpec, hasBurst := strings.Cut(s, ":")
+	count, unit, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q, expected rate/unit[:burst]", s)
+	}
+	n, err := strconv.ParseFloat(count, 64)
+	if err != nil || n < 0 {
+		return Limit{}, fmt.Errorf("invalid rate in limit %q", s)
+	}
+	var per time.Duration
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("invalid unit %q in limit %q, expected s, m or h", unit, s)
+	}
+
+	limit := Limit{Rate: rate.Limit(n / per.Seconds()), Burst: max(int(math.Ceil(n)), 1)}
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(burstSpec)
+		if err != nil || limit.Burst < 0 {
+			return Limit{}, fmt.Errorf("invalid burst in limit %q", s)
+		}
+	}
+	return limit, nil
+}
+
+// ParseLimits parses a comma separated list of method=limit pairs, such as "SearchPosts=2/s:5,SearchUsers=off".
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		method, spec, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q, expected rpc=limit", pair)
+		}
+		limit, err := ParseLimit(spec)
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.TrimSpace(method)] = limit
+	}
+	return limits, nil
+}
+
+// PremiumQuerier tells whether a user has a premium account.
+type PremiumQuerier interface {
+	IsUserPremium(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// Option configures a Limiter.
+type Option func(*Limiter)
+
+// WithLimit sets the limit of an RPC, by method name such as "SearchPosts".
+func WithLimit(method string, limit Limit) Option {
+	return func(l *Limiter) {
+		l.limits[method] = limit
+	}
+}
+
+// WithDefaultLimit replaces DefaultLimit.
+func WithDefaultLimit(limit Limit) Option {
+	return func(l *Limiter) {
+		l.defaultLimit = limit
+	}
+}
+
+// WithPremium gives the premium users found in db limits multiplied by factor.
+func WithPremium(db PremiumQuerier, factor float64) Option {
+	return func(l *Limiter) {
+		l.premium = db
+		l.premiumFactor = factor
+	}
+}
+
+// WithClock replaces time.Now, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(l *Limiter) {
+		l.now = now
+	}
+}
+
+// Limiter holds the token buckets of every caller.
+type Limiter struct {
+	limits        map[string]Limit
+	defaultLimit  Limit
+	premium       PremiumQuerier
+	premiumFactor float64
+	now           func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	premiums  map[string]premiumEntry
+	lastSweep time.Time
+}
+
+type bucketKey struct {
+	method, caller string
+	premium        bool
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type premiumEntry struct {
+	premium bool
+	expires time.Time
+}
+
+// New creates a Limiter.
+func New(opts ...Option) *Limiter {
+	l := &Limiter{
+		limits:        make(map[string]Limit),
+		defaultLimit:  DefaultLimit,
+		premiumFactor: DefaultPremiumFactor,
+		now:           time.Now,
+		buckets:       make(map[bucketKey]*bucket),
+		premiums:      make(map[string]premiumEntry),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Allow takes a token for a call of fullMethod by the caller of ctx. When the bucket is empty it
+// returns false and how long until a token is available.
+func (l *Limiter) Allow(ctx context.Context, fullMethod string) (bool, time.Duration) {
+	method := path.Base(fullMethod)
+	limit, ok := l.limits[method]
+	if !ok {
+		limit = l.defaultLimit
+	}
+	if limit.unlimited() {
+		return true, 0
+	}
+
+	key := bucketKey{method: method}
+	if principal, ok := auth.FromContext(ctx); ok {
+		key.caller = "user:" + principal.Subject
+		key.premium = l.isPremium(ctx, principal.Subject)
+	} else {
+		key.caller = "ip:" + peerIP(ctx)
+	}
+	if key.premium {
+		limit = limit.scale(l.premiumFactor)
+	}
+
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(limit.Rate, limit.Burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// isPremium tells whether subject is a premium user, remembering the answer for a while.
+// Subjects that aren't user IDs and failed lookups get the regular limits.
+func (l *Limiter) isPremium(ctx context.Context, subject string) bool {
+	if l.premium == nil {
+		return false
+	}
+	id, err := uuid.Parse(subject)
+	if err != nil {
+		return false
+	}
+
+	now := l.now()
+	l.mu.Lock()
+	entry, ok := l.premiums[subject]
+	l.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.premium
+	}
+
+	premium, err := l.premium.IsUserPremium(ctx, id)
+	if err != nil {
+		log.Printf("Error checking premium account of %s: %v", subject, err)
+		return false
+	}
+	l.mu.Lock()
+	l.premiums[subject] = premiumEntry{premium: premium, expires: now.Add(premiumTTL)}
+	l.mu.Unlock()
+	return premium
+}
+
+// sweep drops idle buckets and expired premium lookups every idleTimeout, so callers seen once
+// don't stay in memory. l.mu must be held.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTimeout {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+	for subject, entry := range l.premiums {
+		if !now.Before(entry.expires) {
+			delete(l.premiums, subject)
+		}
+	}
+}
+
+// peerIP returns the IP address of the caller of ctx, without the port.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// throttled returns the ResourceExhausted error of a throttled call, after setting the retry-after header.
+func throttled(fullMethod string, retryAfter time.Duration, setHeader func(metadata.MD) error) error {
+	seconds := strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+	if err := setHeader(metadata.Pairs(RetryAfterKey, seconds)); err != nil {
+		log.Printf("Error setting %s header: %v", RetryAfterKey, err)
+	}
+	return status.Errorf(codes.ResourceExhausted, "rate limit of %s exceeded, retry after %ss", path.Base(fullMethod), seconds)
+}
+
+// UnaryServerInterceptor throttles unary RPCs. It must run after authentication.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if ok, retryAfter := l.Allow(ctx, info.FullMethod); !ok {
+			return nil, throttled(info.FullMethod, retryAfter, func(md metadata.MD) error {
+				return grpc.SetHeader(ctx, md)
+			})
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor throttles the start of streaming RPCs. It must run after authentication.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if ok, retryAfter := l.Allow(ss.Context(), info.FullMethod); !ok {
+			return throttled(info.FullMethod, retryAfter, ss.SetHeader)
+		}
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,72 @@
+package reindex
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/index"
+)
+
+// Checkpoint records how far a reindex got, so an interrupted run can resume.
+type Checkpoint struct {
+	// Completed lists the entities that were fully reindexed.
+	Completed []index.Entity `json:"completed"`
+	// Entity is the entity being reindexed when the checkpoint was saved,
+	// and After the ID of the last row of it that was processed.
+	Entity index.Entity `json:"entity,omitempty"`
+	After  uuid.UUID    `json:"after"`
+}
+
+// LoadCheckpoint reads a checkpoint file. A missing file is an empty checkpoint.
+func LoadCheckpoint(path string) (Checkpoint, error) {
+	var checkpoint Checkpoint
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return checkpoint, err
+	}
+	err = json.Unmarshal(data, &checkpoint)
+	return checkpoint, err
+}
+
+// Save writes the checkpoint atomically, so a crash mid-write leaves the previous one intact.
+func (c Checkpoint) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// IsCompleted reports whether entity was fully reindexed.
+func (c Checkpoint) IsCompleted(entity index.Entity) bool {
+	return slices.Contains(c.Completed, entity)
+}
+
+// resumeAfter returns the ID to continue entity after.
+func (c Checkpoint) resumeAfter(entity index.Entity) uuid.UUID {
+	if c.Entity == entity {
+		return c.After
+	}
+	return uuid.Nil
+}
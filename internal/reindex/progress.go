@@ -0,0 +1,44 @@
+package reindex
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/imhasandl/search-service/internal/index"
+)
+
+// Progress is the state of reindexing one entity.
+type Progress struct {
+	Entity index.Entity
+	// Total is the number of rows this run has to process, Done how many it has.
+	Total int64
+	Done  int64
+	// Elapsed is the time spent on the entity so far.
+	Elapsed time.Duration
+}
+
+// Rate returns the throughput in rows per second.
+func (p Progress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Done) / p.Elapsed.Seconds()
+}
+
+// ETA estimates the time left at the current rate. It is zero once done or before any row was processed.
+func (p Progress) ETA() time.Duration {
+	rate := p.Rate()
+	if rate == 0 || p.Done >= p.Total {
+		return 0
+	}
+	return time.Duration(float64(p.Total-p.Done) / rate * float64(time.Second))
+}
+
+func (p Progress) String() string {
+	percent := 100.0
+	if p.Total > 0 {
+		percent = float64(p.Done) / float64(p.Total) * 100
+	}
+	return fmt.Sprintf("%s: %d/%d (%.1f%%), %.0f rows/s, ETA %v",
+		p.Entity, p.Done, p.Total, percent, p.Rate(), p.ETA().Round(time.Second))
+}
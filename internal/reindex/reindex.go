@@ -0,0 +1,216 @@
+// Package reindex rebuilds the derived search data of every row from the Postgres source of truth.
+package reindex
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/langdetect"
+	"github.com/imhasandl/search-service/internal/translit"
+)
+
+// Store is the subset of database queries the reindex needs.
+type Store interface {
+	index.Source
+	CountUsersAfter(ctx context.Context, id uuid.UUID) (int64, error)
+	CountPostsAfter(ctx context.Context, id uuid.UUID) (int64, error)
+	CountCommentsAfter(ctx context.Context, id uuid.UUID) (int64, error)
+	CountReportsAfter(ctx context.Context, id uuid.UUID) (int64, error)
+	SetUsernameTransliteration(ctx context.Context, arg database.SetUsernameTransliterationParams) error
+	SetPostLanguage(ctx context.Context, arg database.SetPostLanguageParams) error
+	TouchComments(ctx context.Context, ids []uuid.UUID) error
+	TouchReports(ctx context.Context, ids []uuid.UUID) error
+}
+
+// Reindexer walks every row of the requested entities in ID order, batch by batch:
+// users get their transliterations recomputed, posts their language, and comments and
+// reports are touched. Every write fires the search change triggers, so running services
+// refresh their in-memory indexes as the reindex goes.
+//
+// With CheckpointPath set, the position is saved after every batch and a later run
+// resumes from it. The file is removed once everything is done.
+type Reindexer struct {
+	DB               Store
+	Transliterator   *translit.Transliterator
+	UsernameAnalyzer *analyzer.Analyzer
+	BatchSize        int32
+	CheckpointPath   string
+	// DryRun only counts the rows that would be reindexed.
+	DryRun bool
+	// ProgressInterval is how often progress is reported.
+	ProgressInterval time.Duration
+	// Report receives progress reports; it defaults to logging them.
+	Report func(p Progress)
+}
+
+// Run reindexes entities in order.
+func (r *Reindexer) Run(ctx context.Context, entities []index.Entity) error {
+	var checkpoint Checkpoint
+	if r.CheckpointPath != "" {
+		var err error
+		checkpoint, err = LoadCheckpoint(r.CheckpointPath)
+		if err != nil {
+			return fmt.Errorf("load checkpoint: %w", err)
+		}
+	}
+
+	for _, entity := range entities {
+		if checkpoint.IsCompleted(entity) {
+			log.Printf("%s: already reindexed, skipping", entity)
+			continue
+		}
+		if err := r.runEntity(ctx, entity, &checkpoint); err != nil {
+			return fmt.Errorf("reindex %s: %w", entity, err)
+		}
+	}
+
+	if r.CheckpointPath != "" && !r.DryRun {
+		if err := os.Remove(r.CheckpointPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Reindexer) runEntity(ctx context.Context, entity index.Entity, checkpoint *Checkpoint) error {
+	after := checkpoint.resumeAfter(entity)
+	if after != uuid.Nil {
+		log.Printf("%s: resuming after %s", entity, after)
+	}
+
+	total, err := r.count(ctx, entity, after)
+	if err != nil {
+		return err
+	}
+	if r.DryRun {
+		log.Printf("%s: %d rows to reindex", entity, total)
+		return nil
+	}
+
+	progress := Progress{Entity: entity, Total: total}
+	startTime := time.Now()
+	lastReport := startTime
+	for {
+		n, last, err := r.batch(ctx, entity, after)
+		if err != nil {
+			return err
+		}
+		progress.Done += int64(n)
+		progress.Elapsed = time.Since(startTime)
+
+		done := n < int(r.BatchSize)
+		if done {
+			checkpoint.Completed = append(checkpoint.Completed, entity)
+			checkpoint.Entity, checkpoint.After = "", uuid.Nil
+		} else {
+			after = last
+			checkpoint.Entity, checkpoint.After = entity, after
+		}
+		if r.CheckpointPath != "" {
+			if err := checkpoint.Save(r.CheckpointPath); err != nil {
+				return fmt.Errorf("save checkpoint: %w", err)
+			}
+		}
+
+		if done || time.Since(lastReport) >= r.ProgressInterval {
+			r.report(progress)
+			lastReport = time.Now()
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+func (r *Reindexer) count(ctx context.Context, entity index.Entity, after uuid.UUID) (int64, error) {
+	switch entity {
+	case index.Users:
+		return r.DB.CountUsersAfter(ctx, after)
+	case index.Posts:
+		return r.DB.CountPostsAfter(ctx, after)
+	case index.Comments:
+		return r.DB.CountCommentsAfter(ctx, after)
+	case index.Reports:
+		return r.DB.CountReportsAfter(ctx, after)
+	}
+	return 0, fmt.Errorf("unknown entity %q", entity)
+}
+
+// batch reindexes the next batch of rows after the given ID and returns how many
+// there were and the ID of the last one.
+func (r *Reindexer) batch(ctx context.Context, entity index.Entity, after uuid.UUID) (int, uuid.UUID, error) {
+	var ids []uuid.UUID
+	switch entity {
+	case index.Users:
+		users, err := r.DB.ListSearchUsersAfter(ctx, database.ListSearchUsersAfterParams{ID: after, Limit: r.BatchSize})
+		if err != nil {
+			return 0, uuid.Nil, err
+		}
+		for _, user := range users {
+			params := translit.UsernameColumns(r.Transliterator, r.UsernameAnalyzer, user.ID, user.Username)
+			if err := r.DB.SetUsernameTransliteration(ctx, params); err != nil {
+				return 0, uuid.Nil, err
+			}
+			ids = append(ids, user.ID)
+		}
+	case index.Posts:
+		posts, err := r.DB.ListPostsAfter(ctx, database.ListPostsAfterParams{ID: after, Limit: r.BatchSize})
+		if err != nil {
+			return 0, uuid.Nil, err
+		}
+		for _, post := range posts {
+			if err := r.DB.SetPostLanguage(ctx, langdetect.PostLanguage(post.ID, post.Body)); err != nil {
+				return 0, uuid.Nil, err
+			}
+			ids = append(ids, post.ID)
+		}
+	case index.Comments:
+		comments, err := r.DB.ListCommentsAfter(ctx, database.ListCommentsAfterParams{ID: after, Limit: r.BatchSize})
+		if err != nil {
+			return 0, uuid.Nil, err
+		}
+		for _, comment := range comments {
+			ids = append(ids, comment.ID)
+		}
+		if len(ids) > 0 {
+			if err := r.DB.TouchComments(ctx, ids); err != nil {
+				return 0, uuid.Nil, err
+			}
+		}
+	case index.Reports:
+		reports, err := r.DB.ListReportsAfter(ctx, database.ListReportsAfterParams{ID: after, Limit: r.BatchSize})
+		if err != nil {
+			return 0, uuid.Nil, err
+		}
+		for _, report := range reports {
+			ids = append(ids, report.ID)
+		}
+		if len(ids) > 0 {
+			if err := r.DB.TouchReports(ctx, ids); err != nil {
+				return 0, uuid.Nil, err
+			}
+		}
+	default:
+		return 0, uuid.Nil, fmt.Errorf("unknown entity %q", entity)
+	}
+
+	if len(ids) == 0 {
+		return 0, after, nil
+	}
+	return len(ids), ids[len(ids)-1], nil
+}
+
+func (r *Reindexer) report(p Progress) {
+	if r.Report != nil {
+		r.Report(p)
+		return
+	}
+	log.Print(p)
+}
@@ -0,0 +1,144 @@
+package synonyms
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/imhasandl/search-service/internal/database"
+)
+
+// Source loads the current synonym rules from wherever they are kept.
+type Source interface {
+	Load(ctx context.Context) ([]Rule, error)
+	Name() string
+}
+
+// FileSource reads rules from a synonyms file in the format accepted by Parse.
+type FileSource struct {
+	Path string
+}
+
+// Load parses the synonyms file.
+func (f FileSource) Load(ctx context.Context) ([]Rule, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Parse(file)
+}
+
+// Name identifies the source in logs and admin responses.
+func (f FileSource) Name() string {
+	return "file:" + f.Path
+}
+
+// SynonymLister is the subset of database queries needed to read rules from the search_synonyms table.
+type SynonymLister interface {
+	ListSynonyms(ctx context.Context) ([]database.SearchSynonym, error)
+}
+
+// DBSource reads rules from the search_synonyms table.
+type DBSource struct {
+	DB SynonymLister
+}
+
+// Load fetches every row of search_synonyms.
+func (d DBSource) Load(ctx context.Context) ([]Rule, error) {
+	rows, err := d.DB.ListSynonyms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]Rule, 0, len(rows))
+	for _, row := range rows {
+		rule := Rule{Terms: row.Terms, Synonyms: row.Synonyms, OneWay: row.OneWay}
+		if err := rule.validate(); err != nil {
+			log.Printf("Skipping synonym rule %d: %v", row.ID, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Name identifies the source in logs and admin responses.
+func (d DBSource) Name() string {
+	return "db:search_synonyms"
+}
+
+// Store keeps the active synonym Set and swaps it atomically on reload,
+// so queries never observe a half-loaded dictionary.
+type Store struct {
+	source        Source
+	maxExpansions int
+	current       atomic.Pointer[Set]
+
+	mu       sync.Mutex
+	loadedAt time.Time
+}
+
+// NewStore creates a Store backed by source. Call Reload before serving queries.
+func NewStore(source Source, maxExpansions int) *Store {
+	s := &Store{source: source, maxExpansions: maxExpansions}
+	s.current.Store(NewSet(nil))
+	return s
+}
+
+// Reload fetches the rules from the source and replaces the active set.
+// On error the previous set stays active.
+func (s *Store) Reload(ctx context.Context) error {
+	rules, err := s.source.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.current.Store(NewSet(rules))
+	s.mu.Lock()
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the rules every interval until ctx is cancelled.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Reload(ctx); err != nil {
+				log.Printf("Error reloading synonyms from %s: %v", s.source.Name(), err)
+			}
+		}
+	}
+}
+
+// Expand rewrites query using the active rules. The original query is always first.
+func (s *Store) Expand(query string) []string {
+	return s.current.Load().Expand(query, s.maxExpansions)
+}
+
+// Rules returns the active rules.
+func (s *Store) Rules() []Rule {
+	return s.current.Load().Rules()
+}
+
+// Source returns the name of the source the rules are loaded from.
+func (s *Store) Source() string {
+	return s.source.Name()
+}
+
+// LoadedAt returns when the active rules were last loaded successfully.
+func (s *Store) LoadedAt() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadedAt
+}
@@ -0,0 +1,205 @@
+package synonyms
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultMaxExpansions caps how many query variants a single query can expand into.
+const DefaultMaxExpansions = 8
+
+// Rule describes a single synonym rule.
+// An equivalence rule makes every entry of Terms interchangeable with the others.
+// A one-way rule rewrites any entry of Terms into each entry of Synonyms, but not back.
+type Rule struct {
+	Terms    []string
+	Synonyms []string
+	OneWay   bool
+}
+
+// String renders the rule in the same format Parse accepts.
+func (r Rule) String() string {
+	if r.OneWay {
+		return strings.Join(r.Terms, ", ") + " => " + strings.Join(r.Synonyms, ", ")
+	}
+	return strings.Join(r.Terms, ", ")
+}
+
+// Parse reads synonym rules, one per line, in the Solr synonyms format.
+// "nyc, new york" declares an equivalence and "pic, pics => photo" declares a one-way rule.
+// Blank lines and lines starting with '#' are ignored.
+func Parse(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		left, right, oneWay := strings.Cut(line, "=>")
+		rule := Rule{Terms: splitTerms(left), OneWay: oneWay}
+		if oneWay {
+			rule.Synonyms = splitTerms(right)
+		}
+
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("synonyms line %d: %w", lineNo, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r Rule) validate() error {
+	if len(r.Terms) == 0 {
+		return fmt.Errorf("rule has no terms")
+	}
+	if r.OneWay && len(r.Synonyms) == 0 {
+		return fmt.Errorf("one-way rule %q has no synonyms", strings.Join(r.Terms, ", "))
+	}
+	if !r.OneWay && len(r.Terms) < 2 {
+		return fmt.Errorf("equivalence rule %q needs at least two terms", r.Terms[0])
+	}
+	return nil
+}
+
+func splitTerms(s string) []string {
+	var terms []string
+	for _, term := range strings.Split(s, ",") {
+		if term = normalize(term); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// Set is an immutable, compiled collection of rules ready for query expansion.
+type Set struct {
+	rules     []Rule
+	mappings  map[string][]string
+	maxPhrase int
+}
+
+// NewSet compiles rules into a Set. Rules that fail validation are skipped.
+func NewSet(rules []Rule) *Set {
+	set := &Set{mappings: make(map[string][]string)}
+	for _, rule := range rules {
+		if rule.validate() != nil {
+			continue
+		}
+		set.rules = append(set.rules, rule)
+
+		if rule.OneWay {
+			for _, term := range rule.Terms {
+				set.add(term, rule.Synonyms...)
+			}
+			continue
+		}
+		for _, term := range rule.Terms {
+			set.add(term, rule.Terms...)
+		}
+	}
+	return set
+}
+
+func (s *Set) add(term string, alternatives ...string) {
+	term = normalize(term)
+	if n := len(strings.Fields(term)); n > s.maxPhrase {
+		s.maxPhrase = n
+	}
+	for _, alt := range alternatives {
+		alt = normalize(alt)
+		if alt == term || contains(s.mappings[term], alt) {
+			continue
+		}
+		s.mappings[term] = append(s.mappings[term], alt)
+	}
+}
+
+// Rules returns the rules the set was compiled from.
+func (s *Set) Rules() []Rule {
+	if s == nil {
+		return nil
+	}
+	return s.rules
+}
+
+// Expand returns the query followed by every rewrite of it the rules allow, up to max variants.
+// Phrases are matched greedily, longest first, against whitespace separated query words.
+// The original query is always the first element so callers can rank exact matches first.
+func (s *Set) Expand(query string, max int) []string {
+	variants := []string{query}
+	if s == nil || len(s.mappings) == 0 || strings.TrimSpace(query) == "" {
+		return variants
+	}
+	if max <= 0 {
+		max = DefaultMaxExpansions
+	}
+
+	words := strings.Fields(query)
+	var segments [][]string
+	expanded := false
+	for i := 0; i < len(words); {
+		matched := 0
+		var alternatives []string
+		for n := min(s.maxPhrase, len(words)-i); n > 0; n-- {
+			if alts, ok := s.mappings[normalize(strings.Join(words[i:i+n], " "))]; ok {
+				matched, alternatives = n, alts
+				break
+			}
+		}
+		if matched == 0 {
+			segments = append(segments, []string{words[i]})
+			i++
+			continue
+		}
+		expanded = true
+		segments = append(segments, append([]string{strings.Join(words[i:i+matched], " ")}, alternatives...))
+		i += matched
+	}
+	if !expanded {
+		return variants
+	}
+
+	seen := map[string]bool{query: true}
+	var build func(idx int, parts []string)
+	build = func(idx int, parts []string) {
+		if len(variants) >= max {
+			return
+		}
+		if idx == len(segments) {
+			variant := strings.Join(parts, " ")
+			if !seen[variant] {
+				seen[variant] = true
+				variants = append(variants, variant)
+			}
+			return
+		}
+		for _, option := range segments[idx] {
+			build(idx+1, append(parts, option))
+		}
+	}
+	build(0, make([]string, 0, len(segments)))
+	return variants
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
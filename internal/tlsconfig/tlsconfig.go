@@ -0,0 +1,175 @@
+// Package tlsconfig builds the TLS configuration of the gRPC listener from certificate files and
+// reloads them when they change, so certificates can be rotated without restarting the service.
+package tlsconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ClientAuth is how client certificates are checked.
+type ClientAuth string
+
+const (
+	// ClientAuthNone doesn't ask for client certificates.
+	ClientAuthNone ClientAuth = "none"
+	// ClientAuthOptional verifies client certificates against the client CA bundle when clients send one.
+	ClientAuthOptional ClientAuth = "optional"
+	// ClientAuthRequire refuses clients without a certificate signed by the client CA bundle (mutual TLS).
+	ClientAuthRequire ClientAuth = "require"
+)
+
+// Config names the files of the TLS configuration.
+type Config struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is a PEM bundle of the CAs client certificates are verified against.
+	// It is required unless ClientAuth is ClientAuthNone.
+	ClientCAFile string
+	ClientAuth   ClientAuth
+}
+
+// Reloader serves the certificates of a Config, reloading them when their files change.
+type Reloader struct {
+	config Config
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	// loaded holds the contents of the files last loaded, to tell when they change.
+	loaded map[string][]byte
+}
+
+// New loads the files of config.
+func New(config Config) (*Reloader, error) {
+	if config.ClientAuth == "" {
+		config.ClientAuth = ClientAuthNone
+	}
+	switch config.ClientAuth {
+	case ClientAuthNone, ClientAuthOptional, ClientAuthRequire:
+	default:
+		return nil, fmt.Errorf("unknown client auth %q, expected none, optional or require", config.ClientAuth)
+	}
+	if config.ClientAuth != ClientAuthNone && config.ClientCAFile == "" {
+		return nil, fmt.Errorf("client auth %q needs a client CA file", config.ClientAuth)
+	}
+
+	r := &Reloader{config: config}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the files again if any of them changed and reports whether they did. On error the
+// certificates loaded before are kept.
+func (r *Reloader) Reload() (bool, error) {
+	files := map[string][]byte{}
+	for _, name := range []string{r.config.CertFile, r.config.KeyFile, r.config.ClientCAFile} {
+		if name == "" {
+			continue
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return false, err
+		}
+		files[name] = data
+	}
+
+	r.mu.RLock()
+	changed := !sameFiles(files, r.loaded)
+	r.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(files[r.config.CertFile], files[r.config.KeyFile])
+	if err != nil {
+		return false, fmt.Errorf("loading %s: %w", r.config.CertFile, err)
+	}
+	var clientCAs *x509.CertPool
+	if r.config.ClientCAFile != "" {
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(files[r.config.ClientCAFile]) {
+			return false, fmt.Errorf("no certificates found in %s", r.config.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.clientCAs, r.loaded = &cert, clientCAs, files
+	r.mu.Unlock()
+	return true, nil
+}
+
+func sameFiles(a, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, data := range a {
+		if !bytes.Equal(data, b[name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Run reloads the files every interval until ctx is cancelled. A certificate and key being
+// rewritten one after the other may not match for a moment; they are loaded on the next try.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := r.Reload()
+		if err != nil {
+			log.Printf("Error reloading TLS certificates: %v", err)
+			continue
+		}
+		if changed {
+			log.Printf("Reloaded TLS certificates from %s", r.config.CertFile)
+		}
+	}
+}
+
+// TLSConfig returns a server configuration that always uses the latest certificates loaded.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			if r.cert == nil {
+				return nil, errors.New("no TLS certificate loaded")
+			}
+
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientCAs:    r.clientCAs,
+				// gRPC needs HTTP/2 to be negotiated.
+				NextProtos: []string{"h2"},
+			}
+			switch r.config.ClientAuth {
+			case ClientAuthOptional:
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+			case ClientAuthRequire:
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return config, nil
+		},
+	}
+}
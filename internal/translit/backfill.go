@@ -0,0 +1,83 @@
+package translit
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/phonetic"
+)
+
+// UsernameStore is the subset of database queries the backfill needs.
+type UsernameStore interface {
+	ListUsersWithoutTransliteration(ctx context.Context, limit int32) ([]database.ListUsersWithoutTransliterationRow, error)
+	SetUsernameTransliteration(ctx context.Context, arg database.SetUsernameTransliterationParams) error
+}
+
+// Backfill fills users.username_latin and users.username_cyrillic for users that don't have them yet.
+// Renaming a user clears both columns, so renamed users are picked up again.
+// Usernames pass through Analyzer before transliteration, the same way queries do.
+type Backfill struct {
+	DB             UsernameStore
+	Transliterator *Transliterator
+	Analyzer       *analyzer.Analyzer
+	BatchSize      int32
+	Interval       time.Duration
+}
+
+// Run transliterates usernames every Interval until ctx is cancelled.
+func (b *Backfill) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := b.RunOnce(ctx); err != nil {
+			log.Printf("Error transliterating usernames: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce transliterates pending usernames batch by batch until none are left and returns how many were updated.
+func (b *Backfill) RunOnce(ctx context.Context) (int, error) {
+	updated := 0
+	for {
+		users, err := b.DB.ListUsersWithoutTransliteration(ctx, b.BatchSize)
+		if err != nil {
+			return updated, err
+		}
+
+		for _, user := range users {
+			params := UsernameColumns(b.Transliterator, b.Analyzer, user.ID, user.Username)
+			if err := b.DB.SetUsernameTransliteration(ctx, params); err != nil {
+				return updated, err
+			}
+			updated++
+		}
+
+		if int32(len(users)) < b.BatchSize {
+			return updated, nil
+		}
+	}
+}
+
+// UsernameColumns computes the transliteration and phonetic columns of a user.
+func UsernameColumns(t *Transliterator, a *analyzer.Analyzer, id uuid.UUID, username string) database.SetUsernameTransliterationParams {
+	username = a.Normalize(username)
+	key := t.Key(username)
+	return database.SetUsernameTransliterationParams{
+		ID:               id,
+		UsernameLatin:    sql.NullString{String: key, Valid: true},
+		UsernameCyrillic: sql.NullString{String: t.ToCyrillic(username), Valid: true},
+		UsernamePhonetic: sql.NullString{String: phonetic.Encode(key), Valid: true},
+	}
+}
@@ -0,0 +1,132 @@
+package translit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Table holds the transliteration rules for both directions.
+// Keys may be longer than one letter, the longest key that matches always wins,
+// so digraphs like "sh" or "o'" are written as single entries.
+type Table struct {
+	CyrillicToLatin map[string]string `json:"cyrillic_to_latin"`
+	LatinToCyrillic map[string]string `json:"latin_to_cyrillic"`
+}
+
+// DefaultTable follows the official Uzbek Latin alphabet and covers the extra Russian letters.
+var DefaultTable = Table{
+	CyrillicToLatin: map[string]string{
+		"а": "a", "б": "b", "в": "v", "г": "g", "д": "d", "е": "e", "ё": "yo", "ж": "j",
+		"з": "z", "и": "i", "й": "y", "к": "k", "л": "l", "м": "m", "н": "n", "о": "o",
+		"п": "p", "р": "r", "с": "s", "т": "t", "у": "u", "ф": "f", "х": "x", "ц": "ts",
+		"ч": "ch", "ш": "sh", "щ": "sh", "ъ": "'", "ы": "i", "ь": "", "э": "e", "ю": "yu",
+		"я": "ya", "ў": "o'", "қ": "q", "ғ": "g'", "ҳ": "h",
+	},
+	LatinToCyrillic: map[string]string{
+		"a": "а", "b": "б", "c": "к", "d": "д", "e": "е", "f": "ф", "g": "г", "h": "ҳ",
+		"i": "и", "j": "ж", "k": "к", "l": "л", "m": "м", "n": "н", "o": "о", "p": "п",
+		"q": "қ", "r": "р", "s": "с", "t": "т", "u": "у", "v": "в", "w": "в", "x": "х",
+		"y": "й", "z": "з", "sh": "ш", "ch": "ч", "yo": "ё", "yu": "ю", "ya": "я",
+		"ts": "ц", "zh": "ж", "kh": "х", "o'": "ў", "g'": "ғ",
+	},
+}
+
+// LoadTable reads a Table from a JSON file with "cyrillic_to_latin" and "latin_to_cyrillic" objects.
+func LoadTable(path string) (Table, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Table{}, err
+	}
+
+	var table Table
+	if err := json.Unmarshal(data, &table); err != nil {
+		return Table{}, fmt.Errorf("parse transliteration table %s: %w", path, err)
+	}
+	if len(table.CyrillicToLatin) == 0 || len(table.LatinToCyrillic) == 0 {
+		return Table{}, fmt.Errorf("transliteration table %s must define both directions", path)
+	}
+	return table, nil
+}
+
+// Transliterator converts text between Cyrillic and Latin scripts.
+type Transliterator struct {
+	toLatin    mapping
+	toCyrillic mapping
+}
+
+// New compiles a Table into a Transliterator.
+func New(table Table) *Transliterator {
+	return &Transliterator{
+		toLatin:    newMapping(table.CyrillicToLatin),
+		toCyrillic: newMapping(table.LatinToCyrillic),
+	}
+}
+
+// ToLatin lowercases text and rewrites every Cyrillic letter the table knows into Latin.
+func (t *Transliterator) ToLatin(text string) string {
+	return t.toLatin.apply(normalizeApostrophes(strings.ToLower(text)))
+}
+
+// ToCyrillic lowercases text and rewrites every Latin letter the table knows into Cyrillic.
+func (t *Transliterator) ToCyrillic(text string) string {
+	return t.toCyrillic.apply(normalizeApostrophes(strings.ToLower(text)))
+}
+
+// Key returns the script independent form of text that usernames are indexed and matched by:
+// lowercase Latin without apostrophes, so "Алишер", "Alisher" and "alisher" share one key.
+func (t *Transliterator) Key(text string) string {
+	return strings.ReplaceAll(t.ToLatin(text), "'", "")
+}
+
+type mapping struct {
+	rules     map[string]string
+	maxKeyLen int
+}
+
+func newMapping(rules map[string]string) mapping {
+	m := mapping{rules: make(map[string]string, len(rules))}
+	for from, to := range rules {
+		from = strings.ToLower(from)
+		m.rules[from] = to
+		if n := utf8.RuneCountInString(from); n > m.maxKeyLen {
+			m.maxKeyLen = n
+		}
+	}
+	return m
+}
+
+func (m mapping) apply(text string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(runes); {
+		matched := false
+		for n := min(m.maxKeyLen, len(runes)-i); n > 0; n-- {
+			if to, ok := m.rules[string(runes[i:i+n])]; ok {
+				b.WriteString(to)
+				i += n
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteRune(runes[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// normalizeApostrophes maps the apostrophe look-alikes used in Uzbek Latin ("oʻ", "gʼ", "o`") to "'".
+func normalizeApostrophes(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case 'ʻ', 'ʼ', '‘', '’', '`':
+			return '\''
+		}
+		return r
+	}, text)
+}
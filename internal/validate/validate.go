@@ -0,0 +1,161 @@
+// Package validate checks and cleans the string fields of every request message before it reaches
+// an RPC: whitespace is trimmed, and values that are too long, aren't valid UTF-8 or contain control
+// characters are rejected with an InvalidArgument error listing every offending field.
+//
+// LIKE wildcards are not escaped here but by the queries themselves (like_escape), so that the
+// in-memory index, which doesn't use LIKE, gets the query as typed.
+package validate
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DefaultMaxLength is the maximum length of a string field, in characters.
+const DefaultMaxLength = 256
+
+// Validator validates request messages.
+type Validator struct {
+	maxLength int
+}
+
+// Option configures a Validator.
+type Option func(*Validator)
+
+// WithMaxLength replaces DefaultMaxLength.
+func WithMaxLength(n int) Option {
+	return func(v *Validator) {
+		v.maxLength = n
+	}
+}
+
+// New creates a Validator.
+func New(opts ...Option) *Validator {
+	v := &Validator{maxLength: DefaultMaxLength}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Request trims the string fields of msg, including those of nested messages and lists, and
+// returns the violations of the remaining values; it returns nil when msg is valid.
+func (v *Validator) Request(msg proto.Message) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+	v.message(msg.ProtoReflect(), "", &violations)
+	return violations
+}
+
+func (v *Validator) message(m protoreflect.Message, prefix string, violations *[]*errdetails.BadRequest_FieldViolation) {
+	// The message can't be changed while ranging over it, so its fields are collected first.
+	var fields []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fields = append(fields, fd)
+		return true
+	})
+
+	for _, fd := range fields {
+		name := prefix + string(fd.Name())
+		switch {
+		case fd.IsMap():
+		case fd.IsList() && fd.Kind() == protoreflect.StringKind:
+			list := m.Mutable(fd).List()
+			for i := 0; i < list.Len(); i++ {
+				list.Set(i, protoreflect.ValueOfString(v.field(fmt.Sprintf("%s[%d]", name, i), list.Get(i).String(), violations)))
+			}
+		case fd.IsList() && fd.Kind() == protoreflect.MessageKind:
+			list := m.Get(fd).List()
+			for i := 0; i < list.Len(); i++ {
+				v.message(list.Get(i).Message(), fmt.Sprintf("%s[%d].", name, i), violations)
+			}
+		case fd.Kind() == protoreflect.StringKind:
+			m.Set(fd, protoreflect.ValueOfString(v.field(name, m.Get(fd).String(), violations)))
+		case fd.Kind() == protoreflect.MessageKind:
+			v.message(m.Get(fd).Message(), name+".", violations)
+		}
+	}
+}
+
+// field returns value trimmed, adding a violation of the field to violations if it is invalid.
+func (v *Validator) field(name, value string, violations *[]*errdetails.BadRequest_FieldViolation) string {
+	if !utf8.ValidString(value) {
+		*violations = append(*violations, violation(name, "must be valid UTF-8"))
+		return value
+	}
+	value = strings.TrimSpace(value)
+	if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		*violations = append(*violations, violation(name, "must not contain control characters"))
+	}
+	if n := utf8.RuneCountInString(value); n > v.maxLength {
+		*violations = append(*violations, violation(name, fmt.Sprintf("must be at most %d characters, got %d", v.maxLength, n)))
+	}
+	return value
+}
+
+func violation(field, description string) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{Field: field, Description: description}
+}
+
+// check validates req, returning an InvalidArgument error with a BadRequest detail if it isn't valid.
+func (v *Validator) check(fullMethod string, req any) error {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil
+	}
+	violations := v.Request(msg)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, len(violations))
+	for i, fv := range violations {
+		descriptions[i] = fv.Field + " " + fv.Description
+	}
+	st := status.New(codes.InvalidArgument, "invalid request - "+path.Base(fullMethod)+": "+strings.Join(descriptions, "; "))
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// UnaryServerInterceptor validates the requests of unary RPCs.
+func (v *Validator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := v.check(info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor validates every message received by streaming RPCs.
+func (v *Validator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &validatedStream{ServerStream: ss, validator: v, method: info.FullMethod})
+	}
+}
+
+// validatedStream is a ServerStream validating the messages it receives.
+type validatedStream struct {
+	grpc.ServerStream
+	validator *Validator
+	method    string
+}
+
+func (s *validatedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.validator.check(s.method, m)
+}
@@ -0,0 +1,332 @@
+// Package verify compares the documents held by the in-memory index with the rows in Postgres,
+// and optionally re-indexes the differences.
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/listener"
+)
+
+// DefaultEntities are the entities checked when none are given.
+var DefaultEntities = []index.Entity{index.Users, index.Posts, index.Reports}
+
+// Options selects what a check covers.
+type Options struct {
+	Entities []index.Entity
+	// SampleSize is the number of rows checked per entity. Zero scans every row.
+	SampleSize int
+	// Repair re-indexes missing and stale documents and deletes orphaned ones.
+	Repair bool
+}
+
+// Report is the outcome of checking one entity.
+type Report struct {
+	Entity  index.Entity
+	Checked int
+	// Missing rows are in Postgres but not in the index.
+	Missing []uuid.UUID
+	// Stale documents differ from their row.
+	Stale []uuid.UUID
+	// Orphaned documents have no row.
+	Orphaned []uuid.UUID
+	Repaired int
+}
+
+// Consistent reports whether no difference was found.
+func (r Report) Consistent() bool {
+	return len(r.Missing) == 0 && len(r.Stale) == 0 && len(r.Orphaned) == 0
+}
+
+// Checker compares the index returned by Index with the rows of DB.
+type Checker struct {
+	DB        listener.RowStore
+	Index     func() *index.Index
+	Applier   listener.Applier
+	BatchSize int32
+	// Settle is how long to wait before checking the differences found again. Rows that changed
+	// during the scan may not have reached the index yet; differences that disappear by then are not reported.
+	Settle time.Duration
+}
+
+// Check compares every entity in opts and repairs the differences if asked to.
+func (c *Checker) Check(ctx context.Context, opts Options) ([]Report, error) {
+	entities := opts.Entities
+	if len(entities) == 0 {
+		entities = DefaultEntities
+	}
+
+	reports := make([]Report, len(entities))
+	for i, entity := range entities {
+		report, err := c.scan(ctx, entity, opts.SampleSize)
+		if err != nil {
+			return nil, fmt.Errorf("check %s: %w", entity, err)
+		}
+		reports[i] = report
+	}
+
+	if c.Settle > 0 && !allConsistent(reports) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.Settle):
+		}
+		for i := range reports {
+			if err := c.recheck(ctx, &reports[i]); err != nil {
+				return nil, fmt.Errorf("check %s: %w", reports[i].Entity, err)
+			}
+		}
+	}
+
+	if opts.Repair {
+		var errs []error
+		for i := range reports {
+			if err := c.repair(ctx, &reports[i]); err != nil {
+				errs = append(errs, fmt.Errorf("repair %s: %w", reports[i].Entity, err))
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return reports, err
+		}
+	}
+
+	for _, report := range reports {
+		log.Printf("Checked %d %s: %d missing, %d stale, %d orphaned, %d repaired",
+			report.Checked, report.Entity, len(report.Missing), len(report.Stale), len(report.Orphaned), report.Repaired)
+	}
+	return reports, nil
+}
+
+// scan checks every row of entity, or windows of rows after random IDs until sampleSize rows were checked.
+// Index documents inside a scanned window that have no row are orphaned.
+func (c *Checker) scan(ctx context.Context, entity index.Entity, sampleSize int) (Report, error) {
+	report := Report{Entity: entity}
+	ix := c.Index()
+	indexed := ix.IDs(entity)
+	checked := make(map[uuid.UUID]bool)
+
+	after, windows := uuid.Nil, 0
+	for sampleSize == 0 || (len(checked) < sampleSize && windows < sampleSize) {
+		limit := max(c.BatchSize, 1)
+		if sampleSize > 0 {
+			limit = min(limit, int32(sampleSize-len(checked)))
+			after = randomID()
+		}
+
+		rows, err := c.page(ctx, entity, after, limit)
+		if err != nil {
+			return Report{}, err
+		}
+
+		// The window ends at the last row read, or at the end of the table on a short page.
+		end := uuid.Max
+		if len(rows) == int(limit) {
+			end = rows[len(rows)-1].id
+		}
+		inPage := make(map[uuid.UUID]bool, len(rows))
+		for _, r := range rows {
+			inPage[r.id] = true
+			if checked[r.id] {
+				continue
+			}
+			checked[r.id] = true
+
+			doc, ok := ix.Lookup(entity, r.id)
+			switch {
+			case !ok:
+				report.Missing = append(report.Missing, r.id)
+			case checksum(doc) != r.sum:
+				report.Stale = append(report.Stale, r.id)
+			}
+		}
+		for _, id := range between(indexed, after, end) {
+			if !inPage[id] {
+				report.Orphaned = append(report.Orphaned, id)
+			}
+		}
+
+		windows++
+		if sampleSize == 0 {
+			if end == uuid.Max {
+				break
+			}
+			after = end
+		}
+	}
+
+	report.Checked = len(checked)
+	report.Orphaned = dedupe(report.Orphaned)
+	return report, nil
+}
+
+// recheck drops the differences in report that are gone.
+func (c *Checker) recheck(ctx context.Context, report *Report) error {
+	ix := c.Index()
+	var missing, stale, orphaned []uuid.UUID
+	for _, ids := range [][]uuid.UUID{report.Missing, report.Stale, report.Orphaned} {
+		for _, id := range ids {
+			sum, found, err := c.row(ctx, report.Entity, id)
+			if err != nil {
+				return err
+			}
+			doc, ok := ix.Lookup(report.Entity, id)
+			switch {
+			case found && !ok:
+				missing = append(missing, id)
+			case found && checksum(doc) != sum:
+				stale = append(stale, id)
+			case !found && ok:
+				orphaned = append(orphaned, id)
+			}
+		}
+	}
+	report.Missing, report.Stale, report.Orphaned = missing, stale, orphaned
+	return nil
+}
+
+// repair re-indexes missing and stale documents and deletes orphaned ones through the applier,
+// so every index version is repaired.
+func (c *Checker) repair(ctx context.Context, report *Report) error {
+	changes := make([]listener.Change, 0, len(report.Missing)+len(report.Stale)+len(report.Orphaned))
+	for _, id := range slices.Concat(report.Missing, report.Stale) {
+		changes = append(changes, listener.Change{Entity: report.Entity, Op: listener.Update, ID: id})
+	}
+	for _, id := range report.Orphaned {
+		changes = append(changes, listener.Change{Entity: report.Entity, Op: listener.Delete, ID: id})
+	}
+
+	for _, change := range changes {
+		if err := c.Applier.Apply(ctx, change); err != nil {
+			return err
+		}
+		report.Repaired++
+	}
+	return nil
+}
+
+// rowSum is the checksum of a row.
+type rowSum struct {
+	id  uuid.UUID
+	sum uint64
+}
+
+// page reads up to limit rows of entity after the given ID.
+func (c *Checker) page(ctx context.Context, entity index.Entity, after uuid.UUID, limit int32) ([]rowSum, error) {
+	switch entity {
+	case index.Users:
+		users, err := c.DB.ListSearchUsersAfter(ctx, database.ListSearchUsersAfterParams{ID: after, Limit: limit})
+		return sums(users, err, func(u database.SearchUser) uuid.UUID { return u.ID })
+	case index.Posts:
+		posts, err := c.DB.ListPostsAfter(ctx, database.ListPostsAfterParams{ID: after, Limit: limit})
+		return sums(posts, err, func(p database.Post) uuid.UUID { return p.ID })
+	case index.Comments:
+		comments, err := c.DB.ListCommentsAfter(ctx, database.ListCommentsAfterParams{ID: after, Limit: limit})
+		return sums(comments, err, func(c database.Comment) uuid.UUID { return c.ID })
+	case index.Reports:
+		reports, err := c.DB.ListReportsAfter(ctx, database.ListReportsAfterParams{ID: after, Limit: limit})
+		return sums(reports, err, func(r database.Report) uuid.UUID { return r.ID })
+	}
+	return nil, fmt.Errorf("unknown entity %q", entity)
+}
+
+// row returns the checksum of one row, and false if it doesn't exist.
+func (c *Checker) row(ctx context.Context, entity index.Entity, id uuid.UUID) (uint64, bool, error) {
+	var (
+		row any
+		err error
+	)
+	switch entity {
+	case index.Users:
+		row, err = c.DB.GetSearchUserByID(ctx, id)
+	case index.Posts:
+		row, err = c.DB.GetPostByID(ctx, id)
+	case index.Comments:
+		row, err = c.DB.GetCommentByID(ctx, id)
+	case index.Reports:
+		row, err = c.DB.GetReportByID(ctx, id)
+	default:
+		return 0, false, fmt.Errorf("unknown entity %q", entity)
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return checksum(row), true, nil
+}
+
+func sums[T any](rows []T, err error, id func(T) uuid.UUID) ([]rowSum, error) {
+	if err != nil {
+		return nil, err
+	}
+	result := make([]rowSum, len(rows))
+	for i, row := range rows {
+		result[i] = rowSum{id: id(row), sum: checksum(row)}
+	}
+	return result, nil
+}
+
+// checksum hashes every column of a row. Rows are compared in their JSON form, so a row read from
+// Postgres and the copy the index holds, possibly restored from a snapshot, hash the same.
+func checksum(row any) uint64 {
+	data, err := json.Marshal(row)
+	if err != nil {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write(data)
+	return h.Sum64()
+}
+
+// between returns the IDs of sorted that are greater than after and at most end.
+func between(sorted []uuid.UUID, after, end uuid.UUID) []uuid.UUID {
+	from := sort.Search(len(sorted), func(i int) bool { return bytes.Compare(sorted[i][:], after[:]) > 0 })
+	to := sort.Search(len(sorted), func(i int) bool { return bytes.Compare(sorted[i][:], end[:]) > 0 })
+	if from >= to {
+		return nil
+	}
+	return sorted[from:to]
+}
+
+func dedupe(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := ids[:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func randomID() uuid.UUID {
+	var id uuid.UUID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func allConsistent(reports []Report) bool {
+	for _, report := range reports {
+		if !report.Consistent() {
+			return false
+		}
+	}
+	return true
+}
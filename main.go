@@ -1,18 +1,41 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"log"
 	"net"
 	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	_ "github.com/lib/pq" // Import the postgres driver
 
 	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/alias"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/audit"
+	"github.com/imhasandl/search-service/internal/cache"
+	"github.com/imhasandl/search-service/internal/coalesce"
 	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/langdetect"
+	"github.com/imhasandl/search-service/internal/listener"
+	"github.com/imhasandl/search-service/internal/ratelimit"
+	"github.com/imhasandl/search-service/internal/synonyms"
+	"github.com/imhasandl/search-service/internal/tlsconfig"
+	"github.com/imhasandl/search-service/internal/translit"
+	"github.com/imhasandl/search-service/internal/validate"
+	"github.com/imhasandl/search-service/internal/verify"
 	pb "github.com/imhasandl/search-service/protos"
 	"github.com/joho/godotenv"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -51,15 +74,450 @@ func main() {
 	dbQueries := database.New(dbConn)
 	defer dbConn.Close()
 
-	server := server.NewServer(dbQueries, tokenSecret)
+	languageBackfill := &langdetect.Backfill{
+		DB:        dbQueries,
+		BatchSize: int32(envPositiveInt("LANGUAGE_BACKFILL_BATCH_SIZE", 500)),
+		Interval:  envPositiveDuration("LANGUAGE_BACKFILL_INTERVAL", time.Minute),
+	}
+	go languageBackfill.Run(context.Background())
+
+	fieldAnalyzers, err := analyzer.NewFields(analyzer.NewRegistry(), os.Getenv("SEARCH_FIELD_ANALYZERS"))
+	if err != nil {
+		log.Fatalf("Error configuring analyzers: %v", err)
+	}
+
+	transliterationTable := translit.DefaultTable
+	if tableFile := os.Getenv("TRANSLIT_TABLE_FILE"); tableFile != "" {
+		transliterationTable, err = translit.LoadTable(tableFile)
+		if err != nil {
+			log.Fatalf("Error loading transliteration table: %v", err)
+		}
+	}
+	transliterator := translit.New(transliterationTable)
+
+	usernameBackfill := &translit.Backfill{
+		DB:             dbQueries,
+		Transliterator: transliterator,
+		Analyzer:       fieldAnalyzers.For(analyzer.FieldUsername),
+		BatchSize:      int32(envPositiveInt("TRANSLIT_BACKFILL_BATCH_SIZE", 500)),
+		Interval:       envPositiveDuration("TRANSLIT_BACKFILL_INTERVAL", time.Minute),
+	}
+	go usernameBackfill.Run(context.Background())
+
+	serverOpts := []server.Option{
+		server.WithAnalyzers(fieldAnalyzers),
+		server.WithTransliterator(transliterator),
+		server.WithProfiles(dbQueries),
+	}
+
+	var synonymSource synonyms.Source
+	if synonymsFile := os.Getenv("SYNONYMS_FILE"); synonymsFile != "" {
+		synonymSource = synonyms.FileSource{Path: synonymsFile}
+	} else if os.Getenv("SYNONYMS_FROM_DB") == "true" {
+		synonymSource = synonyms.DBSource{DB: dbQueries}
+	}
+	if synonymSource != nil {
+		synonymStore := synonyms.NewStore(synonymSource, envInt("SYNONYMS_MAX_EXPANSIONS", synonyms.DefaultMaxExpansions))
+		if err := synonymStore.Reload(context.Background()); err != nil {
+			log.Fatalf("Error loading synonyms from %s: %v", synonymSource.Name(), err)
+		}
+		go synonymStore.Watch(context.Background(), envPositiveDuration("SYNONYMS_RELOAD_INTERVAL", time.Minute))
+		serverOpts = append(serverOpts, server.WithSynonyms(synonymStore))
+	}
 
-	s := grpc.NewServer()
+	var resultCache *cache.Cache
+	if size := envInt("SEARCH_CACHE_SIZE", 0); size > 0 {
+		resultCache = cache.New(size,
+			cache.WithTTL(index.Users, envDuration("SEARCH_CACHE_TTL_USERS", cache.DefaultTTL)),
+			cache.WithTTL(index.Posts, envDuration("SEARCH_CACHE_TTL_POSTS", cache.DefaultTTL)),
+			cache.WithTTL(index.Reports, envDuration("SEARCH_CACHE_TTL_REPORTS", cache.DefaultTTL)),
+		)
+		go logCacheStats(context.Background(), resultCache, envDuration("SEARCH_CACHE_STATS_INTERVAL", 5*time.Minute))
+	}
+
+	var searchBackend server.DatabaseQuerier = dbQueries
+	switch backend := os.Getenv("SEARCH_BACKEND"); backend {
+	case "", "postgres":
+		if resultCache != nil {
+			// Cached results are invalidated by the writes the change triggers announce.
+			cacheListener := &listener.Listener{
+				DSN:                  dbURL,
+				Applier:              resultCache,
+				MinReconnectInterval: envDuration("SEARCH_LISTENER_MIN_RECONNECT_INTERVAL", 10*time.Second),
+				MaxReconnectInterval: envDuration("SEARCH_LISTENER_MAX_RECONNECT_INTERVAL", time.Minute),
+				PingInterval:         envPositiveDuration("SEARCH_LISTENER_PING_INTERVAL", 90*time.Second),
+			}
+			if err := cacheListener.Start(); err != nil {
+				log.Fatalf("Error starting search change listener: %v", err)
+			}
+			go cacheListener.Run(context.Background())
+		}
+	case "memory":
+		// Changes reach the cache after the index, so no result older than the index is cached again.
+		indexVersions, applier := startMemoryIndex(context.Background(), dbConn, dbQueries, dbURL, fieldAnalyzers, resultCache)
+		searchBackend = indexVersions
+		serverOpts = append(serverOpts, server.WithIndexVersions(indexVersions), server.WithVerifier(&verify.Checker{
+			DB:        dbQueries,
+			Index:     indexVersions.Live,
+			Applier:   applier,
+			BatchSize: int32(envInt("SEARCH_VERIFY_BATCH_SIZE", 1000)),
+			Settle:    envDuration("SEARCH_VERIFY_SETTLE", 5*time.Second),
+		}))
+	default:
+		log.Fatalf("Unknown SEARCH_BACKEND %q", backend)
+	}
+
+	if resultCache != nil {
+		cachedRPCs := defaultCachedRPCs
+		if rpcs := os.Getenv("SEARCH_CACHE_RPCS"); rpcs != "" {
+			cachedRPCs = strings.Split(rpcs, ",")
+		}
+		serverOpts = append(serverOpts, server.WithCache(resultCache, cachedRPCs...))
+	}
+
+	if os.Getenv("SEARCH_COALESCE") != "false" {
+		serverOpts = append(serverOpts, server.WithCoalescing(&coalesce.Group{}))
+	}
+
+	var auditWriter *audit.Writer
+	stopAuditWriter := func() {}
+	if os.Getenv("SEARCH_AUDIT") != "false" {
+		auditWriter = audit.NewWriter(dbQueries, audit.WithBufferSize(envPositiveInt("SEARCH_AUDIT_BUFFER_SIZE", audit.DefaultBufferSize)))
+		auditCtx, cancelAudit := context.WithCancel(context.Background())
+		auditDone := make(chan struct{})
+		go func() {
+			auditWriter.Run(auditCtx)
+			close(auditDone)
+		}()
+		// Stopping the writer makes it write the entries still queued; it returns once they are written.
+		stopAuditWriter = func() {
+			cancelAudit()
+			<-auditDone
+		}
+		serverOpts = append(serverOpts, server.WithAuditLog(dbQueries))
+	}
+
+	policy, err := server.NewPolicy(os.Getenv("AUTH_POLICY"))
+	if err != nil {
+		log.Fatalf("Error configuring access rules: %v", err)
+	}
+	serverOpts = append(serverOpts, server.WithPolicy(policy))
+
+	if rpcs := os.Getenv("AUTH_PUBLIC_RPCS"); rpcs != "" {
+		publicRPCs := strings.Split(rpcs, ",")
+		if err := server.CheckPublicRPCs(policy, publicRPCs...); err != nil {
+			log.Fatalf("Error configuring public RPCs: %v", err)
+		}
+		serverOpts = append(serverOpts, server.WithPublicRPCs(publicRPCs...))
+	}
+
+	server := server.NewServer(searchBackend, tokenSecret, serverOpts...)
+
+	var unaryInterceptors []grpc.UnaryServerInterceptor
+	// The audit log runs before authentication, so refused calls are recorded too.
+	if auditWriter != nil {
+		auditedRPCs := audit.DefaultRPCs
+		if rpcs := os.Getenv("SEARCH_AUDIT_RPCS"); rpcs != "" {
+			auditedRPCs = strings.Split(rpcs, ",")
+		}
+		unaryInterceptors = append(unaryInterceptors, auditWriter.UnaryServerInterceptor(auditedRPCs...))
+	}
+	unaryInterceptors = append(unaryInterceptors, server.UnaryInterceptor())
+	streamInterceptors := []grpc.StreamServerInterceptor{server.StreamInterceptor()}
+	if os.Getenv("RATE_LIMIT") != "false" {
+		limiter := newRateLimiter(dbQueries)
+		unaryInterceptors = append(unaryInterceptors, limiter.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, limiter.StreamServerInterceptor())
+	}
+
+	validator := validate.New(validate.WithMaxLength(envInt("SEARCH_MAX_INPUT_LENGTH", validate.DefaultMaxLength)))
+	unaryInterceptors = append(unaryInterceptors, validator.UnaryServerInterceptor())
+	streamInterceptors = append(streamInterceptors, validator.StreamServerInterceptor())
+
+	grpcOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	}
+	if certFile := os.Getenv("TLS_CERT_FILE"); certFile != "" {
+		clientAuth := tlsconfig.ClientAuth(os.Getenv("TLS_CLIENT_AUTH"))
+		if clientAuth == "" && os.Getenv("TLS_CLIENT_CA_FILE") != "" {
+			clientAuth = tlsconfig.ClientAuthRequire
+		}
+		certificates, err := tlsconfig.New(tlsconfig.Config{
+			CertFile:     certFile,
+			KeyFile:      os.Getenv("TLS_KEY_FILE"),
+			ClientCAFile: os.Getenv("TLS_CLIENT_CA_FILE"),
+			ClientAuth:   clientAuth,
+		})
+		if err != nil {
+			log.Fatalf("Error loading TLS certificates: %v", err)
+		}
+		go certificates.Run(context.Background(), envPositiveDuration("TLS_RELOAD_INTERVAL", 30*time.Second))
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(certificates.TLSConfig())))
+	} else {
+		log.Printf("TLS_CERT_FILE is not set, serving without TLS")
+	}
+
+	s := grpc.NewServer(grpcOpts...)
 	pb.RegisterSearchServiceServer(s, server)
 
 	reflection.Register(s)
 	log.Printf("Server listening on %v", lis.Addr())
 
+	// On SIGINT or SIGTERM, stop accepting calls and let the running ones finish.
+	shutdownCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	go func() {
+		<-shutdownCtx.Done()
+		log.Printf("Shutting down")
+		s.GracefulStop()
+	}()
+
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("failed to lister: %v", err)
 	}
+	stopAuditWriter()
+}
+
+// newRateLimiter configures the rate limiter from RATE_LIMIT_DEFAULT, RATE_LIMITS and RATE_LIMIT_PREMIUM_FACTOR.
+func newRateLimiter(db ratelimit.PremiumQuerier) *ratelimit.Limiter {
+	premiumFactor := float64(ratelimit.DefaultPremiumFactor)
+	if value := os.Getenv("RATE_LIMIT_PREMIUM_FACTOR"); value != "" {
+		factor, err := strconv.ParseFloat(value, 64)
+		if err != nil || factor <= 0 {
+			log.Fatalf("Invalid RATE_LIMIT_PREMIUM_FACTOR %q", value)
+		}
+		premiumFactor = factor
+	}
+	opts := []ratelimit.Option{ratelimit.WithPremium(db, premiumFactor)}
+
+	if value := os.Getenv("RATE_LIMIT_DEFAULT"); value != "" {
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			log.Fatalf("Invalid RATE_LIMIT_DEFAULT: %v", err)
+		}
+		opts = append(opts, ratelimit.WithDefaultLimit(limit))
+	}
+
+	limits, err := ratelimit.ParseLimits(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMITS: %v", err)
+	}
+	for rpc, limit := range limits {
+		if !isRPC(rpc) {
+			log.Fatalf("Invalid RATE_LIMITS: unknown RPC %q", rpc)
+		}
+		opts = append(opts, ratelimit.WithLimit(rpc, limit))
+	}
+	return ratelimit.New(opts...)
+}
+
+// isRPC reports whether rpc is the name of an RPC of the search service.
+func isRPC(rpc string) bool {
+	for _, method := range pb.SearchService_ServiceDesc.Methods {
+		if method.MethodName == rpc {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultCachedRPCs are the RPCs served through the result cache unless SEARCH_CACHE_RPCS lists others.
+var defaultCachedRPCs = []string{
+	"SearchUsers", "SearchUsersByDate",
+	"SearchPosts", "SearchPostsByDate",
+	"SearchReports", "SearchReportsByDate",
+}
+
+// logCacheStats logs the counters of the result cache every interval.
+func logCacheStats(ctx context.Context, c *cache.Cache, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stats := c.Stats()
+		log.Printf("Search result cache: %d entries, %d evictions, %d invalidations", stats.Entries, stats.Evictions, stats.Invalidations)
+		for _, method := range stats.Methods {
+			log.Printf("Search result cache %s: %d hits, %d misses", method.Method, method.Hits, method.Misses)
+		}
+	}
+}
+
+// startMemoryIndex loads the in-memory index, from the latest snapshot if there is a usable one
+// and from Postgres otherwise, and keeps it up to date with the configured change feed.
+// The index is served as the first version of an alias, so new versions can be built and swapped in.
+// Changes are passed on to resultCache, if there is one, once the index has applied them;
+// the returned applier applies a change to both.
+func startMemoryIndex(ctx context.Context, dbConn *sql.DB, dbQueries *database.Queries, dbURL string, fieldAnalyzers *analyzer.Fields, resultCache *cache.Cache) (*alias.Alias, listener.Applier) {
+	indexOptions := []index.Option{
+		index.WithShards(envInt("SEARCH_INDEX_SHARDS", runtime.NumCPU())),
+		index.WithShardTimeout(envDuration("SEARCH_INDEX_SHARD_TIMEOUT", 200*time.Millisecond)),
+		index.WithTopK(envInt("SEARCH_INDEX_TOP_K", 0)),
+	}
+	searchIndex := index.New(fieldAnalyzers, indexOptions...)
+	indexBatchSize := int32(envPositiveInt("SEARCH_INDEX_BATCH_SIZE", 1000))
+
+	versionName := os.Getenv("SEARCH_INDEX_VERSION")
+	if versionName == "" {
+		versionName = "v1"
+	}
+	indexVersions, err := alias.New(dbQueries, analyzer.NewRegistry(), indexBatchSize, versionName, os.Getenv("SEARCH_FIELD_ANALYZERS"), searchIndex, indexOptions...)
+	if err != nil {
+		log.Fatalf("Invalid SEARCH_INDEX_VERSION %q: %v", versionName, err)
+	}
+
+	var applier listener.Applier = indexVersions
+	if resultCache != nil {
+		applier = listener.Chain{indexVersions, resultCache}
+	}
+
+	snapshotDir := os.Getenv("SEARCH_SNAPSHOT_DIR")
+	var snapshot *index.SnapshotInfo
+	if snapshotDir != "" {
+		info, err := searchIndex.LoadLatestSnapshot(snapshotDir)
+		switch {
+		case err == nil:
+			log.Printf("Loaded search index snapshot %s from %v with %d documents", info.Path, info.CreatedAt, info.Documents)
+			snapshot = &info
+		case errors.Is(err, index.ErrNoSnapshot):
+			log.Printf("No search index snapshot in %s", snapshotDir)
+		default:
+			log.Printf("Error loading search index snapshot: %v", err)
+		}
+	}
+
+	// Subscribe to changes before loading, so changes made during the load are applied afterwards.
+	var (
+		followChanges func(ctx context.Context)
+		highWater     func() index.HighWater
+		replayable    bool
+	)
+	switch feed := os.Getenv("SEARCH_CHANGE_FEED"); feed {
+	case "", "notify":
+		changeListener := &listener.Listener{
+			DSN:                  dbURL,
+			Applier:              applier,
+			MinReconnectInterval: envDuration("SEARCH_LISTENER_MIN_RECONNECT_INTERVAL", 10*time.Second),
+			MaxReconnectInterval: envDuration("SEARCH_LISTENER_MAX_RECONNECT_INTERVAL", time.Minute),
+			PingInterval:         envPositiveDuration("SEARCH_LISTENER_PING_INTERVAL", 90*time.Second),
+		}
+		if err := changeListener.Start(); err != nil {
+			log.Fatalf("Error starting search change listener: %v", err)
+		}
+		followChanges = changeListener.Run
+	case "outbox":
+		// Hostnames change with every redeploy, so the checkpoint name has to be set explicitly.
+		consumerName := os.Getenv("SEARCH_OUTBOX_CONSUMER")
+		if consumerName == "" {
+			log.Fatalf("Set SEARCH_OUTBOX_CONSUMER in env to use the outbox change feed")
+		}
+		outboxConsumer := &listener.Consumer{
+			Name:      consumerName,
+			Outbox:    listener.SQLOutbox{DB: dbConn, Queries: dbQueries},
+			Applier:   applier,
+			BatchSize: int32(envPositiveInt("SEARCH_OUTBOX_BATCH_SIZE", 500)),
+			Interval:  envPositiveDuration("SEARCH_OUTBOX_INTERVAL", time.Second),
+			Retention: envPositiveDuration("SEARCH_OUTBOX_RETENTION", 24*time.Hour),
+		}
+
+		// Entries older than the retention period may be gone, so older snapshots can't be caught up.
+		if snapshot != nil && time.Since(snapshot.CreatedAt) < outboxConsumer.Retention {
+			if err := outboxConsumer.SeekTo(ctx, snapshot.HighWater); err != nil {
+				log.Fatalf("Error positioning search outbox consumer: %v", err)
+			}
+			replayable = true
+		} else if err := outboxConsumer.Seek(ctx); err != nil {
+			log.Fatalf("Error positioning search outbox consumer: %v", err)
+		}
+		followChanges = outboxConsumer.Run
+		highWater = outboxConsumer.Applied
+	default:
+		log.Fatalf("Unknown SEARCH_CHANGE_FEED %q", feed)
+	}
+
+	switch {
+	case snapshot != nil && replayable:
+		// The outbox replays every change since the snapshot.
+		go followChanges(ctx)
+	case snapshot != nil:
+		// Serve the snapshot right away and catch up with a full reload in the background.
+		go func() {
+			if err := applier.Resync(ctx); err != nil {
+				log.Printf("Error reloading search index: %v", err)
+			}
+			followChanges(ctx)
+		}()
+	default:
+		if err := index.Load(ctx, dbQueries, searchIndex, indexBatchSize); err != nil {
+			log.Fatalf("Error loading search index: %v", err)
+		}
+		go followChanges(ctx)
+	}
+
+	if snapshotDir != "" {
+		snapshotter := &index.Snapshotter{
+			Index:     indexVersions.Live,
+			Dir:       snapshotDir,
+			Interval:  envPositiveDuration("SEARCH_SNAPSHOT_INTERVAL", 10*time.Minute),
+			Keep:      envInt("SEARCH_SNAPSHOT_KEEP", 3),
+			HighWater: highWater,
+		}
+		go snapshotter.Run(ctx)
+	}
+	return indexVersions, applier
+}
+
+// envInt reads an optional integer setting, falling back to def when it is unset or invalid.
+func envInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %d", key, value, def)
+		return def
+	}
+	return n
+}
+
+// envPositiveInt reads an optional integer setting like envInt, refusing zero and negative values.
+func envPositiveInt(key string, def int) int {
+	n := envInt(key, def)
+	if n <= 0 {
+		log.Fatalf("Invalid %s %d, it must be positive", key, n)
+	}
+	return n
+}
+
+// envDuration reads an optional duration setting such as "30s", falling back to def when it is unset or invalid.
+func envDuration(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %v", key, value, def)
+		return def
+	}
+	return d
+}
+
+// envPositiveDuration reads an optional duration setting like envDuration, refusing zero and negative values.
+func envPositiveDuration(key string, def time.Duration) time.Duration {
+	d := envDuration(key, def)
+	if d <= 0 {
+		log.Fatalf("Invalid %s %v, it must be positive", key, d)
+	}
+	return d
 }
@@ -24,10 +24,19 @@ const _ = grpc.SupportPackageIsVersion7
 type SearchServiceClient interface {
 	SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (*SearchUsersResponse, error)
 	SearchUsersByDate(ctx context.Context, in *SearchUsersByDateRequest, opts ...grpc.CallOption) (*SearchUsersByDateResponse, error)
+	GetUserProfile(ctx context.Context, in *GetUserProfileRequest, opts ...grpc.CallOption) (*GetUserProfileResponse, error)
 	SearchPosts(ctx context.Context, in *SearchPostsRequest, opts ...grpc.CallOption) (*SearchPostsResponse, error)
 	SearchPostsByDate(ctx context.Context, in *SearchPostsByDateRequest, opts ...grpc.CallOption) (*SearchPostsByDateResponse, error)
 	SearchReports(ctx context.Context, in *SearchReportsRequest, opts ...grpc.CallOption) (*SearchReportsResponse, error)
 	SearchReportsByDate(ctx context.Context, in *SearchReportsByDateRequest, opts ...grpc.CallOption) (*SearchReportsByDateResponse, error)
+	ListSynonyms(ctx context.Context, in *ListSynonymsRequest, opts ...grpc.CallOption) (*ListSynonymsResponse, error)
+	ReloadSynonyms(ctx context.Context, in *ReloadSynonymsRequest, opts ...grpc.CallOption) (*ReloadSynonymsResponse, error)
+	ListIndexVersions(ctx context.Context, in *ListIndexVersionsRequest, opts ...grpc.CallOption) (*ListIndexVersionsResponse, error)
+	BuildIndexVersion(ctx context.Context, in *BuildIndexVersionRequest, opts ...grpc.CallOption) (*BuildIndexVersionResponse, error)
+	SwapIndexVersion(ctx context.Context, in *SwapIndexVersionRequest, opts ...grpc.CallOption) (*SwapIndexVersionResponse, error)
+	RollbackIndexVersion(ctx context.Context, in *RollbackIndexVersionRequest, opts ...grpc.CallOption) (*RollbackIndexVersionResponse, error)
+	VerifyIndex(ctx context.Context, in *VerifyIndexRequest, opts ...grpc.CallOption) (*VerifyIndexResponse, error)
+	ListAuditLog(ctx context.Context, in *ListAuditLogRequest, opts ...grpc.CallOption) (*ListAuditLogResponse, error)
 }
 
 type searchServiceClient struct {
@@ -56,6 +65,15 @@ func (c *searchServiceClient) SearchUsersByDate(ctx context.Context, in *SearchU
 	return out, nil
 }
 
+func (c *searchServiceClient) GetUserProfile(ctx context.Context, in *GetUserProfileRequest, opts ...grpc.CallOption) (*GetUserProfileResponse, error) {
+	out := new(GetUserProfileResponse)
+	err := c.cc.Invoke(ctx, "/search.SearchService/GetUserProfile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *searchServiceClient) SearchPosts(ctx context.Context, in *SearchPostsRequest, opts ...grpc.CallOption) (*SearchPostsResponse, error) {
 	out := new(SearchPostsResponse)
 	err := c.cc.Invoke(ctx, "/search.SearchService/SearchPosts", in, out, opts...)
@@ -92,16 +110,97 @@ func (c *searchServiceClient) SearchReportsByDate(ctx context.Context, in *Searc
 	return out, nil
 }
 
+func (c *searchServiceClient) ListSynonyms(ctx context.Context, in *ListSynonymsRequest, opts ...grpc.CallOption) (*ListSynonymsResponse, error) {
+	out := new(ListSynonymsResponse)
+	err := c.cc.Invoke(ctx, "/search.SearchService/ListSynonyms", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchServiceClient) ReloadSynonyms(ctx context.Context, in *ReloadSynonymsRequest, opts ...grpc.CallOption) (*ReloadSynonymsResponse, error) {
+	out := new(ReloadSynonymsResponse)
+	err := c.cc.Invoke(ctx, "/search.SearchService/ReloadSynonyms", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchServiceClient) ListIndexVersions(ctx context.Context, in *ListIndexVersionsRequest, opts ...grpc.CallOption) (*ListIndexVersionsResponse, error) {
+	out := new(ListIndexVersionsResponse)
+	err := c.cc.Invoke(ctx, "/search.SearchService/ListIndexVersions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchServiceClient) BuildIndexVersion(ctx context.Context, in *BuildIndexVersionRequest, opts ...grpc.CallOption) (*BuildIndexVersionResponse, error) {
+	out := new(BuildIndexVersionResponse)
+	err := c.cc.Invoke(ctx, "/search.SearchService/BuildIndexVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchServiceClient) SwapIndexVersion(ctx context.Context, in *SwapIndexVersionRequest, opts ...grpc.CallOption) (*SwapIndexVersionResponse, error) {
+	out := new(SwapIndexVersionResponse)
+	err := c.cc.Invoke(ctx, "/search.SearchService/SwapIndexVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchServiceClient) RollbackIndexVersion(ctx context.Context, in *RollbackIndexVersionRequest, opts ...grpc.CallOption) (*RollbackIndexVersionResponse, error) {
+	out := new(RollbackIndexVersionResponse)
+	err := c.cc.Invoke(ctx, "/search.SearchService/RollbackIndexVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchServiceClient) VerifyIndex(ctx context.Context, in *VerifyIndexRequest, opts ...grpc.CallOption) (*VerifyIndexResponse, error) {
+	out := new(VerifyIndexResponse)
+	err := c.cc.Invoke(ctx, "/search.SearchService/VerifyIndex", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchServiceClient) ListAuditLog(ctx context.Context, in *ListAuditLogRequest, opts ...grpc.CallOption) (*ListAuditLogResponse, error) {
+	out := new(ListAuditLogResponse)
+	err := c.cc.Invoke(ctx, "/search.SearchService/ListAuditLog", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SearchServiceServer is the server API for SearchService service.
 // All implementations must embed UnimplementedSearchServiceServer
 // for forward compatibility
 type SearchServiceServer interface {
 	SearchUsers(context.Context, *SearchUsersRequest) (*SearchUsersResponse, error)
 	SearchUsersByDate(context.Context, *SearchUsersByDateRequest) (*SearchUsersByDateResponse, error)
+	GetUserProfile(context.Context, *GetUserProfileRequest) (*GetUserProfileResponse, error)
 	SearchPosts(context.Context, *SearchPostsRequest) (*SearchPostsResponse, error)
 	SearchPostsByDate(context.Context, *SearchPostsByDateRequest) (*SearchPostsByDateResponse, error)
 	SearchReports(context.Context, *SearchReportsRequest) (*SearchReportsResponse, error)
 	SearchReportsByDate(context.Context, *SearchReportsByDateRequest) (*SearchReportsByDateResponse, error)
+	ListSynonyms(context.Context, *ListSynonymsRequest) (*ListSynonymsResponse, error)
+	ReloadSynonyms(context.Context, *ReloadSynonymsRequest) (*ReloadSynonymsResponse, error)
+	ListIndexVersions(context.Context, *ListIndexVersionsRequest) (*ListIndexVersionsResponse, error)
+	BuildIndexVersion(context.Context, *BuildIndexVersionRequest) (*BuildIndexVersionResponse, error)
+	SwapIndexVersion(context.Context, *SwapIndexVersionRequest) (*SwapIndexVersionResponse, error)
+	RollbackIndexVersion(context.Context, *RollbackIndexVersionRequest) (*RollbackIndexVersionResponse, error)
+	VerifyIndex(context.Context, *VerifyIndexRequest) (*VerifyIndexResponse, error)
+	ListAuditLog(context.Context, *ListAuditLogRequest) (*ListAuditLogResponse, error)
 	mustEmbedUnimplementedSearchServiceServer()
 }
 
@@ -115,6 +214,9 @@ func (UnimplementedSearchServiceServer) SearchUsers(context.Context, *SearchUser
 func (UnimplementedSearchServiceServer) SearchUsersByDate(context.Context, *SearchUsersByDateRequest) (*SearchUsersByDateResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SearchUsersByDate not implemented")
 }
+func (UnimplementedSearchServiceServer) GetUserProfile(context.Context, *GetUserProfileRequest) (*GetUserProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserProfile not implemented")
+}
 func (UnimplementedSearchServiceServer) SearchPosts(context.Context, *SearchPostsRequest) (*SearchPostsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SearchPosts not implemented")
 }
@@ -127,6 +229,30 @@ func (UnimplementedSearchServiceServer) SearchReports(context.Context, *SearchRe
 func (UnimplementedSearchServiceServer) SearchReportsByDate(context.Context, *SearchReportsByDateRequest) (*SearchReportsByDateResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SearchReportsByDate not implemented")
 }
+func (UnimplementedSearchServiceServer) ListSynonyms(context.Context, *ListSynonymsRequest) (*ListSynonymsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSynonyms not implemented")
+}
+func (UnimplementedSearchServiceServer) ReloadSynonyms(context.Context, *ReloadSynonymsRequest) (*ReloadSynonymsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReloadSynonyms not implemented")
+}
+func (UnimplementedSearchServiceServer) ListIndexVersions(context.Context, *ListIndexVersionsRequest) (*ListIndexVersionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListIndexVersions not implemented")
+}
+func (UnimplementedSearchServiceServer) BuildIndexVersion(context.Context, *BuildIndexVersionRequest) (*BuildIndexVersionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BuildIndexVersion not implemented")
+}
+func (UnimplementedSearchServiceServer) SwapIndexVersion(context.Context, *SwapIndexVersionRequest) (*SwapIndexVersionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SwapIndexVersion not implemented")
+}
+func (UnimplementedSearchServiceServer) RollbackIndexVersion(context.Context, *RollbackIndexVersionRequest) (*RollbackIndexVersionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RollbackIndexVersion not implemented")
+}
+func (UnimplementedSearchServiceServer) VerifyIndex(context.Context, *VerifyIndexRequest) (*VerifyIndexResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyIndex not implemented")
+}
+func (UnimplementedSearchServiceServer) ListAuditLog(context.Context, *ListAuditLogRequest) (*ListAuditLogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditLog not implemented")
+}
 func (UnimplementedSearchServiceServer) mustEmbedUnimplementedSearchServiceServer() {}
 
 // UnsafeSearchServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -176,6 +302,24 @@ func _SearchService_SearchUsersByDate_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _SearchService_GetUserProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).GetUserProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.SearchService/GetUserProfile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).GetUserProfile(ctx, req.(*GetUserProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _SearchService_SearchPosts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SearchPostsRequest)
 	if err := dec(in); err != nil {
@@ -248,6 +392,150 @@ func _SearchService_SearchReportsByDate_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _SearchService_ListSynonyms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSynonymsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).ListSynonyms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.SearchService/ListSynonyms",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).ListSynonyms(ctx, req.(*ListSynonymsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SearchService_ReloadSynonyms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadSynonymsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).ReloadSynonyms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.SearchService/ReloadSynonyms",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).ReloadSynonyms(ctx, req.(*ReloadSynonymsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SearchService_ListIndexVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListIndexVersionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).ListIndexVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.SearchService/ListIndexVersions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).ListIndexVersions(ctx, req.(*ListIndexVersionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SearchService_BuildIndexVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BuildIndexVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).BuildIndexVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.SearchService/BuildIndexVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).BuildIndexVersion(ctx, req.(*BuildIndexVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SearchService_SwapIndexVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SwapIndexVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).SwapIndexVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.SearchService/SwapIndexVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).SwapIndexVersion(ctx, req.(*SwapIndexVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SearchService_RollbackIndexVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackIndexVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).RollbackIndexVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.SearchService/RollbackIndexVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).RollbackIndexVersion(ctx, req.(*RollbackIndexVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SearchService_VerifyIndex_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyIndexRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).VerifyIndex(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.SearchService/VerifyIndex",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).VerifyIndex(ctx, req.(*VerifyIndexRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SearchService_ListAuditLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAuditLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).ListAuditLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.SearchService/ListAuditLog",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).ListAuditLog(ctx, req.(*ListAuditLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // SearchService_ServiceDesc is the grpc.ServiceDesc for SearchService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -263,6 +551,10 @@ var SearchService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SearchUsersByDate",
 			Handler:    _SearchService_SearchUsersByDate_Handler,
 		},
+		{
+			MethodName: "GetUserProfile",
+			Handler:    _SearchService_GetUserProfile_Handler,
+		},
 		{
 			MethodName: "SearchPosts",
 			Handler:    _SearchService_SearchPosts_Handler,
@@ -279,6 +571,38 @@ var SearchService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SearchReportsByDate",
 			Handler:    _SearchService_SearchReportsByDate_Handler,
 		},
+		{
+			MethodName: "ListSynonyms",
+			Handler:    _SearchService_ListSynonyms_Handler,
+		},
+		{
+			MethodName: "ReloadSynonyms",
+			Handler:    _SearchService_ReloadSynonyms_Handler,
+		},
+		{
+			MethodName: "ListIndexVersions",
+			Handler:    _SearchService_ListIndexVersions_Handler,
+		},
+		{
+			MethodName: "BuildIndexVersion",
+			Handler:    _SearchService_BuildIndexVersion_Handler,
+		},
+		{
+			MethodName: "SwapIndexVersion",
+			Handler:    _SearchService_SwapIndexVersion_Handler,
+		},
+		{
+			MethodName: "RollbackIndexVersion",
+			Handler:    _SearchService_RollbackIndexVersion_Handler,
+		},
+		{
+			MethodName: "VerifyIndex",
+			Handler:    _SearchService_VerifyIndex_Handler,
+		},
+		{
+			MethodName: "ListAuditLog",
+			Handler:    _SearchService_ListAuditLog_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "search.proto",
@@ -0,0 +1,12 @@
+-- filters is passed as text, lib/pq would send bytes as bytea.
+-- name: InsertAuditEntry :exec
+INSERT INTO search_audit_log (created_at, actor, rpc, query, filters, result_count, status)
+VALUES ($1, $2, $3, $4, $5::text::jsonb, $6, $7);
+
+-- name: ListAuditEntries :many
+SELECT * FROM search_audit_log
+WHERE (sqlc.arg(actor)::text = '' OR actor = sqlc.arg(actor))
+  AND created_at >= sqlc.arg(since)
+  AND created_at < sqlc.arg(until)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit);
@@ -0,0 +1,18 @@
+-- name: ListCommentsAfter :many
+SELECT * FROM comments
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: GetCommentByID :one
+SELECT * FROM comments
+WHERE id = $1;
+
+-- name: CountCommentsAfter :one
+SELECT COUNT(*) FROM comments
+WHERE id > $1;
+
+-- name: TouchComments :exec
+UPDATE comments
+SET id = id
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
@@ -0,0 +1,36 @@
+-- name: EnsureOutboxCheckpoint :exec
+INSERT INTO search_outbox_checkpoints (consumer)
+VALUES ($1)
+ON CONFLICT (consumer) DO NOTHING;
+
+-- name: LockOutboxCheckpoint :one
+SELECT * FROM search_outbox_checkpoints
+WHERE consumer = $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: SetOutboxCheckpoint :exec
+UPDATE search_outbox_checkpoints
+SET last_txid = $2, last_id = $3, updated_at = CURRENT_TIMESTAMP
+WHERE consumer = $1;
+
+-- name: ListOutboxAfter :many
+SELECT * FROM search_outbox
+WHERE (txid, id) > (sqlc.arg(last_txid)::bigint, sqlc.arg(last_id)::bigint)
+  AND txid < txid_snapshot_xmin(txid_current_snapshot())
+ORDER BY txid, id
+LIMIT sqlc.arg(batch_size);
+
+-- name: GetOutboxHead :one
+SELECT txid, id FROM search_outbox
+WHERE txid < txid_snapshot_xmin(txid_current_snapshot())
+ORDER BY txid DESC, id DESC
+LIMIT 1;
+
+-- name: DeleteOutboxBefore :exec
+DELETE FROM search_outbox
+WHERE created_at < $1;
+
+-- name: DeleteOutboxCheckpointsBefore :exec
+DELETE FROM search_outbox_checkpoints
+WHERE updated_at < sqlc.arg(updated_at)
+  AND consumer <> sqlc.arg(consumer);
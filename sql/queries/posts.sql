@@ -1,8 +1,46 @@
 -- name: SearchPosts :many
 SELECT * FROM posts
-WHERE body LIKE $1 || '%';
+WHERE body LIKE like_escape($1) || '%'
+   OR to_tsvector(search_text_config(language), body) @@ search_tsquery($1);
 
 -- name: SearchPostsByDate :many
 SELECT * FROM posts
-WHERE body LIKE $1 || '%'
-ORDER BY created_at;
\ No newline at end of file
+WHERE body LIKE like_escape($1) || '%'
+   OR to_tsvector(search_text_config(language), body) @@ search_tsquery($1)
+ORDER BY created_at;
+
+-- name: SearchPostsByLanguage :many
+SELECT * FROM posts
+WHERE language = sqlc.narg(language)
+  AND (body LIKE like_escape(sqlc.narg(query)::text) || '%'
+   OR to_tsvector(search_text_config(language), body) @@ search_tsquery(sqlc.narg(query)::text));
+
+-- name: SearchPostsBySubstring :many
+SELECT * FROM posts
+WHERE body ILIKE '%' || like_escape(sqlc.arg(pattern)::text) || '%'
+  AND (sqlc.narg(language)::text IS NULL OR language = sqlc.narg(language))
+ORDER BY created_at DESC, id;
+
+-- name: ListPostsWithoutLanguage :many
+SELECT id, body FROM posts
+WHERE language IS NULL
+LIMIT $1;
+
+-- name: SetPostLanguage :exec
+UPDATE posts
+SET language = $2
+WHERE id = $1;
+
+-- name: ListPostsAfter :many
+SELECT * FROM posts
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: GetPostByID :one
+SELECT * FROM posts
+WHERE id = $1;
+
+-- name: CountPostsAfter :one
+SELECT COUNT(*) FROM posts
+WHERE id > $1;
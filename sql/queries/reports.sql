@@ -1,8 +1,29 @@
 -- name: SearchReports :many
 SELECT * FROM reports
-WHERE reported_by LIKE $1 || '%';
+WHERE reported_by::text LIKE like_escape($1) || '%'
+   OR to_tsvector('simple', reason) @@ plainto_tsquery('simple', $1);
 
 -- name: SearchReportsByDate :many
 SELECT * FROM reports
-WHERE reported_by LIKE $1 || '%'
-ORDER BY reported_at;
\ No newline at end of file
+WHERE reported_by::text LIKE like_escape($1) || '%'
+   OR to_tsvector('simple', reason) @@ plainto_tsquery('simple', $1)
+ORDER BY reported_at;
+
+-- name: ListReportsAfter :many
+SELECT * FROM reports
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: GetReportByID :one
+SELECT * FROM reports
+WHERE id = $1;
+
+-- name: CountReportsAfter :one
+SELECT COUNT(*) FROM reports
+WHERE id > $1;
+
+-- name: TouchReports :exec
+UPDATE reports
+SET id = id
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
@@ -0,0 +1,3 @@
+-- name: ListSynonyms :many
+SELECT * FROM search_synonyms
+ORDER BY id;
@@ -1,8 +1,50 @@
 -- name: SearchUsers :many
-SELECT * FROM users
-WHERE username LIKE $1 || '%';
+SELECT * FROM search_users
+WHERE username LIKE like_escape($1) || '%'
+   OR username_latin LIKE like_escape($1) || '%'
+   OR username_cyrillic LIKE like_escape($1) || '%';
 
 -- name: SearchUsersByDate :many
-SELECT * FROM users
-WHERE username LIKE $1 || '%'
-ORDER BY created_at;
\ No newline at end of file
+SELECT * FROM search_users
+WHERE username LIKE like_escape($1) || '%'
+   OR username_latin LIKE like_escape($1) || '%'
+   OR username_cyrillic LIKE like_escape($1) || '%'
+ORDER BY created_at;
+
+-- name: ListUsersWithoutTransliteration :many
+SELECT id, username FROM users
+WHERE username_latin IS NULL OR username_phonetic IS NULL
+LIMIT $1;
+
+-- name: SetUsernameTransliteration :exec
+UPDATE users
+SET username_latin = $2, username_cyrillic = $3, username_phonetic = $4
+WHERE id = $1;
+
+-- name: SearchUsersByPhonetic :many
+SELECT * FROM search_users
+WHERE username_phonetic = $1
+ORDER BY username, id;
+
+-- name: ListSearchUsersAfter :many
+SELECT * FROM search_users
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: GetSearchUserByID :one
+SELECT * FROM search_users
+WHERE id = $1;
+
+-- name: CountUsersAfter :one
+SELECT COUNT(*) FROM users
+WHERE id > $1;
+
+-- name: GetUserProfile :one
+SELECT id, created_at, updated_at, email, username, is_premium, verification_code, is_verified
+FROM users
+WHERE id = $1;
+
+-- name: IsUserPremium :one
+SELECT is_premium FROM users
+WHERE id = $1;
@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE search_synonyms (
+   id SERIAL PRIMARY KEY,
+   terms TEXT[] NOT NULL,
+   synonyms TEXT[] NOT NULL DEFAULT '{}',
+   one_way BOOLEAN NOT NULL DEFAULT FALSE,
+   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE search_synonyms;
@@ -0,0 +1,35 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN language TEXT;
+
+-- search_text_config maps a detected language code to the Postgres text search
+-- configuration used to stem and filter stopwords for that language.
+-- Uzbek has no built-in configuration, so it falls back to 'simple'.
+-- +goose StatementBegin
+CREATE FUNCTION search_text_config(language TEXT) RETURNS regconfig AS $$
+   SELECT CASE language
+      WHEN 'en' THEN 'english'::regconfig
+      WHEN 'ru' THEN 'russian'::regconfig
+      ELSE 'simple'::regconfig
+   END
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+-- search_tsquery builds a query that matches a post stemmed with any of the configurations,
+-- since the language of a short query can't be detected reliably.
+-- +goose StatementBegin
+CREATE FUNCTION search_tsquery(query TEXT) RETURNS tsquery AS $$
+   SELECT plainto_tsquery('english', query)
+       || plainto_tsquery('russian', query)
+       || plainto_tsquery('simple', query)
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+CREATE INDEX idx_posts_body_tsv ON posts USING GIN (to_tsvector(search_text_config(language), body));
+CREATE INDEX idx_posts_language ON posts(language);
+
+-- +goose Down
+DROP INDEX idx_posts_language;
+DROP INDEX idx_posts_body_tsv;
+DROP FUNCTION search_tsquery(TEXT);
+DROP FUNCTION search_text_config(TEXT);
+ALTER TABLE posts DROP COLUMN language;
@@ -0,0 +1,32 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN username_latin TEXT;
+ALTER TABLE users ADD COLUMN username_cyrillic TEXT;
+
+CREATE INDEX idx_users_username_latin ON users(username_latin text_pattern_ops);
+CREATE INDEX idx_users_username_cyrillic ON users(username_cyrillic text_pattern_ops);
+
+-- Transliterations are computed by the search service, so a rename clears them
+-- and the service fills them in again.
+-- +goose StatementBegin
+CREATE FUNCTION reset_username_transliteration() RETURNS trigger AS $$
+BEGIN
+   IF NEW.username IS DISTINCT FROM OLD.username THEN
+      NEW.username_latin := NULL;
+      NEW.username_cyrillic := NULL;
+   END IF;
+   RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER users_reset_username_transliteration
+BEFORE UPDATE OF username ON users
+FOR EACH ROW EXECUTE FUNCTION reset_username_transliteration();
+
+-- +goose Down
+DROP TRIGGER users_reset_username_transliteration ON users;
+DROP FUNCTION reset_username_transliteration();
+DROP INDEX idx_users_username_cyrillic;
+DROP INDEX idx_users_username_latin;
+ALTER TABLE users DROP COLUMN username_cyrillic;
+ALTER TABLE users DROP COLUMN username_latin;
@@ -0,0 +1,41 @@
+-- +goose Up
+-- Every change to a searchable table is announced on the search_changes channel
+-- so running search services can update their in-memory indexes.
+-- +goose StatementBegin
+CREATE FUNCTION notify_search_change() RETURNS trigger AS $$
+DECLARE
+   changed_id UUID;
+BEGIN
+   IF TG_OP = 'DELETE' THEN
+      changed_id := OLD.id;
+   ELSE
+      changed_id := NEW.id;
+   END IF;
+   PERFORM pg_notify('search_changes', json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'id', changed_id)::text);
+   RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER users_notify_search_change
+AFTER INSERT OR UPDATE OR DELETE ON users
+FOR EACH ROW EXECUTE FUNCTION notify_search_change();
+
+CREATE TRIGGER posts_notify_search_change
+AFTER INSERT OR UPDATE OR DELETE ON posts
+FOR EACH ROW EXECUTE FUNCTION notify_search_change();
+
+CREATE TRIGGER comments_notify_search_change
+AFTER INSERT OR UPDATE OR DELETE ON comments
+FOR EACH ROW EXECUTE FUNCTION notify_search_change();
+
+CREATE TRIGGER reports_notify_search_change
+AFTER INSERT OR UPDATE OR DELETE ON reports
+FOR EACH ROW EXECUTE FUNCTION notify_search_change();
+
+-- +goose Down
+DROP TRIGGER reports_notify_search_change ON reports;
+DROP TRIGGER comments_notify_search_change ON comments;
+DROP TRIGGER posts_notify_search_change ON posts;
+DROP TRIGGER users_notify_search_change ON users;
+DROP FUNCTION notify_search_change();
@@ -0,0 +1,63 @@
+-- +goose Up
+-- Changes to searchable tables are also recorded in an outbox, so a search service that was
+-- down when they happened can catch up. txid orders entries by the transaction that wrote them.
+CREATE TABLE search_outbox (
+   id BIGSERIAL PRIMARY KEY,
+   txid BIGINT NOT NULL DEFAULT txid_current(),
+   table_name TEXT NOT NULL,
+   op TEXT NOT NULL,
+   row_id UUID NOT NULL,
+   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_search_outbox_position ON search_outbox(txid, id);
+CREATE INDEX idx_search_outbox_created_at ON search_outbox(created_at);
+
+-- How far each consumer has read the outbox.
+CREATE TABLE search_outbox_checkpoints (
+   consumer TEXT PRIMARY KEY,
+   last_txid BIGINT NOT NULL DEFAULT 0,
+   last_id BIGINT NOT NULL DEFAULT 0,
+   updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose StatementBegin
+CREATE FUNCTION enqueue_search_change() RETURNS trigger AS $$
+DECLARE
+   changed_id UUID;
+BEGIN
+   IF TG_OP = 'DELETE' THEN
+      changed_id := OLD.id;
+   ELSE
+      changed_id := NEW.id;
+   END IF;
+   INSERT INTO search_outbox (table_name, op, row_id) VALUES (TG_TABLE_NAME, TG_OP, changed_id);
+   RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER users_enqueue_search_change
+AFTER INSERT OR UPDATE OR DELETE ON users
+FOR EACH ROW EXECUTE FUNCTION enqueue_search_change();
+
+CREATE TRIGGER posts_enqueue_search_change
+AFTER INSERT OR UPDATE OR DELETE ON posts
+FOR EACH ROW EXECUTE FUNCTION enqueue_search_change();
+
+CREATE TRIGGER comments_enqueue_search_change
+AFTER INSERT OR UPDATE OR DELETE ON comments
+FOR EACH ROW EXECUTE FUNCTION enqueue_search_change();
+
+CREATE TRIGGER reports_enqueue_search_change
+AFTER INSERT OR UPDATE OR DELETE ON reports
+FOR EACH ROW EXECUTE FUNCTION enqueue_search_change();
+
+-- +goose Down
+DROP TRIGGER reports_enqueue_search_change ON reports;
+DROP TRIGGER comments_enqueue_search_change ON comments;
+DROP TRIGGER posts_enqueue_search_change ON posts;
+DROP TRIGGER users_enqueue_search_change ON users;
+DROP FUNCTION enqueue_search_change();
+DROP TABLE search_outbox_checkpoints;
+DROP TABLE search_outbox;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- The B-tree on posts.body only serves prefix LIKE; a trigram index serves
+-- LIKE and ILIKE with the pattern anywhere in the body.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_posts_body_trgm ON posts USING GIN (body gin_trgm_ops);
+
+-- like_escape escapes the LIKE wildcards of a user supplied pattern, so it matches literally.
+-- +goose StatementBegin
+CREATE FUNCTION like_escape(pattern TEXT) RETURNS TEXT AS $$
+   SELECT replace(replace(replace(pattern, '\', '\\'), '%', '\%'), '_', '\_')
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION like_escape(TEXT);
+DROP INDEX idx_posts_body_trgm;
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,35 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN username_phonetic TEXT;
+
+CREATE INDEX idx_users_username_phonetic ON users(username_phonetic);
+
+-- Phonetic codes are computed by the search service alongside the transliterations,
+-- so a rename clears them as well.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION reset_username_transliteration() RETURNS trigger AS $$
+BEGIN
+   IF NEW.username IS DISTINCT FROM OLD.username THEN
+      NEW.username_latin := NULL;
+      NEW.username_cyrillic := NULL;
+      NEW.username_phonetic := NULL;
+   END IF;
+   RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION reset_username_transliteration() RETURNS trigger AS $$
+BEGIN
+   IF NEW.username IS DISTINCT FROM OLD.username THEN
+      NEW.username_latin := NULL;
+      NEW.username_cyrillic := NULL;
+   END IF;
+   RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP INDEX idx_users_username_phonetic;
+ALTER TABLE users DROP COLUMN username_phonetic;
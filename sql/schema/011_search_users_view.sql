@@ -0,0 +1,10 @@
+-- +goose Up
+-- The columns of users that search reads and returns. Search queries select from this
+-- view, so passwords and verification codes never leave the database through them.
+CREATE VIEW search_users AS
+SELECT id, created_at, updated_at, email, username, is_premium, is_verified,
+       username_latin, username_cyrillic, username_phonetic
+FROM users;
+
+-- +goose Down
+DROP VIEW search_users;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Search results no longer include emails, so the search projection drops them.
+DROP VIEW search_users;
+CREATE VIEW search_users AS
+SELECT id, created_at, updated_at, username, is_premium, is_verified,
+       username_latin, username_cyrillic, username_phonetic
+FROM users;
+
+-- +goose Down
+DROP VIEW search_users;
+CREATE VIEW search_users AS
+SELECT id, created_at, updated_at, email, username, is_premium, is_verified,
+       username_latin, username_cyrillic, username_phonetic
+FROM users;
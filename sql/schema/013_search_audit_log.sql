@@ -0,0 +1,33 @@
+-- +goose Up
+-- Sensitive searches are recorded for auditing. Rows are only ever inserted: a trigger
+-- rejects updates and deletes, so the trail can't be rewritten through the application role.
+CREATE TABLE search_audit_log (
+   id BIGSERIAL PRIMARY KEY,
+   created_at TIMESTAMP NOT NULL,
+   actor TEXT NOT NULL,
+   rpc TEXT NOT NULL,
+   query TEXT NOT NULL,
+   filters JSONB NOT NULL DEFAULT '{}',
+   result_count INTEGER NOT NULL,
+   status TEXT NOT NULL
+);
+
+CREATE INDEX idx_search_audit_log_actor ON search_audit_log(actor, created_at);
+CREATE INDEX idx_search_audit_log_created_at ON search_audit_log(created_at);
+
+-- +goose StatementBegin
+CREATE FUNCTION reject_search_audit_log_change() RETURNS trigger AS $$
+BEGIN
+   RAISE EXCEPTION 'search_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER search_audit_log_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON search_audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION reject_search_audit_log_change();
+
+-- +goose Down
+DROP TRIGGER search_audit_log_append_only ON search_audit_log;
+DROP FUNCTION reject_search_audit_log_change();
+DROP TABLE search_audit_log;
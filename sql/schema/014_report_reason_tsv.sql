@@ -0,0 +1,6 @@
+-- +goose Up
+-- Report searches match the reason by its words, like the in-memory index does.
+CREATE INDEX idx_reports_reason_tsv ON reports USING GIN (to_tsvector('simple', reason));
+
+-- +goose Down
+DROP INDEX idx_reports_reason_tsv;
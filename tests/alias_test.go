@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/alias"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/listener"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// gatedStore holds the first page of users until gate is closed, so a build can be caught mid-load.
+type gatedStore struct {
+	rowStore
+	gate chan struct{}
+}
+
+func (s *gatedStore) ListSearchUsersAfter(ctx context.Context, arg database.ListSearchUsersAfterParams) ([]database.SearchUser, error) {
+	<-s.gate
+	return s.rowStore.ListSearchUsersAfter(ctx, arg)
+}
+
+func newTestAlias(t *testing.T) (*alias.Alias, *gatedStore) {
+	t.Helper()
+	store := &gatedStore{gate: make(chan struct{})}
+	store.posts = []database.Post{{ID: orderedID(1), Body: "Running with dogs"}}
+
+	live := newTestIndex()
+	require.NoError(t, index.Load(context.Background(), &store.rowStore, live, 10))
+	a, err := alias.New(store, analyzer.NewRegistry(), 10, "v1", "", live)
+	require.NoError(t, err)
+	return a, store
+}
+
+func waitReady(t *testing.T, a *alias.Alias, name string) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		for _, v := range a.Versions() {
+			if v.Name == name {
+				return v.Status == alias.Ready
+			}
+		}
+		return false
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestAliasBuildSwapRollback(t *testing.T) {
+	a, store := newTestAlias(t)
+
+	// "with" is a stopword for the standard analyzer, but not for the simple one.
+	building, err := a.Build(context.Background(), "v2", analyzer.FieldPostBody+"=simple")
+	require.NoError(t, err)
+	assert.Equal(t, alias.Building, building.Status)
+	posts, err := a.SearchPosts(context.Background(), validQuery("with"))
+	require.NoError(t, err)
+	assert.Empty(t, posts, "searches keep hitting the live version during a build")
+
+	close(store.gate)
+	waitReady(t, a, "v2")
+
+	live, previous, err := a.Swap("v2")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", live.Name)
+	assert.Equal(t, "v1", previous.Name)
+	assert.Equal(t, 1, live.Documents[index.Posts])
+	assert.Equal(t, "simple", a.Analyzers().For(analyzer.FieldPostBody).Name, "analyzers are swapped with the index")
+	posts, err = a.SearchPosts(context.Background(), validQuery("with"))
+	require.NoError(t, err)
+	assert.Len(t, posts, 1)
+
+	live, previous, err = a.Rollback()
+	require.NoError(t, err)
+	assert.Equal(t, "v1", live.Name)
+	assert.Equal(t, "v2", previous.Name)
+	posts, err = a.SearchPosts(context.Background(), validQuery("with"))
+	require.NoError(t, err)
+	assert.Empty(t, posts)
+}
+
+func TestAliasErrors(t *testing.T) {
+	a, _ := newTestAlias(t)
+
+	_, err := a.Build(context.Background(), "V 2", "")
+	assert.ErrorIs(t, err, alias.ErrInvalidName)
+	_, err = a.Build(context.Background(), "v1", "")
+	assert.ErrorIs(t, err, alias.ErrVersionExists)
+	_, err = a.Build(context.Background(), "v2", analyzer.FieldPostBody+"=klingon")
+	assert.ErrorIs(t, err, alias.ErrInvalidFields)
+
+	_, err = a.Build(context.Background(), "v2", "")
+	require.NoError(t, err)
+	_, _, err = a.Swap("v2")
+	assert.ErrorIs(t, err, alias.ErrNotReady)
+	_, _, err = a.Swap("v3")
+	assert.ErrorIs(t, err, alias.ErrUnknownVersion)
+	_, _, err = a.Rollback()
+	assert.ErrorIs(t, err, alias.ErrNoPrevious)
+}
+
+func TestAliasAppliesChangesToEveryVersion(t *testing.T) {
+	a, store := newTestAlias(t)
+	_, err := a.Build(context.Background(), "v2", "")
+	require.NoError(t, err)
+
+	// The post is still in the table the build reads, but the delete is queued and applied once loading is done.
+	require.NoError(t, a.Apply(context.Background(), listener.Change{Entity: index.Posts, Op: listener.Delete, ID: orderedID(1)}))
+	assert.Zero(t, a.Live().Count(index.Posts))
+
+	close(store.gate)
+	waitReady(t, a, "v2")
+	live, _, err := a.Swap("v2")
+	require.NoError(t, err)
+	assert.Zero(t, live.Documents[index.Posts])
+}
+
+func TestIndexVersionRPCs(t *testing.T) {
+	a, store := newTestAlias(t)
+	close(store.gate)
+	testServer := server.NewServer(a, "test-secret", server.WithIndexVersions(a))
+	ctx := context.Background()
+
+	built, err := testServer.BuildIndexVersion(ctx, &pb.BuildIndexVersionRequest{Name: "v2", Analyzers: analyzer.FieldPostBody + "=simple"})
+	require.NoError(t, err)
+	assert.Equal(t, "v2", built.Version.Name)
+	waitReady(t, a, "v2")
+
+	_, err = testServer.BuildIndexVersion(ctx, &pb.BuildIndexVersionRequest{Name: "v2"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = testServer.BuildIndexVersion(ctx, &pb.BuildIndexVersionRequest{Name: "V 3"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "invalid version name")
+	_, err = testServer.BuildIndexVersion(ctx, &pb.BuildIndexVersionRequest{Name: "v3", Analyzers: analyzer.FieldPostBody + "=klingon"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "invalid analyzers")
+	_, err = testServer.SwapIndexVersion(ctx, &pb.SwapIndexVersionRequest{Name: "v9"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = testServer.RollbackIndexVersion(ctx, &pb.RollbackIndexVersionRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	swapped, err := testServer.SwapIndexVersion(ctx, &pb.SwapIndexVersionRequest{Name: "v2"})
+	require.NoError(t, err)
+	assert.True(t, swapped.Live.Live)
+	assert.True(t, swapped.Previous.Previous)
+
+	list, err := testServer.ListIndexVersions(ctx, &pb.ListIndexVersionsRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.Versions, 2)
+	assert.Equal(t, "v2", list.Versions[0].Name)
+	assert.Equal(t, "ready", list.Versions[0].Status)
+	assert.Equal(t, int64(1), list.Versions[0].Documents["posts"])
+	assert.NotNil(t, list.Versions[0].BuiltAt)
+
+	resp, err := testServer.SearchPosts(ctx, &pb.SearchPostsRequest{Query: "with"})
+	require.NoError(t, err)
+	assert.Len(t, resp.Post, 1, "searches go to the swapped-in version")
+
+	withoutVersions := server.NewServer(a, "test-secret")
+	_, err = withoutVersions.ListIndexVersions(ctx, &pb.ListIndexVersionsRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStandardAnalyzer(t *testing.T) {
+	standard, ok := analyzer.NewRegistry().Get("standard")
+	require.True(t, ok)
+
+	testCases := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{name: "lowercase and stopwords", text: "The Quick Fox", expected: []string{"quick", "fox"}},
+		{name: "english stemming", text: "running dogs jumped", expected: []string{"run", "dog", "jump"}},
+		{name: "russian stemming", text: "Книги и книга", expected: []string{"книг", "книг"}},
+		{name: "accent folding", text: "Café crème", expected: []string{"cafe", "creme"}},
+		{name: "nfkc", text: "Ｈｅｌｌｏ ﬁle", expected: []string{"hello", "file"}},
+		{name: "emoji are terms", text: "party🎉time 👍🏽", expected: []string{"party", "🎉", "time", "👍🏽"}},
+		{name: "apostrophes inside words", text: "o'zbek 'quoted'", expected: []string{"o'zbek", "quot"}},
+		{name: "cyrillic marks are kept", text: "Йогурт", expected: []string{"йогурт"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, standard.Terms(tc.text))
+		})
+	}
+}
+
+func TestStemmers(t *testing.T) {
+	assert.Equal(t, "run", analyzer.Stem("en", "running"))
+	assert.Equal(t, "pony", analyzer.Stem("en", "ponies"))
+	assert.Equal(t, "class", analyzer.Stem("en", "classes"))
+	assert.Equal(t, "create", analyzer.Stem("en", "created"))
+	assert.Equal(t, "книг", analyzer.Stem("ru", "книгами"))
+	assert.Equal(t, "kitob", analyzer.Stem("uz", "kitoblarning"))
+	assert.Equal(t, "maktab", analyzer.Stem("uz", "maktabga"))
+	assert.Equal(t, "books", analyzer.Stem("de", "books"))
+}
+
+func TestKeywordAnalyzer(t *testing.T) {
+	keyword, ok := analyzer.NewRegistry().Get("keyword")
+	require.True(t, ok)
+
+	assert.Equal(t, []string{"jose doe"}, keyword.Terms("José Doe"))
+	assert.Equal(t, "jose doe", keyword.Normalize("José Doe"))
+	assert.Empty(t, keyword.Terms(""))
+}
+
+func TestFieldAnalyzers(t *testing.T) {
+	fields, err := analyzer.NewFields(analyzer.NewRegistry(), "posts.body=english, reports.reason=simple")
+	require.NoError(t, err)
+	assert.Equal(t, "english", fields.For(analyzer.FieldPostBody).Name)
+	assert.Equal(t, "simple", fields.For(analyzer.FieldReportReason).Name)
+	assert.Equal(t, "keyword", fields.For(analyzer.FieldUsername).Name)
+	assert.Equal(t, "standard", fields.For(analyzer.FieldCommentText).Name)
+
+	_, err = analyzer.NewFields(analyzer.NewRegistry(), "posts.title=standard")
+	assert.Error(t, err)
+	_, err = analyzer.NewFields(analyzer.NewRegistry(), "posts.body=klingon")
+	assert.Error(t, err)
+	_, err = analyzer.NewFields(analyzer.NewRegistry(), "posts.body")
+	assert.Error(t, err)
+}
+
+func TestCustomAnalyzerChain(t *testing.T) {
+	registry := analyzer.NewRegistry()
+	registry.Register(analyzer.New("no-emoji", analyzer.UnicodeTokenizer{}, analyzer.Lowercase{}, analyzer.EmojiFilter{Drop: true}))
+
+	fields, err := analyzer.NewFields(registry, "comments.comment_text=no-emoji")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nice", "shot"}, fields.For(analyzer.FieldCommentText).Terms("Nice 🔥 shot"))
+
+	fields, err = analyzer.NewFields(registry, "comments.comment_text=standard_no_emoji")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nice", "shot"}, fields.For(analyzer.FieldCommentText).Terms("Nice 🔥 shot"))
+}
@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/audit"
+	"github.com/imhasandl/search-service/internal/auth"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/mocks"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeAuditStore records the entries written to it. Until release is closed, writes block.
+type fakeAuditStore struct {
+	mu      sync.Mutex
+	entries []database.InsertAuditEntryParams
+	release chan struct{}
+	err     error
+}
+
+func (s *fakeAuditStore) InsertAuditEntry(ctx context.Context, arg database.InsertAuditEntryParams) error {
+	if s.release != nil {
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.entries = append(s.entries, arg)
+	return nil
+}
+
+func (s *fakeAuditStore) written() []database.InsertAuditEntryParams {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]database.InsertAuditEntryParams(nil), s.entries...)
+}
+
+// runWriter runs w until the test ends, waiting for it to flush.
+func runWriter(t *testing.T, w *audit.Writer) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func callAudited(ctx context.Context, w *audit.Writer, rpc string, req any, resp any, err error) {
+	_, _ = w.UnaryServerInterceptor(audit.DefaultRPCs...)(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/search.SearchService/" + rpc},
+		func(context.Context, any) (any, error) { return resp, err })
+}
+
+func TestAuditInterceptorRecordsSensitiveSearches(t *testing.T) {
+	store := &fakeAuditStore{}
+	w := audit.NewWriter(store)
+	runWriter(t, w)
+	moderator := auth.NewContext(context.Background(), auth.Principal{Subject: "moderator-1", Roles: []string{auth.RoleModerator}})
+
+	before := time.Now()
+	callAudited(moderator, w, "SearchReports", &pb.SearchReportsRequest{Query: "spam"},
+		&pb.SearchReportsResponse{Report: []*pb.Report{{Id: "1"}, {Id: "2"}}}, nil)
+	callAudited(moderator, w, "GetUserProfile", &pb.GetUserProfileRequest{Id: "user-7"}, nil,
+		status.Error(codes.NotFound, "user not found"))
+	callAudited(context.Background(), w, "GetUserProfile", &pb.GetUserProfileRequest{Id: "user-8"},
+		&pb.GetUserProfileResponse{User: &pb.User{Id: "user-8"}}, nil)
+	// Public searches aren't audited.
+	callAudited(moderator, w, "SearchPosts", &pb.SearchPostsRequest{Query: "hello", Language: "en"}, &pb.SearchPostsResponse{}, nil)
+
+	require.Eventually(t, func() bool { return len(store.written()) == 3 }, time.Second, time.Millisecond)
+	entries := store.written()
+
+	assert.Equal(t, "moderator-1", entries[0].Actor)
+	assert.Equal(t, "SearchReports", entries[0].Rpc)
+	assert.Equal(t, "spam", entries[0].Query)
+	assert.Equal(t, int32(2), entries[0].ResultCount)
+	assert.Equal(t, "OK", entries[0].Status)
+	assert.Equal(t, "{}", entries[0].Filters)
+	assert.False(t, entries[0].CreatedAt.Before(before))
+
+	assert.Equal(t, "user-7", entries[1].Query)
+	assert.Equal(t, int32(0), entries[1].ResultCount)
+	assert.Equal(t, "NotFound", entries[1].Status)
+
+	assert.Equal(t, audit.Anonymous, entries[2].Actor)
+	assert.Equal(t, int32(1), entries[2].ResultCount)
+}
+
+func TestAuditInterceptorRecordsFilters(t *testing.T) {
+	store := &fakeAuditStore{}
+	w := audit.NewWriter(store)
+	runWriter(t, w)
+
+	_, _ = w.UnaryServerInterceptor("SearchPosts")(context.Background(),
+		&pb.SearchPostsRequest{Query: "hello", Language: "en", Mode: pb.PostSearchMode_POST_SEARCH_MODE_SUBSTRING},
+		&grpc.UnaryServerInfo{FullMethod: "/search.SearchService/SearchPosts"},
+		func(context.Context, any) (any, error) { return &pb.SearchPostsResponse{}, nil })
+
+	require.Eventually(t, func() bool { return len(store.written()) == 1 }, time.Second, time.Millisecond)
+	var filters map[string]string
+	require.NoError(t, json.Unmarshal([]byte(store.written()[0].Filters), &filters))
+	assert.Equal(t, map[string]string{"language": "en", "mode": "POST_SEARCH_MODE_SUBSTRING"}, filters)
+}
+
+func TestAuditInterceptorRecordsRefusedCalls(t *testing.T) {
+	store := &fakeAuditStore{}
+	w := audit.NewWriter(store)
+	runWriter(t, w)
+	testServer := server.NewServer(mocks.NewMockQueries(), testSecret)
+
+	// The audit interceptor runs first, like in main.
+	audited := w.UnaryServerInterceptor(audit.DefaultRPCs...)
+	call := func(ctx context.Context, rpc string) {
+		info := &grpc.UnaryServerInfo{FullMethod: "/search.SearchService/" + rpc}
+		_, _ = audited(ctx, &pb.GetUserProfileRequest{Id: "user-7"}, info, func(ctx context.Context, req any) (any, error) {
+			return testServer.UnaryInterceptor()(ctx, req, info, func(context.Context, any) (any, error) {
+				return &pb.GetUserProfileResponse{}, nil
+			})
+		})
+	}
+	call(withBearer(signToken(t, jwt.SigningMethodHS256, testSecret, userClaims("user-1"))), "GetUserProfile")
+	call(context.Background(), "GetUserProfile")
+	call(withBearer(signToken(t, jwt.SigningMethodHS256, testSecret, userClaims("admin-1", auth.RoleAdmin))), "GetUserProfile")
+
+	require.Eventually(t, func() bool { return len(store.written()) == 3 }, time.Second, time.Millisecond)
+	entries := store.written()
+	assert.Equal(t, "user-1", entries[0].Actor, "the actor is read from the token of a refused call")
+	assert.Equal(t, "PermissionDenied", entries[0].Status)
+	assert.Equal(t, audit.Anonymous, entries[1].Actor)
+	assert.Equal(t, "Unauthenticated", entries[1].Status)
+	assert.Equal(t, "admin-1", entries[2].Actor)
+	assert.Equal(t, "OK", entries[2].Status)
+}
+
+func TestAuditWriterIgnoresEmptyBuffers(t *testing.T) {
+	w := audit.NewWriter(&fakeAuditStore{}, audit.WithBufferSize(0))
+	w.Record(audit.Entry{Actor: "moderator-1", RPC: "SearchReports", Time: time.Now()})
+	assert.Zero(t, w.Dropped(), "the default buffer is kept")
+}
+
+func TestAuditWriterNeverBlocks(t *testing.T) {
+	store := &fakeAuditStore{release: make(chan struct{})}
+	w := audit.NewWriter(store, audit.WithBufferSize(2))
+	runWriter(t, w)
+
+	// The store is stuck: one entry is being written, two are buffered and the rest are dropped.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			w.Record(audit.Entry{Actor: "moderator-1", RPC: "SearchReports", Time: time.Now()})
+			time.Sleep(time.Millisecond)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Record blocked on a stuck store")
+	}
+	assert.GreaterOrEqual(t, w.Dropped(), int64(7))
+
+	close(store.release)
+	require.Eventually(t, func() bool { return int64(len(store.written()))+w.Dropped() == 10 }, time.Second, time.Millisecond)
+}
+
+func TestAuditWriterFlushesOnStop(t *testing.T) {
+	store := &fakeAuditStore{}
+	w := audit.NewWriter(store)
+	for i := 0; i < 5; i++ {
+		w.Record(audit.Entry{Actor: "moderator-1", RPC: "SearchReports", Time: time.Now()})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w.Run(ctx)
+	assert.Len(t, store.written(), 5)
+}
+
+func TestAuditWriterCountsFailures(t *testing.T) {
+	store := &fakeAuditStore{err: errors.New("database is down")}
+	w := audit.NewWriter(store)
+	w.Record(audit.Entry{Actor: "moderator-1", RPC: "SearchReports", Time: time.Now()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w.Run(ctx)
+	assert.Equal(t, int64(1), w.Failed())
+}
+
+func TestListAuditLog(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	testServer := server.NewServer(mockDB, "test-secret", server.WithAuditLog(mockDB))
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)
+
+	mockDB.On("ListAuditEntries", mock.Anything, database.ListAuditEntriesParams{
+		Actor: "moderator-1", Since: since, Until: until, RowLimit: 100,
+	}).Return([]database.SearchAuditLog{{
+		ID:          7,
+		CreatedAt:   since.Add(time.Hour),
+		Actor:       "moderator-1",
+		Rpc:         "SearchPosts",
+		Query:       "hello",
+		Filters:     json.RawMessage(`{"language": "en"}`),
+		ResultCount: 3,
+		Status:      "OK",
+	}}, nil).Once()
+
+	resp, err := testServer.ListAuditLog(context.Background(), &pb.ListAuditLogRequest{
+		Actor: "moderator-1",
+		Since: timestamppb.New(since),
+		Until: timestamppb.New(until),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Entries, 1)
+	assert.Equal(t, int64(7), resp.Entries[0].Id)
+	assert.Equal(t, "SearchPosts", resp.Entries[0].Rpc)
+	assert.Equal(t, map[string]string{"language": "en"}, resp.Entries[0].Filters)
+	assert.Equal(t, int32(3), resp.Entries[0].ResultCount)
+	mockDB.AssertExpectations(t)
+
+	for _, req := range []*pb.ListAuditLogRequest{
+		{Since: timestamppb.New(until), Until: timestamppb.New(since)},
+		{Limit: 1001},
+		{Limit: -1},
+	} {
+		_, err := testServer.ListAuditLog(context.Background(), req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	_, err = server.NewServer(mockDB, "test-secret").ListAuditLog(context.Background(), &pb.ListAuditLogRequest{Actor: uuid.NewString()})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
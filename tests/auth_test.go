@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/auth"
+	"github.com/imhasandl/search-service/internal/mocks"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testSecret = "test-secret"
+
+func signToken(t *testing.T, method jwt.SigningMethod, secret string, claims auth.Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return token
+}
+
+func userClaims(subject string, roles ...string) auth.Claims {
+	return auth.Claims{
+		Roles: roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+}
+
+func withBearer(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+// callUnary runs a unary call of method through the server's interceptor and returns the principal the handler saw.
+func callUnary(ctx context.Context, s server.Server, method string) (auth.Principal, bool, error) {
+	var principal auth.Principal
+	var ok bool
+	_, err := s.UnaryInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, _ any) (any, error) {
+		principal, ok = auth.FromContext(ctx)
+		return nil, nil
+	})
+	return principal, ok, err
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	testServer := server.NewServer(mocks.NewMockQueries(), testSecret, server.WithPublicRPCs("SearchPosts"))
+	const method = "/search.SearchService/SearchUsers"
+
+	expired := userClaims("user-1")
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	noExpiry := userClaims("user-1")
+	noExpiry.ExpiresAt = nil
+
+	testCases := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{name: "no metadata", ctx: context.Background()},
+		{name: "not a bearer token", ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Basic dXNlcg=="))},
+		{name: "wrong secret", ctx: withBearer(signToken(t, jwt.SigningMethodHS256, "other-secret", userClaims("user-1")))},
+		{name: "wrong algorithm", ctx: withBearer(signToken(t, jwt.SigningMethodHS512, testSecret, userClaims("user-1")))},
+		{name: "expired", ctx: withBearer(signToken(t, jwt.SigningMethodHS256, testSecret, expired))},
+		{name: "no expiry", ctx: withBearer(signToken(t, jwt.SigningMethodHS256, testSecret, noExpiry))},
+		{name: "no subject", ctx: withBearer(signToken(t, jwt.SigningMethodHS256, testSecret, userClaims("")))},
+		{name: "malformed", ctx: withBearer("not.a.token")},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := callUnary(tc.ctx, testServer, method)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		ctx := withBearer(signToken(t, jwt.SigningMethodHS256, testSecret, userClaims("user-1", auth.RoleAdmin)))
+		principal, ok, err := callUnary(ctx, testServer, method)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "user-1", principal.Subject)
+		assert.True(t, principal.HasRole(auth.RoleAdmin))
+	})
+
+	t.Run("public RPC without token", func(t *testing.T) {
+		_, ok, err := callUnary(context.Background(), testServer, "/search.SearchService/SearchPosts")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("public RPC with an invalid token", func(t *testing.T) {
+		_, _, err := callUnary(withBearer("not.a.token"), testServer, "/search.SearchService/SearchPosts")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestAuthInterceptorWithoutSecret(t *testing.T) {
+	testServer := server.NewServer(mocks.NewMockQueries(), "")
+
+	ctx := withBearer(signToken(t, jwt.SigningMethodHS256, "", userClaims("user-1")))
+	_, _, err := callUnary(ctx, testServer, "/search.SearchService/SearchUsers")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestAuthStreamInterceptor(t *testing.T) {
+	testServer := server.NewServer(mocks.NewMockQueries(), testSecret,
+		server.WithPublicRPCs("/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"))
+
+	call := func(ctx context.Context, method string) (auth.Principal, bool, error) {
+		var principal auth.Principal
+		var ok bool
+		err := testServer.StreamInterceptor()(nil, fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: method},
+			func(_ any, stream grpc.ServerStream) error {
+				principal, ok = auth.FromContext(stream.Context())
+				return nil
+			})
+		return principal, ok, err
+	}
+
+	_, _, err := call(context.Background(), "/search.SearchService/SearchUsers")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	principal, ok, err := call(withBearer(signToken(t, jwt.SigningMethodHS256, testSecret, userClaims("user-2"))), "/search.SearchService/SearchUsers")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "user-2", principal.Subject)
+
+	_, ok, err = call(context.Background(), "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestAuthPolicy(t *testing.T) {
+	testServer := server.NewServer(mocks.NewMockQueries(), testSecret)
+	token := func(roles ...string) context.Context {
+		return withBearer(signToken(t, jwt.SigningMethodHS256, testSecret, userClaims("user-1", roles...)))
+	}
+
+	testCases := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		code   codes.Code
+	}{
+		{name: "user searches users", ctx: token(), method: "SearchUsers", code: codes.OK},
+		{name: "user searches reports", ctx: token(), method: "SearchReports", code: codes.PermissionDenied},
+		{name: "user searches reports by date", ctx: token(auth.RoleUser), method: "SearchReportsByDate", code: codes.PermissionDenied},
+		{name: "moderator searches reports", ctx: token(auth.RoleModerator), method: "SearchReports", code: codes.OK},
+		{name: "moderator reads a profile", ctx: token(auth.RoleModerator), method: "GetUserProfile", code: codes.PermissionDenied},
+		{name: "admin searches reports", ctx: token(auth.RoleAdmin), method: "SearchReportsByDate", code: codes.OK},
+		{name: "admin reads a profile", ctx: token(auth.RoleAdmin), method: "GetUserProfile", code: codes.OK},
+		{name: "unknown role", ctx: token("superuser"), method: "VerifyIndex", code: codes.PermissionDenied},
+		{name: "RPC without a rule", ctx: token(auth.RoleAdmin), method: "Unknown", code: codes.PermissionDenied},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := callUnary(tc.ctx, testServer, "/search.SearchService/"+tc.method)
+			assert.Equal(t, tc.code, status.Code(err))
+		})
+	}
+}
+
+func TestDefaultPolicyCoversEveryRPC(t *testing.T) {
+	for _, method := range pb.SearchService_ServiceDesc.Methods {
+		assert.Contains(t, server.DefaultPolicy, method.MethodName)
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	policy, err := server.NewPolicy("SearchReports=admin, SearchPosts = moderator")
+	require.NoError(t, err)
+	assert.Equal(t, auth.RoleAdmin, policy["/search.SearchService/SearchReports"])
+	assert.Equal(t, auth.RoleModerator, policy["/search.SearchService/SearchPosts"])
+	assert.Equal(t, auth.RoleModerator, policy["/search.SearchService/SearchReportsByDate"])
+
+	for _, overrides := range []string{"SearchReports", "SearchReport=admin", "SearchReports=owner", "GetUserProfile=moderator", "VerifyIndex=user"} {
+		_, err := server.NewPolicy(overrides)
+		assert.Error(t, err, overrides)
+	}
+
+	testServer := server.NewServer(mocks.NewMockQueries(), testSecret, server.WithPolicy(policy))
+	ctx := withBearer(signToken(t, jwt.SigningMethodHS256, testSecret, userClaims("user-1", auth.RoleModerator)))
+	_, _, err = callUnary(ctx, testServer, "/search.SearchService/SearchReports")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestAdminRPCsAreNeverPublic(t *testing.T) {
+	policy, err := server.NewPolicy("")
+	require.NoError(t, err)
+	assert.NoError(t, server.CheckPublicRPCs(policy, "SearchPosts", "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"))
+	assert.Error(t, server.CheckPublicRPCs(policy, "SearchPosts", "GetUserProfile"))
+	assert.Error(t, server.CheckPublicRPCs(policy, "/search.SearchService/ListAuditLog"))
+
+	testServer := server.NewServer(mocks.NewMockQueries(), testSecret, server.WithPublicRPCs("SearchPosts", "GetUserProfile"))
+	_, _, err = callUnary(context.Background(), testServer, "/search.SearchService/SearchPosts")
+	require.NoError(t, err)
+	_, _, err = callUnary(context.Background(), testServer, "/search.SearchService/GetUserProfile")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
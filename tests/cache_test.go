@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/cache"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/listener"
+	"github.com/imhasandl/search-service/internal/mocks"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheServesRepeatedSearches(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	querier := cache.New(10).Wrap(mockDB)
+
+	john := database.SearchUser{ID: uuid.New(), Username: "john"}
+	mockDB.On("SearchUsers", mock.Anything, validQuery("jo")).Return([]database.SearchUser{john}, nil).Once()
+
+	for i := 0; i < 3; i++ {
+		users, err := querier.SearchUsers(context.Background(), validQuery("jo"))
+		require.NoError(t, err)
+		assert.Equal(t, []database.SearchUser{john}, users)
+	}
+	mockDB.AssertExpectations(t)
+}
+
+func TestCacheDoesNotChangeResults(t *testing.T) {
+	ix := newTestIndex()
+	reporter := uuid.MustParse("3f2a1b2c-1234-4321-8765-0123456789ab")
+	ix.PutPost(database.Post{ID: orderedID(1), Body: "Hello World"})
+	ix.PutPost(database.Post{ID: orderedID(2), Body: "hello whales"})
+	ix.PutPost(database.Post{ID: orderedID(3), Body: "50% off"})
+	ix.PutReport(database.Report{ID: orderedID(4), ReportedBy: reporter, Reason: "spam"})
+	querier := cache.New(100).Wrap(ix)
+
+	// Queries that analyze alike run one after the other, so a shared entry would show.
+	for _, query := range []string{"Hello W", "hello w", "hello", "50%", "50"} {
+		for i := 0; i < 2; i++ {
+			want, err := ix.SearchPosts(context.Background(), validQuery(query))
+			require.NoError(t, err)
+			got, err := querier.SearchPosts(context.Background(), validQuery(query))
+			require.NoError(t, err)
+			assert.Equal(t, postIDs(want), postIDs(got), query)
+		}
+	}
+	for _, query := range []string{"3f2a1b2c-1234", "3f2a1b2c 1234", "spam"} {
+		for i := 0; i < 2; i++ {
+			want, err := ix.SearchReports(context.Background(), validQuery(query))
+			require.NoError(t, err)
+			got, err := querier.SearchReports(context.Background(), validQuery(query))
+			require.NoError(t, err)
+			assert.Equal(t, want, got, query)
+		}
+	}
+}
+
+func TestCacheKeysIncludeFilters(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	c := cache.New(10)
+	querier := c.Wrap(mockDB)
+
+	english := database.SearchPostsByLanguageParams{Query: validQuery("run"), Language: validQuery("en")}
+	russian := database.SearchPostsByLanguageParams{Query: validQuery("run"), Language: validQuery("ru")}
+	mockDB.On("SearchPostsByLanguage", mock.Anything, english).Return([]database.Post{{ID: orderedID(1)}}, nil).Once()
+	mockDB.On("SearchPostsByLanguage", mock.Anything, russian).Return([]database.Post{}, nil).Once()
+	mockDB.On("SearchPosts", mock.Anything, sql.NullString{}).Return([]database.Post{}, nil).Once()
+	mockDB.On("SearchPosts", mock.Anything, validQuery("")).Return([]database.Post{{ID: orderedID(2)}}, nil).Once()
+
+	for i := 0; i < 2; i++ {
+		posts, err := querier.SearchPostsByLanguage(context.Background(), english)
+		require.NoError(t, err)
+		assert.Len(t, posts, 1)
+		posts, err = querier.SearchPostsByLanguage(context.Background(), russian)
+		require.NoError(t, err)
+		assert.Empty(t, posts)
+		posts, err = querier.SearchPosts(context.Background(), sql.NullString{})
+		require.NoError(t, err)
+		assert.Empty(t, posts, "a NULL query is not an empty one")
+		posts, err = querier.SearchPosts(context.Background(), validQuery(""))
+		require.NoError(t, err)
+		assert.Len(t, posts, 1)
+	}
+	mockDB.AssertExpectations(t)
+
+	stats := c.Stats()
+	require.Len(t, stats.Methods, 2)
+	assert.Equal(t, cache.MethodStats{Method: "SearchPosts", Hits: 2, Misses: 2}, stats.Methods[0])
+	assert.Equal(t, cache.MethodStats{Method: "SearchPostsByLanguage", Hits: 2, Misses: 2}, stats.Methods[1])
+	assert.Equal(t, 4, stats.Entries)
+}
+
+func TestCacheExpiresPerEntity(t *testing.T) {
+	now := time.Now()
+	mockDB := mocks.NewMockQueries()
+	c := cache.New(10,
+		cache.WithTTL(index.Users, time.Minute),
+		cache.WithTTL(index.Reports, 0),
+		cache.WithClock(func() time.Time { return now }),
+	)
+	querier := c.Wrap(mockDB)
+
+	mockDB.On("SearchUsers", mock.Anything, validQuery("jo")).Return([]database.SearchUser{}, nil).Twice()
+	mockDB.On("SearchReports", mock.Anything, validQuery("spam")).Return([]database.Report{}, nil).Times(3)
+
+	search := func() {
+		_, err := querier.SearchUsers(context.Background(), validQuery("jo"))
+		require.NoError(t, err)
+		_, err = querier.SearchReports(context.Background(), validQuery("spam"))
+		require.NoError(t, err)
+	}
+	search()
+	now = now.Add(30 * time.Second)
+	search() // users cached, reports never cached
+	now = now.Add(31 * time.Second)
+	search() // users expired
+	mockDB.AssertExpectations(t)
+}
+
+func TestCacheInvalidatesOnChanges(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	c := cache.New(10)
+	querier := c.Wrap(mockDB)
+
+	mockDB.On("SearchUsers", mock.Anything, validQuery("jo")).Return([]database.SearchUser{}, nil).Twice()
+	mockDB.On("SearchReports", mock.Anything, validQuery("spam")).Return([]database.Report{}, nil).Once()
+
+	search := func() {
+		_, err := querier.SearchUsers(context.Background(), validQuery("jo"))
+		require.NoError(t, err)
+		_, err = querier.SearchReports(context.Background(), validQuery("spam"))
+		require.NoError(t, err)
+	}
+	search()
+	require.NoError(t, c.Apply(context.Background(), listener.Change{Entity: index.Users, Op: listener.Update, ID: uuid.New()}))
+	search() // only users are searched again
+	search()
+	mockDB.AssertExpectations(t)
+
+	mockDB.On("SearchUsers", mock.Anything, validQuery("jo")).Return([]database.SearchUser{}, nil).Once()
+	mockDB.On("SearchReports", mock.Anything, validQuery("spam")).Return([]database.Report{}, nil).Once()
+	require.NoError(t, c.Resync(context.Background()))
+	search()
+	mockDB.AssertExpectations(t)
+	assert.Equal(t, int64(2), c.Stats().Invalidations)
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	c := cache.New(2)
+	querier := c.Wrap(mockDB)
+
+	mockDB.On("SearchUsers", mock.Anything, mock.Anything).Return([]database.SearchUser{}, nil)
+	for _, query := range []string{"a", "b", "a", "c", "a", "b"} {
+		_, err := querier.SearchUsers(context.Background(), validQuery(query))
+		require.NoError(t, err)
+	}
+
+	// "b" was evicted by "c" and searched again, evicting "a" after its last use.
+	mockDB.AssertNumberOfCalls(t, "SearchUsers", 4)
+	stats := c.Stats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, int64(2), stats.Evictions)
+}
+
+func TestCacheSkipsPartialResults(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	querier := cache.New(10).Wrap(mockDB)
+
+	mockDB.On("SearchPosts", mock.Anything, validQuery("run")).
+		Run(func(args mock.Arguments) { index.MarkPartial(args.Get(0).(context.Context)) }).
+		Return([]database.Post{}, nil).Twice()
+
+	for i := 0; i < 2; i++ {
+		ctx, partial := index.TrackPartial(context.Background())
+		_, err := querier.SearchPosts(ctx, validQuery("run"))
+		require.NoError(t, err)
+		assert.True(t, partial.Load(), "the caller still learns the results are partial")
+	}
+	mockDB.AssertExpectations(t)
+}
+
+func TestServerCachesSelectedRPCs(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	testServer := server.NewServer(mockDB, "test-secret", server.WithCache(cache.New(10), "SearchReports"))
+
+	mockDB.On("SearchReports", mock.Anything, validQuery("spam")).Return([]database.Report{}, nil).Once()
+	mockDB.On("SearchReportsByDate", mock.Anything, validQuery("spam")).Return([]database.Report{}, nil).Twice()
+
+	for i := 0; i < 2; i++ {
+		_, err := testServer.SearchReports(context.Background(), &pb.SearchReportsRequest{Query: "spam"})
+		require.NoError(t, err)
+		_, err = testServer.SearchReportsByDate(context.Background(), &pb.SearchReportsByDateRequest{Query: "spam"})
+		require.NoError(t, err)
+	}
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/coalesce"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/mocks"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const callers = 50
+
+// waitForWaiters blocks until n callers wait on the group.
+func waitForWaiters(t *testing.T, g *coalesce.Group, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool { return g.Waiters() == n }, 5*time.Second, time.Millisecond)
+}
+
+func TestCoalesceSharesOneCall(t *testing.T) {
+	var g coalesce.Group
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	results := make([]int, callers)
+	shared := make([]bool, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, isShared, err := coalesce.Do(context.Background(), &g, "key", func(context.Context) (int, error) {
+				calls.Add(1)
+				<-release
+				return 42, nil
+			})
+			assert.NoError(t, err)
+			results[i], shared[i] = result, isShared
+		}(i)
+	}
+	waitForWaiters(t, &g, callers)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for i := range results {
+		assert.Equal(t, 42, results[i])
+		assert.True(t, shared[i])
+	}
+	assert.Equal(t, 0, g.Waiters())
+}
+
+func TestCoalesceKeysAreIndependent(t *testing.T) {
+	var g coalesce.Group
+	var calls atomic.Int32
+	search := func(context.Context) (int, error) {
+		calls.Add(1)
+		return 0, errors.New("boom")
+	}
+
+	for _, key := range []string{"a", "b", "a"} {
+		_, shared, err := coalesce.Do(context.Background(), &g, key, search)
+		assert.EqualError(t, err, "boom")
+		assert.False(t, shared)
+	}
+	assert.Equal(t, int32(3), calls.Load(), "calls that don't overlap are not shared")
+}
+
+func TestCoalesceCallerCancellation(t *testing.T) {
+	var g coalesce.Group
+	release := make(chan struct{})
+	var callCtx context.Context
+	started := make(chan struct{})
+	search := func(ctx context.Context) (string, error) {
+		callCtx = ctx
+		close(started)
+		select {
+		case <-release:
+			return "rows", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, _, err := coalesce.Do(leaderCtx, &g, "key", search)
+		leaderErr <- err
+	}()
+	<-started
+
+	followerResult := make(chan string, 1)
+	go func() {
+		result, _, err := coalesce.Do(context.Background(), &g, "key", search)
+		assert.NoError(t, err)
+		followerResult <- result
+	}()
+	waitForWaiters(t, &g, 2)
+
+	// The caller that started the query gives up; the query keeps running for the other one.
+	cancelLeader()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+	assert.NoError(t, callCtx.Err())
+
+	close(release)
+	assert.Equal(t, "rows", <-followerResult)
+}
+
+func TestCoalesceCancelsWhenEveryCallerLeaves(t *testing.T) {
+	var g coalesce.Group
+	cancelled := make(chan struct{})
+	started := make(chan struct{})
+	search := func(ctx context.Context) (int, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return 0, ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := coalesce.Do(ctx, &g, "key", search)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		}()
+	}
+	wg.Wait()
+
+	<-started
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the shared call was not cancelled after every caller left")
+	}
+
+	// A new caller starts a new call instead of getting the cancelled one.
+	result, _, err := coalesce.Do(context.Background(), &g, "key", func(context.Context) (int, error) { return 7, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 7, result)
+}
+
+func TestCoalescePassesPartialResults(t *testing.T) {
+	var g coalesce.Group
+	ctx, partial := index.TrackPartial(context.Background())
+	_, _, err := coalesce.Do(ctx, &g, "key", func(ctx context.Context) (int, error) {
+		index.MarkPartial(ctx)
+		return 0, nil
+	})
+	require.NoError(t, err)
+	assert.True(t, partial.Load())
+}
+
+func TestServerCoalescesSearchPosts(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	group := &coalesce.Group{}
+	testServer := server.NewServer(mockDB, "test-secret", server.WithCoalescing(group))
+
+	release := make(chan struct{})
+	mockDB.On("SearchPosts", mock.Anything, validQuery("golang")).
+		Run(func(mock.Arguments) { <-release }).
+		Return([]database.Post{{ID: orderedID(1), Body: "golang"}}, nil).Once()
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := testServer.SearchPosts(context.Background(), &pb.SearchPostsRequest{Query: "golang"})
+			assert.NoError(t, err)
+			assert.Len(t, resp.GetPost(), 1)
+		}()
+	}
+	waitForWaiters(t, group, callers)
+	close(release)
+	wg.Wait()
+
+	mockDB.AssertNumberOfCalls(t, "SearchPosts", 1)
+}
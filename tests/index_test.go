@@ -0,0 +1,253 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// indexSource serves List*After queries from slices sorted by ID.
+type indexSource struct {
+	users    []database.SearchUser
+	posts    []database.Post
+	comments []database.Comment
+	reports  []database.Report
+	calls    int
+}
+
+func after[T any](rows []T, id func(T) uuid.UUID, from uuid.UUID, limit int32) []T {
+	var page []T
+	for _, row := range rows {
+		if id(row).String() > from.String() && int32(len(page)) < limit {
+			page = append(page, row)
+		}
+	}
+	return page
+}
+
+func (s *indexSource) ListSearchUsersAfter(ctx context.Context, arg database.ListSearchUsersAfterParams) ([]database.SearchUser, error) {
+	s.calls++
+	return after(s.users, func(u database.SearchUser) uuid.UUID { return u.ID }, arg.ID, arg.Limit), nil
+}
+
+func (s *indexSource) ListPostsAfter(ctx context.Context, arg database.ListPostsAfterParams) ([]database.Post, error) {
+	s.calls++
+	return after(s.posts, func(p database.Post) uuid.UUID { return p.ID }, arg.ID, arg.Limit), nil
+}
+
+func (s *indexSource) ListCommentsAfter(ctx context.Context, arg database.ListCommentsAfterParams) ([]database.Comment, error) {
+	s.calls++
+	return after(s.comments, func(c database.Comment) uuid.UUID { return c.ID }, arg.ID, arg.Limit), nil
+}
+
+func (s *indexSource) ListReportsAfter(ctx context.Context, arg database.ListReportsAfterParams) ([]database.Report, error) {
+	s.calls++
+	return after(s.reports, func(r database.Report) uuid.UUID { return r.ID }, arg.ID, arg.Limit), nil
+}
+
+func orderedID(n byte) uuid.UUID {
+	return uuid.UUID{15: n}
+}
+
+func newTestIndex() *index.Index {
+	return index.New(analyzer.DefaultFields())
+}
+
+func validQuery(q string) sql.NullString {
+	return sql.NullString{String: q, Valid: true}
+}
+
+func TestIndexSearchPostsRanking(t *testing.T) {
+	ix := newTestIndex()
+	now := time.Now()
+	ix.PutPost(database.Post{ID: orderedID(1), CreatedAt: now, Body: "Running with my dog in the big green park today"})
+	ix.PutPost(database.Post{ID: orderedID(2), CreatedAt: now.Add(-time.Hour), Body: "Dogs, dogs and more dogs"})
+	ix.PutPost(database.Post{ID: orderedID(3), CreatedAt: now, Body: "Cats are great"})
+
+	posts, err := ix.SearchPosts(context.Background(), validQuery("dog"))
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	assert.Equal(t, orderedID(2), posts[0].ID, "more frequent term in a shorter post ranks first")
+	assert.Equal(t, orderedID(1), posts[1].ID)
+
+	posts, err = ix.SearchPostsByDate(context.Background(), validQuery("dog"))
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	assert.Equal(t, orderedID(2), posts[0].ID, "oldest first")
+
+	posts, err = ix.SearchPosts(context.Background(), validQuery("park dog"))
+	require.NoError(t, err)
+	require.Len(t, posts, 1, "every query word must match")
+	assert.Equal(t, orderedID(1), posts[0].ID)
+
+	posts, err = ix.SearchPosts(context.Background(), validQuery("gre"))
+	require.NoError(t, err)
+	assert.Len(t, posts, 2, "last word matches as a prefix")
+
+	posts, err = ix.SearchPosts(context.Background(), sql.NullString{})
+	require.NoError(t, err)
+	assert.Empty(t, posts)
+}
+
+func TestIndexFuzzyMatching(t *testing.T) {
+	ix := newTestIndex()
+	ix.PutUser(database.SearchUser{ID: orderedID(1), Username: "john_doe"})
+	ix.PutUser(database.SearchUser{ID: orderedID(2), Username: "jane"})
+	ix.PutPost(database.Post{ID: orderedID(3), Body: "Weekend concert tickets"})
+
+	users, err := ix.SearchUsers(context.Background(), validQuery("jonh_doe"))
+	require.NoError(t, err)
+	require.Len(t, users, 1, "a transposition is one edit")
+	assert.Equal(t, "john_doe", users[0].Username)
+
+	users, err = ix.SearchUsers(context.Background(), validQuery("jne"))
+	require.NoError(t, err)
+	assert.Empty(t, users, "short terms must match exactly")
+
+	posts, err := ix.SearchPosts(context.Background(), validQuery("concret"))
+	require.NoError(t, err)
+	assert.Len(t, posts, 1)
+}
+
+func TestIndexUsersAndReports(t *testing.T) {
+	ix := newTestIndex()
+	now := time.Now()
+	ix.PutUser(database.SearchUser{ID: orderedID(1), CreatedAt: now, Username: "johnny"})
+	ix.PutUser(database.SearchUser{ID: orderedID(2), CreatedAt: now.Add(-time.Hour), Username: "john"})
+	ix.PutUser(database.SearchUser{
+		ID:               orderedID(3),
+		CreatedAt:        now,
+		Username:         "Жасур",
+		UsernameLatin:    validQuery("jasur"),
+		UsernameCyrillic: validQuery("жасур"),
+	})
+
+	users, err := ix.SearchUsers(context.Background(), validQuery("john"))
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "john", users[0].Username, "exact match ranks before prefix match")
+
+	users, err = ix.SearchUsers(context.Background(), validQuery("jas"))
+	require.NoError(t, err)
+	require.Len(t, users, 1, "transliterated usernames are indexed")
+	assert.Equal(t, orderedID(3), users[0].ID)
+
+	reporter := uuid.MustParse("7a3b1c2d-0000-4000-8000-000000000000")
+	ix.PutReport(database.Report{ID: orderedID(4), ReportedAt: now, ReportedBy: reporter, Reason: "Spam links everywhere"})
+	ix.PutReport(database.Report{ID: orderedID(5), ReportedAt: now.Add(-time.Minute), ReportedBy: uuid.New(), Reason: "Harassment"})
+
+	reports, err := ix.SearchReports(context.Background(), validQuery("7a3b"))
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, orderedID(4), reports[0].ID)
+
+	reports, err = ix.SearchReportsByDate(context.Background(), validQuery("spam"))
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+
+	ix.Delete(index.Reports, orderedID(4))
+	reports, err = ix.SearchReports(context.Background(), validQuery("spam"))
+	require.NoError(t, err)
+	assert.Empty(t, reports)
+	assert.Equal(t, 1, ix.Count(index.Reports))
+}
+
+func TestIndexSearchPostsByLanguage(t *testing.T) {
+	ix := newTestIndex()
+	ix.PutPost(database.Post{ID: orderedID(1), Body: "Новые книги", Language: validQuery("ru")})
+	ix.PutPost(database.Post{ID: orderedID(2), Body: "Новые книги", Language: validQuery("uz")})
+
+	posts, err := ix.SearchPostsByLanguage(context.Background(), database.SearchPostsByLanguageParams{
+		Language: validQuery("ru"),
+		Query:    validQuery("книга"),
+	})
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	assert.Equal(t, orderedID(1), posts[0].ID)
+}
+
+func TestIndexReplaceDocument(t *testing.T) {
+	ix := newTestIndex()
+	ix.PutPost(database.Post{ID: orderedID(1), Body: "Old news"})
+	ix.PutPost(database.Post{ID: orderedID(1), Body: "Fresh story"})
+
+	posts, err := ix.SearchPosts(context.Background(), validQuery("news"))
+	require.NoError(t, err)
+	assert.Empty(t, posts)
+
+	posts, err = ix.SearchPosts(context.Background(), validQuery("story"))
+	require.NoError(t, err)
+	assert.Len(t, posts, 1)
+	assert.Equal(t, 1, ix.Count(index.Posts))
+}
+
+func TestIndexRawPrefixFollowsWrites(t *testing.T) {
+	ix := newTestIndex()
+	ix.PutPost(database.Post{ID: orderedID(1), Body: "#1 tip"})
+	ix.PutPost(database.Post{ID: orderedID(2), Body: "#2 tip"})
+	ix.PutPost(database.Post{ID: orderedID(3), Body: "no tag"})
+
+	// "#" has no terms, so only the raw prefix matches.
+	posts, err := ix.SearchPosts(context.Background(), validQuery("#"))
+	require.NoError(t, err)
+	assert.Len(t, posts, 2)
+
+	ix.PutPost(database.Post{ID: orderedID(1), Body: "no tag either"})
+	ix.Delete(index.Posts, orderedID(2))
+	posts, err = ix.SearchPosts(context.Background(), validQuery("#"))
+	require.NoError(t, err)
+	assert.Empty(t, posts)
+
+	reporter := uuid.MustParse("7a3b1c2d-0000-4000-8000-000000000000")
+	ix.PutReport(database.Report{ID: orderedID(4), ReportedBy: reporter, Reason: "Spam"})
+	ix.PutReport(database.Report{ID: orderedID(4), ReportedBy: uuid.MustParse("1a3b1c2d-0000-4000-8000-000000000000"), Reason: "Spam"})
+	reports, err := ix.SearchReports(context.Background(), validQuery("7a3b"))
+	require.NoError(t, err)
+	assert.Empty(t, reports)
+	reports, err = ix.SearchReports(context.Background(), validQuery("1a3b"))
+	require.NoError(t, err)
+	assert.Len(t, reports, 1)
+}
+
+func TestLoadIndex(t *testing.T) {
+	src := &indexSource{
+		users: []database.SearchUser{
+			{ID: orderedID(1), Username: "alice"},
+			{ID: orderedID(2), Username: "bob"},
+			{ID: orderedID(3), Username: "carol"},
+		},
+		posts:    []database.Post{{ID: orderedID(4), Body: "hello world"}},
+		comments: []database.Comment{{ID: orderedID(5), CommentText: "nice"}},
+		reports:  []database.Report{{ID: orderedID(6), Reason: "spam"}},
+	}
+
+	ix := newTestIndex()
+	require.NoError(t, index.Load(context.Background(), src, ix, 2))
+	assert.Equal(t, 3, ix.Count(index.Users))
+	assert.Equal(t, 1, ix.Count(index.Posts))
+	assert.Equal(t, 1, ix.Count(index.Comments))
+	assert.Equal(t, 1, ix.Count(index.Reports))
+	// Users take two pages, every other entity one short page.
+	assert.Equal(t, 5, src.calls)
+}
+
+func TestServerWithIndexBackend(t *testing.T) {
+	ix := newTestIndex()
+	ix.PutUser(database.SearchUser{ID: orderedID(1), CreatedAt: time.Now(), Username: "john"})
+	testServer := server.NewServer(ix, "test-secret")
+
+	resp, err := testServer.SearchUsers(context.Background(), &pb.SearchUsersRequest{Query: "Jo"})
+	require.NoError(t, err)
+	require.Len(t, resp.Users, 1)
+	assert.Equal(t, "john", resp.Users[0].Username)
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/langdetect"
+	"github.com/imhasandl/search-service/internal/mocks"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	testCases := []struct {
+		text     string
+		expected string
+	}{
+		{text: "I love running in the morning with my dog", expected: langdetect.English},
+		{text: "The books are on the table", expected: langdetect.English},
+		{text: "Я люблю читать книги по вечерам", expected: langdetect.Russian},
+		{text: "Новые книги в магазине", expected: langdetect.Russian},
+		{text: "Men kitob o'qishni yaxshi ko'raman", expected: langdetect.Uzbek},
+		{text: "Kecha kino ko'rdim juda zo'r ekan", expected: langdetect.Uzbek},
+		{text: "Мен китоб ўқишни яхши кўраман", expected: langdetect.Uzbek},
+		{text: "hi", expected: langdetect.Undetermined},
+		{text: "12345 !!!", expected: langdetect.Undetermined},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			assert.Equal(t, tc.expected, langdetect.Detect(tc.text))
+		})
+	}
+}
+
+func TestSearchPostsByLanguage(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	testServer := server.NewServer(mockDB, "test-secret")
+
+	post := database.Post{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		PostedBy:  uuid.New(),
+		Body:      "Я люблю читать книги",
+		Language:  sql.NullString{String: langdetect.Russian, Valid: true},
+	}
+	mockDB.On("SearchPostsByLanguage", mock.Anything, database.SearchPostsByLanguageParams{
+		Language: sql.NullString{String: langdetect.Russian, Valid: true},
+		Query:    sql.NullString{String: "книга", Valid: true},
+	}).Return([]database.Post{post}, nil).Once()
+	// The query is also searched as the posts.body analyzer stems it.
+	mockDB.On("SearchPostsByLanguage", mock.Anything, database.SearchPostsByLanguageParams{
+		Language: sql.NullString{String: langdetect.Russian, Valid: true},
+		Query:    sql.NullString{String: "книг", Valid: true},
+	}).Return([]database.Post{post}, nil).Once()
+
+	resp, err := testServer.SearchPosts(context.Background(), &pb.SearchPostsRequest{Query: "книга", Language: langdetect.Russian})
+	require.NoError(t, err)
+	require.Len(t, resp.Post, 1)
+	assert.Equal(t, langdetect.Russian, resp.Post[0].Language)
+	mockDB.AssertExpectations(t)
+
+	_, err = testServer.SearchPosts(context.Background(), &pb.SearchPostsRequest{Query: "book", Language: "de"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/levenshtein"
+	"github.com/stretchr/testify/assert"
+)
+
+// randomTerms returns n distinct sorted terms over a small alphabet, so many are close to each other.
+func randomTerms(rng *rand.Rand, n int) []string {
+	const alphabet = "abdehiklmnorsuyшахм"
+	letters := []rune(alphabet)
+	seen := make(map[string]bool, n)
+	terms := make([]string, 0, n)
+	for len(terms) < n {
+		word := make([]rune, 3+rng.Intn(8))
+		for i := range word {
+			word[i] = letters[rng.Intn(len(letters))]
+		}
+		if term := string(word); !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// bruteForce is the baseline the automaton replaces: the edit distance to every dictionary term.
+func bruteForce(query string, maxEdits int, dictionary []string) []levenshtein.Match {
+	var matches []levenshtein.Match
+	for _, term := range dictionary {
+		if d := levenshtein.Distance(query, term, maxEdits); d <= maxEdits {
+			matches = append(matches, levenshtein.Match{Term: term, Distance: d})
+		}
+	}
+	return matches
+}
+
+func TestAutomatonDistances(t *testing.T) {
+	testCases := []struct {
+		query, term string
+		distance    int
+	}{
+		{query: "alisher", term: "alisher", distance: 0},
+		{query: "alisher", term: "alishr", distance: 1},
+		{query: "alisher", term: "alihser", distance: 1},
+		{query: "alisher", term: "alisherr", distance: 1},
+		{query: "alisher", term: "alishar", distance: 1},
+		{query: "alisher", term: "laihser", distance: 2},
+		{query: "alisher", term: "bobur", distance: 3},
+		{query: "шахноза", term: "шахнозa", distance: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.term, func(t *testing.T) {
+			a := levenshtein.New(tc.query, 2)
+			state := a.Start()
+			for _, r := range tc.term {
+				state = a.Step(state, r)
+			}
+			assert.Equal(t, tc.distance, a.Distance(state))
+			assert.Equal(t, tc.distance <= 2, a.IsMatch(state))
+			assert.Equal(t, tc.distance, levenshtein.Distance(tc.query, tc.term, 2))
+		})
+	}
+}
+
+func TestAutomatonIntersectMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	dictionary := randomTerms(rng, 5000)
+
+	for i := 0; i < 200; i++ {
+		query := dictionary[rng.Intn(len(dictionary))]
+		if i%2 == 1 {
+			query = randomTerms(rng, 1)[0]
+		}
+		for maxEdits := 0; maxEdits <= 2; maxEdits++ {
+			want := bruteForce(query, maxEdits, dictionary)
+			got := levenshtein.New(query, maxEdits).Intersect(dictionary)
+			assert.Equal(t, want, got, "query %q, max edits %d", query, maxEdits)
+		}
+	}
+}
+
+func TestMaxEditsScalesWithLength(t *testing.T) {
+	assert.Equal(t, 0, index.MaxEdits("ali"))
+	assert.Equal(t, 1, index.MaxEdits("alish"))
+	assert.Equal(t, 2, index.MaxEdits("alisher"))
+}
+
+func BenchmarkFuzzyTerms(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	for _, size := range []int{1000, 10000, 100000} {
+		dictionary := randomTerms(rng, size)
+		queries := make([]string, 64)
+		for i := range queries {
+			queries[i] = randomTerms(rng, 1)[0]
+		}
+
+		b.Run(fmt.Sprintf("automaton/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				query := queries[i%len(queries)]
+				levenshtein.New(query, index.MaxEdits(query)).Intersect(dictionary)
+			}
+		})
+		b.Run(fmt.Sprintf("brute-force/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				query := queries[i%len(queries)]
+				bruteForce(query, index.MaxEdits(query), dictionary)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/listener"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rowStore serves single-row lookups from the same slices as indexSource.
+type rowStore struct {
+	indexSource
+}
+
+func findRow[T any](rows []T, id func(T) uuid.UUID, want uuid.UUID) (T, error) {
+	for _, row := range rows {
+		if id(row) == want {
+			return row, nil
+		}
+	}
+	var zero T
+	return zero, sql.ErrNoRows
+}
+
+func (s *rowStore) GetSearchUserByID(ctx context.Context, id uuid.UUID) (database.SearchUser, error) {
+	return findRow(s.users, func(u database.SearchUser) uuid.UUID { return u.ID }, id)
+}
+
+func (s *rowStore) GetPostByID(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	return findRow(s.posts, func(p database.Post) uuid.UUID { return p.ID }, id)
+}
+
+func (s *rowStore) GetCommentByID(ctx context.Context, id uuid.UUID) (database.Comment, error) {
+	return findRow(s.comments, func(c database.Comment) uuid.UUID { return c.ID }, id)
+}
+
+func (s *rowStore) GetReportByID(ctx context.Context, id uuid.UUID) (database.Report, error) {
+	return findRow(s.reports, func(r database.Report) uuid.UUID { return r.ID }, id)
+}
+
+// recordingApplier records what the listener asks of it.
+type recordingApplier struct {
+	applied   []listener.Change
+	resyncs   int
+	applyErr  error
+	resyncErr error
+}
+
+func (a *recordingApplier) Apply(ctx context.Context, change listener.Change) error {
+	a.applied = append(a.applied, change)
+	return a.applyErr
+}
+
+func (a *recordingApplier) Resync(ctx context.Context) error {
+	a.resyncs++
+	return a.resyncErr
+}
+
+func TestParseChange(t *testing.T) {
+	id := uuid.New()
+	change, err := listener.ParseChange(`{"table": "posts", "op": "UPDATE", "id": "` + id.String() + `"}`)
+	require.NoError(t, err)
+	assert.Equal(t, listener.Change{Entity: index.Posts, Op: listener.Update, ID: id}, change)
+
+	_, err = listener.ParseChange(`{"table": "messages", "op": "INSERT", "id": "` + id.String() + `"}`)
+	assert.Error(t, err)
+	_, err = listener.ParseChange(`{"table": "posts", "op": "TRUNCATE", "id": "` + id.String() + `"}`)
+	assert.Error(t, err)
+	_, err = listener.ParseChange(`not json`)
+	assert.Error(t, err)
+}
+
+func TestListenerHandle(t *testing.T) {
+	applier := &recordingApplier{}
+	l := &listener.Listener{Applier: applier}
+	id := uuid.New()
+
+	l.Handle(context.Background(), &pq.Notification{Extra: `{"table": "users", "op": "INSERT", "id": "` + id.String() + `"}`})
+	require.Len(t, applier.applied, 1)
+	assert.Equal(t, id, applier.applied[0].ID)
+	assert.Equal(t, 0, applier.resyncs)
+
+	// A nil notification follows a reconnect: changes may have been missed.
+	l.Handle(context.Background(), nil)
+	assert.Equal(t, 1, applier.resyncs)
+
+	applier.applyErr = errors.New("connection refused")
+	applier.resyncErr = errors.New("connection refused")
+	l.Handle(context.Background(), &pq.Notification{Extra: `{"table": "users", "op": "UPDATE", "id": "` + id.String() + `"}`})
+	assert.Equal(t, 2, applier.resyncs)
+	assert.True(t, l.Stale())
+
+	applier.resyncErr = nil
+	l.Handle(context.Background(), nil)
+	assert.False(t, l.Stale())
+}
+
+func TestIndexApplier(t *testing.T) {
+	store := &rowStore{}
+	ix := newTestIndex()
+	applier := &listener.IndexApplier{DB: store, Index: ix, BatchSize: 10}
+	ctx := context.Background()
+
+	post := database.Post{ID: orderedID(1), Body: "Fresh bread at the bakery"}
+	store.posts = append(store.posts, post)
+	require.NoError(t, applier.Apply(ctx, listener.Change{Entity: index.Posts, Op: listener.Insert, ID: post.ID}))
+	posts, err := ix.SearchPosts(ctx, validQuery("bread"))
+	require.NoError(t, err)
+	assert.Len(t, posts, 1)
+
+	store.posts[0].Body = "Stale bread"
+	require.NoError(t, applier.Apply(ctx, listener.Change{Entity: index.Posts, Op: listener.Update, ID: post.ID}))
+	posts, err = ix.SearchPosts(ctx, validQuery("bakery"))
+	require.NoError(t, err)
+	assert.Empty(t, posts)
+
+	// The row is gone by the time the update is applied.
+	store.posts = nil
+	require.NoError(t, applier.Apply(ctx, listener.Change{Entity: index.Posts, Op: listener.Update, ID: post.ID}))
+	assert.Equal(t, 0, ix.Count(index.Posts))
+
+	ix.PutUser(database.SearchUser{ID: orderedID(2), Username: "ghost"})
+	require.NoError(t, applier.Apply(ctx, listener.Change{Entity: index.Users, Op: listener.Delete, ID: orderedID(2)}))
+	assert.Equal(t, 0, ix.Count(index.Users))
+}
+
+func TestIndexApplierResync(t *testing.T) {
+	store := &rowStore{}
+	store.users = []database.SearchUser{{ID: orderedID(1), Username: "alice"}}
+	ix := newTestIndex()
+	ix.PutUser(database.SearchUser{ID: orderedID(2), Username: "deleted_while_offline"})
+
+	applier := &listener.IndexApplier{DB: store, Index: ix, BatchSize: 10}
+	require.NoError(t, applier.Resync(context.Background()))
+
+	assert.Equal(t, 1, ix.Count(index.Users))
+	users, err := ix.SearchUsers(context.Background(), validQuery("alice"))
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+	users, err = ix.SearchUsers(context.Background(), validQuery("deleted"))
+	require.NoError(t, err)
+	assert.Empty(t, users)
+}
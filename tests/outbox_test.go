@@ -0,0 +1,260 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/listener"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryOutbox is an in-memory outbox. Transactions roll back checkpoint changes on error,
+// entries with txid >= xmin belong to transactions that are still running.
+type memoryOutbox struct {
+	entries     []database.SearchOutbox
+	checkpoints map[string]database.SearchOutboxCheckpoint
+	locked      map[string]bool
+	xmin        int64
+}
+
+func newMemoryOutbox() *memoryOutbox {
+	return &memoryOutbox{
+		checkpoints: make(map[string]database.SearchOutboxCheckpoint),
+		locked:      make(map[string]bool),
+		xmin:        1 << 62,
+	}
+}
+
+func (o *memoryOutbox) add(txid int64, table index.Entity, op listener.Op, rowID uuid.UUID) {
+	o.entries = append(o.entries, database.SearchOutbox{
+		ID:        int64(len(o.entries) + 1),
+		Txid:      txid,
+		TableName: string(table),
+		Op:        string(op),
+		RowID:     rowID,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (o *memoryOutbox) InTx(ctx context.Context, fn func(q listener.OutboxStore) error) error {
+	saved := make(map[string]database.SearchOutboxCheckpoint, len(o.checkpoints))
+	for name, checkpoint := range o.checkpoints {
+		saved[name] = checkpoint
+	}
+	if err := fn(o); err != nil {
+		o.checkpoints = saved
+		return err
+	}
+	return nil
+}
+
+func (o *memoryOutbox) EnsureOutboxCheckpoint(ctx context.Context, consumer string) error {
+	if _, ok := o.checkpoints[consumer]; !ok {
+		o.checkpoints[consumer] = database.SearchOutboxCheckpoint{Consumer: consumer, UpdatedAt: time.Now()}
+	}
+	return nil
+}
+
+func (o *memoryOutbox) LockOutboxCheckpoint(ctx context.Context, consumer string) (database.SearchOutboxCheckpoint, error) {
+	if o.locked[consumer] {
+		return database.SearchOutboxCheckpoint{}, sql.ErrNoRows
+	}
+	return o.checkpoints[consumer], nil
+}
+
+func (o *memoryOutbox) SetOutboxCheckpoint(ctx context.Context, arg database.SetOutboxCheckpointParams) error {
+	o.checkpoints[arg.Consumer] = database.SearchOutboxCheckpoint{
+		Consumer:  arg.Consumer,
+		LastTxid:  arg.LastTxid,
+		LastID:    arg.LastID,
+		UpdatedAt: time.Now(),
+	}
+	return nil
+}
+
+func (o *memoryOutbox) ListOutboxAfter(ctx context.Context, arg database.ListOutboxAfterParams) ([]database.SearchOutbox, error) {
+	var visible []database.SearchOutbox
+	for _, entry := range o.entries {
+		after := entry.Txid > arg.LastTxid || (entry.Txid == arg.LastTxid && entry.ID > arg.LastID)
+		if after && entry.Txid < o.xmin {
+			visible = append(visible, entry)
+		}
+	}
+	sortOutbox(visible)
+	if len(visible) > int(arg.BatchSize) {
+		visible = visible[:arg.BatchSize]
+	}
+	return visible, nil
+}
+
+func (o *memoryOutbox) GetOutboxHead(ctx context.Context) (database.GetOutboxHeadRow, error) {
+	visible, _ := o.ListOutboxAfter(ctx, database.ListOutboxAfterParams{BatchSize: 1 << 30})
+	if len(visible) == 0 {
+		return database.GetOutboxHeadRow{}, sql.ErrNoRows
+	}
+	last := visible[len(visible)-1]
+	return database.GetOutboxHeadRow{Txid: last.Txid, ID: last.ID}, nil
+}
+
+func (o *memoryOutbox) DeleteOutboxBefore(ctx context.Context, createdAt time.Time) error {
+	kept := o.entries[:0]
+	for _, entry := range o.entries {
+		if !entry.CreatedAt.Before(createdAt) {
+			kept = append(kept, entry)
+		}
+	}
+	o.entries = kept
+	return nil
+}
+
+func (o *memoryOutbox) DeleteOutboxCheckpointsBefore(ctx context.Context, arg database.DeleteOutboxCheckpointsBeforeParams) error {
+	for name, checkpoint := range o.checkpoints {
+		if name != arg.Consumer && checkpoint.UpdatedAt.Before(arg.UpdatedAt) {
+			delete(o.checkpoints, name)
+		}
+	}
+	return nil
+}
+
+func sortOutbox(entries []database.SearchOutbox) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Txid != entries[j].Txid {
+			return entries[i].Txid < entries[j].Txid
+		}
+		return entries[i].ID < entries[j].ID
+	})
+}
+
+func newTestConsumer(outbox *memoryOutbox, applier listener.Applier) *listener.Consumer {
+	return &listener.Consumer{
+		Name:      "search-1",
+		Outbox:    outbox,
+		Applier:   applier,
+		BatchSize: 2,
+		Retention: time.Hour,
+	}
+}
+
+func TestOutboxConsumerAppliesInOrder(t *testing.T) {
+	outbox := newMemoryOutbox()
+	applier := &recordingApplier{}
+	consumer := newTestConsumer(outbox, applier)
+
+	first, second, third := uuid.New(), uuid.New(), uuid.New()
+	// The transaction with txid 20 wrote its entry first but committed after txid 10.
+	outbox.add(20, index.Posts, listener.Insert, second)
+	outbox.add(10, index.Users, listener.Insert, first)
+	outbox.add(30, index.Comments, listener.Delete, third)
+	outbox.add(30, "messages", listener.Insert, uuid.New())
+
+	applied, err := consumer.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 4, applied)
+	require.Len(t, applier.applied, 3, "entries of unknown tables are skipped")
+	assert.Equal(t, []uuid.UUID{first, second, third},
+		[]uuid.UUID{applier.applied[0].ID, applier.applied[1].ID, applier.applied[2].ID})
+	assert.Equal(t, int64(4), outbox.checkpoints["search-1"].LastID)
+
+	applied, err = consumer.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied, "resumes after the checkpoint")
+}
+
+func TestOutboxConsumerWaitsForRunningTransactions(t *testing.T) {
+	outbox := newMemoryOutbox()
+	applier := &recordingApplier{}
+	consumer := newTestConsumer(outbox, applier)
+
+	outbox.add(10, index.Users, listener.Insert, uuid.New())
+	outbox.add(12, index.Users, listener.Insert, uuid.New())
+	outbox.xmin = 11 // txid 11 is still running
+
+	_, err := consumer.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, applier.applied, 1)
+
+	// txid 11 commits with an entry that sorts before 12.
+	late := uuid.New()
+	outbox.add(11, index.Posts, listener.Update, late)
+	outbox.xmin = 13
+
+	_, err = consumer.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, applier.applied, 3)
+	assert.Equal(t, late, applier.applied[1].ID)
+}
+
+func TestOutboxConsumerRetriesFailedBatch(t *testing.T) {
+	outbox := newMemoryOutbox()
+	applier := &recordingApplier{applyErr: errors.New("connection refused")}
+	consumer := newTestConsumer(outbox, applier)
+	outbox.add(10, index.Users, listener.Insert, uuid.New())
+
+	_, err := consumer.RunOnce(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, int64(0), outbox.checkpoints["search-1"].LastID)
+
+	applier.applyErr = nil
+	applied, err := consumer.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+}
+
+func TestOutboxConsumerSharedCheckpoint(t *testing.T) {
+	outbox := newMemoryOutbox()
+	applier := &recordingApplier{}
+	consumer := newTestConsumer(outbox, applier)
+	outbox.add(10, index.Users, listener.Insert, uuid.New())
+	require.NoError(t, outbox.EnsureOutboxCheckpoint(context.Background(), "search-1"))
+	outbox.locked["search-1"] = true
+
+	applied, err := consumer.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied, "another replica holds the checkpoint")
+	assert.Empty(t, applier.applied)
+}
+
+func TestOutboxConsumerSeekAndStaleCheckpoint(t *testing.T) {
+	outbox := newMemoryOutbox()
+	applier := &recordingApplier{}
+	consumer := newTestConsumer(outbox, applier)
+	outbox.add(10, index.Users, listener.Insert, uuid.New())
+	outbox.add(11, index.Users, listener.Update, uuid.New())
+
+	require.NoError(t, consumer.Seek(context.Background()))
+	applied, err := consumer.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied, "entries before the seek are covered by the full load")
+
+	checkpoint := outbox.checkpoints["search-1"]
+	checkpoint.LastTxid, checkpoint.LastID = 0, 0
+	checkpoint.UpdatedAt = time.Now().Add(-2 * time.Hour)
+	outbox.checkpoints["search-1"] = checkpoint
+
+	_, err = consumer.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, applier.resyncs)
+	assert.Empty(t, applier.applied)
+	assert.Equal(t, int64(2), outbox.checkpoints["search-1"].LastID)
+}
+
+func TestOutboxPruneDeletesAbandonedCheckpoints(t *testing.T) {
+	outbox := newMemoryOutbox()
+	consumer := newTestConsumer(outbox, &recordingApplier{})
+	require.NoError(t, consumer.Seek(context.Background()))
+	outbox.checkpoints["old-pod"] = database.SearchOutboxCheckpoint{Consumer: "old-pod", UpdatedAt: time.Now().Add(-2 * time.Hour)}
+	outbox.checkpoints["search-2"] = database.SearchOutboxCheckpoint{Consumer: "search-2", UpdatedAt: time.Now()}
+
+	require.NoError(t, consumer.Prune(context.Background()))
+	assert.NotContains(t, outbox.checkpoints, "old-pod")
+	assert.Contains(t, outbox.checkpoints, "search-2")
+	assert.Contains(t, outbox.checkpoints, "search-1")
+}
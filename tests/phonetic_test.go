@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/mocks"
+	"github.com/imhasandl/search-service/internal/phonetic"
+	"github.com/imhasandl/search-service/internal/translit"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPhoneticEquivalentSpellings(t *testing.T) {
+	tr := translit.New(translit.DefaultTable)
+
+	testCases := map[string][]string{
+		"MHMT":   {"Mohammed", "Muhammad", "Mohamed", "Мухаммад"},
+		"HRXT":   {"Xurshid", "Khurshid", "Хуршид"},
+		"XR":     {"Shoxrux", "Shohruh", "Шохрух"},
+		"AFKN":   {"Yevgeniy", "Evgeniy", "Евгений"},
+		"ALKSNT": {"Aleksandr", "Александр"},
+		"APTL":   {"Abdulla", "Abdullah"},
+	}
+	for code, names := range testCases {
+		for _, name := range names {
+			assert.Equal(t, code, phonetic.Encode(tr.Key(name)), name)
+		}
+	}
+
+	assert.NotEqual(t, phonetic.Encode("sasha"), phonetic.Encode("sara"))
+	assert.Equal(t, "", phonetic.Encode("12345"))
+	assert.LessOrEqual(t, len(phonetic.Encode("abdurakhmonbekovich")), phonetic.MaxLength)
+}
+
+func TestUsernameColumnsIncludePhonetic(t *testing.T) {
+	tr := translit.New(translit.DefaultTable)
+	params := translit.UsernameColumns(tr, analyzer.DefaultFields().For(analyzer.FieldUsername), uuid.New(), "Мухаммад")
+	assert.Equal(t, sql.NullString{String: "MHMT", Valid: true}, params.UsernamePhonetic)
+}
+
+func TestIndexSearchUsersByPhonetic(t *testing.T) {
+	ix := newTestIndex()
+	code := sql.NullString{String: "MHMT", Valid: true}
+	ix.PutUser(database.SearchUser{ID: orderedID(1), Username: "muhammad", UsernamePhonetic: code})
+	ix.PutUser(database.SearchUser{ID: orderedID(2), Username: "mohamed", UsernamePhonetic: code})
+	ix.PutUser(database.SearchUser{ID: orderedID(3), Username: "alisher", UsernamePhonetic: sql.NullString{String: "ALXR", Valid: true}})
+
+	users, err := ix.SearchUsersByPhonetic(context.Background(), code)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "mohamed", users[0].Username)
+	assert.Equal(t, "muhammad", users[1].Username)
+
+	// A rename moves the user to its new code.
+	ix.PutUser(database.SearchUser{ID: orderedID(2), Username: "alisherbek", UsernamePhonetic: sql.NullString{String: "ALXRPK", Valid: true}})
+	ix.Delete(index.Users, orderedID(1))
+	users, err = ix.SearchUsersByPhonetic(context.Background(), code)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+}
+
+func TestSearchUsersPhoneticMode(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	testServer := server.NewServer(mockDB, "test-secret", server.WithTransliterator(translit.New(translit.DefaultTable)))
+
+	exact := database.SearchUser{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Username: "mohammed"}
+	soundsLike := database.SearchUser{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Username: "muhammad"}
+	mockDB.On("SearchUsers", mock.Anything, sql.NullString{String: "mohammed", Valid: true}).Return([]database.SearchUser{exact}, nil).Once()
+	mockDB.On("SearchUsersByPhonetic", mock.Anything, sql.NullString{String: "MHMT", Valid: true}).Return([]database.SearchUser{soundsLike, exact}, nil).Once()
+
+	resp, err := testServer.SearchUsers(context.Background(), &pb.SearchUsersRequest{
+		Query: "mohammed",
+		Mode:  pb.UserSearchMode_USER_SEARCH_MODE_PHONETIC,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Users, 2)
+	assert.Equal(t, "mohammed", resp.Users[0].Username, "exact matches rank first")
+	assert.Equal(t, "muhammad", resp.Users[1].Username)
+	mockDB.AssertExpectations(t)
+}
+
+func TestSearchUsersPhoneticModeLeavesSharedRowsAlone(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	testServer := server.NewServer(mockDB, "test-secret", server.WithTransliterator(translit.New(translit.DefaultTable)))
+
+	exact := database.SearchUser{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Username: "mohammed"}
+	soundsLike := database.SearchUser{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Username: "muhammad"}
+	// Rows with spare capacity, like a cached or coalesced result other requests still read.
+	shared := make([]database.SearchUser, 1, 4)
+	shared[0] = exact
+	mockDB.On("SearchUsers", mock.Anything, sql.NullString{String: "mohammed", Valid: true}).Return(shared, nil).Once()
+	mockDB.On("SearchUsersByPhonetic", mock.Anything, sql.NullString{String: "MHMT", Valid: true}).Return([]database.SearchUser{soundsLike}, nil).Once()
+
+	resp, err := testServer.SearchUsers(context.Background(), &pb.SearchUsersRequest{
+		Query: "mohammed",
+		Mode:  pb.UserSearchMode_USER_SEARCH_MODE_PHONETIC,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Users, 2)
+	assert.Equal(t, database.SearchUser{}, shared[:2][1], "the shared rows are not written to")
+}
+
+func TestSearchUsersRejectsUnknownMode(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	testServer := server.NewServer(mockDB, "test-secret")
+
+	_, err := testServer.SearchUsers(context.Background(), &pb.SearchUsersRequest{Query: "john", Mode: pb.UserSearchMode(7)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	mockDB.AssertNotCalled(t, "SearchUsers", mock.Anything, mock.Anything)
+}
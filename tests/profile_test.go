@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/auth"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/mocks"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetUserProfile(t *testing.T) {
+	admin := auth.NewContext(context.Background(), auth.Principal{Subject: "admin-1", Roles: []string{auth.RoleAdmin}})
+	member := auth.NewContext(context.Background(), auth.Principal{Subject: "user-1"})
+	userID := uuid.New()
+	testTime := time.Now()
+
+	testCases := []struct {
+		name         string
+		ctx          context.Context
+		id           string
+		mockSetup    func(mockDB *mocks.MockQueries)
+		expectedCode codes.Code
+	}{
+		{
+			name:         "unauthenticated",
+			ctx:          context.Background(),
+			id:           userID.String(),
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "not an admin",
+			ctx:          member,
+			id:           userID.String(),
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "invalid id",
+			ctx:          admin,
+			id:           "not-a-uuid",
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "not found",
+			ctx:  admin,
+			id:   userID.String(),
+			mockSetup: func(mockDB *mocks.MockQueries) {
+				mockDB.On("GetUserProfile", mock.Anything, userID).Return(database.GetUserProfileRow{}, sql.ErrNoRows).Once()
+			},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name: "admin",
+			ctx:  admin,
+			id:   userID.String(),
+			mockSetup: func(mockDB *mocks.MockQueries) {
+				mockDB.On("GetUserProfile", mock.Anything, userID).Return(database.GetUserProfileRow{
+					ID:               userID,
+					CreatedAt:        testTime,
+					UpdatedAt:        testTime,
+					Email:            "john@example.com",
+					Username:         "johndoe",
+					VerificationCode: 123456,
+					IsVerified:       true,
+				}, nil).Once()
+			},
+			expectedCode: codes.OK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDB := mocks.NewMockQueries()
+			if tc.mockSetup != nil {
+				tc.mockSetup(mockDB)
+			}
+			testServer := server.NewServer(mockDB, "test-secret", server.WithProfiles(mockDB))
+
+			resp, err := testServer.GetUserProfile(tc.ctx, &pb.GetUserProfileRequest{Id: tc.id})
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			if tc.expectedCode == codes.OK {
+				require.NoError(t, err)
+				assert.Equal(t, "john@example.com", resp.User.Email)
+				assert.Equal(t, int32(123456), resp.User.VerificationCode)
+			}
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetUserProfileWithoutProfiles(t *testing.T) {
+	admin := auth.NewContext(context.Background(), auth.Principal{Roles: []string{auth.RoleAdmin}})
+	testServer := server.NewServer(mocks.NewMockQueries(), "test-secret")
+
+	_, err := testServer.GetUserProfile(admin, &pb.GetUserProfileRequest{Id: uuid.New().String()})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
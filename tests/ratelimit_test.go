@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/auth"
+	"github.com/imhasandl/search-service/internal/mocks"
+	"github.com/imhasandl/search-service/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// headerStream records the headers set by interceptors.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "" }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *headerStream) SetTrailer(metadata.MD) error { return nil }
+
+func fromIP(ip string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 40000}})
+}
+
+// callLimited runs a unary call of rpc through the limiter and returns its headers and error.
+func callLimited(ctx context.Context, l *ratelimit.Limiter, rpc string) (metadata.MD, error) {
+	stream := &headerStream{}
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+	_, err := l.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/search.SearchService/" + rpc},
+		func(context.Context, any) (any, error) { return nil, nil })
+	return stream.header, err
+}
+
+func TestRateLimitByIP(t *testing.T) {
+	now := time.Now()
+	limiter := ratelimit.New(
+		ratelimit.WithDefaultLimit(ratelimit.Limit{Rate: 1, Burst: 2}),
+		ratelimit.WithClock(func() time.Time { return now }),
+	)
+
+	for i := 0; i < 2; i++ {
+		_, err := callLimited(fromIP("10.0.0.1"), limiter, "SearchPosts")
+		require.NoError(t, err)
+	}
+	header, err := callLimited(fromIP("10.0.0.1"), limiter, "SearchPosts")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"1"}, header.Get(ratelimit.RetryAfterKey))
+
+	// Other callers and other RPCs have buckets of their own.
+	_, err = callLimited(fromIP("10.0.0.2"), limiter, "SearchPosts")
+	assert.NoError(t, err)
+	_, err = callLimited(fromIP("10.0.0.1"), limiter, "SearchUsers")
+	assert.NoError(t, err)
+
+	now = now.Add(time.Second)
+	_, err = callLimited(fromIP("10.0.0.1"), limiter, "SearchPosts")
+	assert.NoError(t, err)
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	now := time.Now()
+	limiter := ratelimit.New(
+		ratelimit.WithDefaultLimit(ratelimit.Limit{Rate: 0.1, Burst: 1}),
+		ratelimit.WithClock(func() time.Time { return now }),
+	)
+	user := func(ip string) context.Context {
+		return auth.NewContext(fromIP(ip), auth.Principal{Subject: "user-1"})
+	}
+
+	_, err := callLimited(user("10.0.0.1"), limiter, "SearchPosts")
+	require.NoError(t, err)
+	// Changing address doesn't give an authenticated user a new bucket.
+	header, err := callLimited(user("10.0.0.2"), limiter, "SearchPosts")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"10"}, header.Get(ratelimit.RetryAfterKey))
+	// Nor does it take the bucket of anonymous callers from that address.
+	_, err = callLimited(fromIP("10.0.0.1"), limiter, "SearchPosts")
+	assert.NoError(t, err)
+}
+
+func TestRateLimitPremium(t *testing.T) {
+	now := time.Now()
+	mockDB := mocks.NewMockQueries()
+	premiumID, regularID := uuid.New(), uuid.New()
+	mockDB.On("IsUserPremium", mock.Anything, premiumID).Return(true, nil).Once()
+	mockDB.On("IsUserPremium", mock.Anything, regularID).Return(false, nil).Once()
+
+	limiter := ratelimit.New(
+		ratelimit.WithDefaultLimit(ratelimit.Limit{Rate: 1, Burst: 2}),
+		ratelimit.WithPremium(mockDB, 3),
+		ratelimit.WithClock(func() time.Time { return now }),
+	)
+	allowed := func(id uuid.UUID) int {
+		ctx := auth.NewContext(context.Background(), auth.Principal{Subject: id.String()})
+		n := 0
+		for ; n < 20; n++ {
+			if _, err := callLimited(ctx, limiter, "SearchPosts"); err != nil {
+				break
+			}
+		}
+		return n
+	}
+
+	assert.Equal(t, 6, allowed(premiumID))
+	assert.Equal(t, 2, allowed(regularID))
+	// Premium lookups are remembered, the mock expects one call per user.
+	mockDB.AssertExpectations(t)
+}
+
+func TestRateLimitPerRPC(t *testing.T) {
+	limiter := ratelimit.New(
+		ratelimit.WithDefaultLimit(ratelimit.Limit{Rate: 1, Burst: 1}),
+		ratelimit.WithLimit("SearchPosts", ratelimit.Limit{Rate: 1, Burst: 3}),
+		ratelimit.WithLimit("SearchUsers", ratelimit.Limit{}),
+	)
+	count := func(rpc string) int {
+		n := 0
+		for ; n < 10; n++ {
+			if _, err := callLimited(fromIP("10.0.0.1"), limiter, rpc); err != nil {
+				break
+			}
+		}
+		return n
+	}
+
+	assert.Equal(t, 3, count("SearchPosts"))
+	assert.Equal(t, 10, count("SearchUsers"))
+	assert.Equal(t, 1, count("SearchReports"))
+}
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		spec  string
+		limit ratelimit.Limit
+	}{
+		{spec: "10/s", limit: ratelimit.Limit{Rate: 10, Burst: 10}},
+		{spec: "60/m:5", limit: ratelimit.Limit{Rate: 1, Burst: 5}},
+		{spec: "0.5/s", limit: ratelimit.Limit{Rate: 0.5, Burst: 1}},
+		{spec: "off", limit: ratelimit.Limit{}},
+	}
+	for _, tc := range testCases {
+		limit, err := ratelimit.ParseLimit(tc.spec)
+		require.NoError(t, err, tc.spec)
+		assert.Equal(t, tc.limit, limit, tc.spec)
+	}
+
+	for _, spec := range []string{"10", "10/d", "x/s", "10/s:x", "-1/s"} {
+		_, err := ratelimit.ParseLimit(spec)
+		assert.Error(t, err, spec)
+	}
+
+	limits, err := ratelimit.ParseLimits("SearchPosts=2/s:5, SearchUsers=off")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]ratelimit.Limit{
+		"SearchPosts": {Rate: rate.Limit(2), Burst: 5},
+		"SearchUsers": {},
+	}, limits)
+}
@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/reindex"
+	"github.com/imhasandl/search-service/internal/translit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reindexStore records the writes of a reindex. failAfter makes the n-th write fail.
+type reindexStore struct {
+	indexSource
+	transliterated []database.SetUsernameTransliterationParams
+	languages      []database.SetPostLanguageParams
+	touched        []uuid.UUID
+	writes         int
+	failAfter      int
+}
+
+func countAfter(ids []uuid.UUID, after uuid.UUID) int64 {
+	var n int64
+	for _, id := range ids {
+		if id.String() > after.String() {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *reindexStore) write() error {
+	s.writes++
+	if s.failAfter > 0 && s.writes > s.failAfter {
+		return errors.New("connection reset")
+	}
+	return nil
+}
+
+func (s *reindexStore) CountUsersAfter(ctx context.Context, id uuid.UUID) (int64, error) {
+	var ids []uuid.UUID
+	for _, user := range s.users {
+		ids = append(ids, user.ID)
+	}
+	return countAfter(ids, id), nil
+}
+
+func (s *reindexStore) CountPostsAfter(ctx context.Context, id uuid.UUID) (int64, error) {
+	var ids []uuid.UUID
+	for _, post := range s.posts {
+		ids = append(ids, post.ID)
+	}
+	return countAfter(ids, id), nil
+}
+
+func (s *reindexStore) CountCommentsAfter(ctx context.Context, id uuid.UUID) (int64, error) {
+	var ids []uuid.UUID
+	for _, comment := range s.comments {
+		ids = append(ids, comment.ID)
+	}
+	return countAfter(ids, id), nil
+}
+
+func (s *reindexStore) CountReportsAfter(ctx context.Context, id uuid.UUID) (int64, error) {
+	var ids []uuid.UUID
+	for _, report := range s.reports {
+		ids = append(ids, report.ID)
+	}
+	return countAfter(ids, id), nil
+}
+
+func (s *reindexStore) SetUsernameTransliteration(ctx context.Context, arg database.SetUsernameTransliterationParams) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	s.transliterated = append(s.transliterated, arg)
+	return nil
+}
+
+func (s *reindexStore) SetPostLanguage(ctx context.Context, arg database.SetPostLanguageParams) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	s.languages = append(s.languages, arg)
+	return nil
+}
+
+func (s *reindexStore) TouchComments(ctx context.Context, ids []uuid.UUID) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	s.touched = append(s.touched, ids...)
+	return nil
+}
+
+func (s *reindexStore) TouchReports(ctx context.Context, ids []uuid.UUID) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	s.touched = append(s.touched, ids...)
+	return nil
+}
+
+func newReindexStore() *reindexStore {
+	store := &reindexStore{}
+	store.users = []database.SearchUser{{ID: orderedID(1), Username: "Жасур"}, {ID: orderedID(2), Username: "john"}}
+	store.posts = []database.Post{
+		{ID: orderedID(3), Body: "I love running in the morning with my dog"},
+		{ID: orderedID(4), Body: "Я люблю читать книги по вечерам"},
+		{ID: orderedID(5), Body: "Men kitob o'qishni yaxshi ko'raman"},
+	}
+	store.comments = []database.Comment{{ID: orderedID(6), CommentText: "nice"}}
+	store.reports = []database.Report{{ID: orderedID(7), Reason: "spam"}}
+	return store
+}
+
+func newTestReindexer(store reindex.Store, checkpointPath string) (*reindex.Reindexer, *[]reindex.Progress) {
+	var reports []reindex.Progress
+	return &reindex.Reindexer{
+		DB:               store,
+		Transliterator:   translit.New(translit.DefaultTable),
+		UsernameAnalyzer: analyzer.DefaultFields().For(analyzer.FieldUsername),
+		BatchSize:        2,
+		CheckpointPath:   checkpointPath,
+		Report:           func(p reindex.Progress) { reports = append(reports, p) },
+	}, &reports
+}
+
+func TestReindexAllEntities(t *testing.T) {
+	store := newReindexStore()
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+	reindexer, reports := newTestReindexer(store, checkpointPath)
+
+	require.NoError(t, reindexer.Run(context.Background(), index.Entities))
+
+	require.Len(t, store.transliterated, 2)
+	assert.Equal(t, "jasur", store.transliterated[0].UsernameLatin.String)
+	require.Len(t, store.languages, 3)
+	assert.Equal(t, []string{"en", "ru", "uz"},
+		[]string{store.languages[0].Language.String, store.languages[1].Language.String, store.languages[2].Language.String})
+	assert.Equal(t, []uuid.UUID{orderedID(6), orderedID(7)}, store.touched)
+
+	// The last report of each entity is complete.
+	final := map[index.Entity]reindex.Progress{}
+	for _, p := range *reports {
+		final[p.Entity] = p
+	}
+	assert.Equal(t, int64(3), final[index.Posts].Done)
+	assert.Equal(t, int64(3), final[index.Posts].Total)
+
+	assert.NoFileExists(t, checkpointPath, "a finished run removes its checkpoint")
+}
+
+func TestReindexSingleEntityDryRun(t *testing.T) {
+	store := newReindexStore()
+	reindexer, _ := newTestReindexer(store, "")
+	reindexer.DryRun = true
+
+	require.NoError(t, reindexer.Run(context.Background(), []index.Entity{index.Posts}))
+	assert.Zero(t, store.writes)
+}
+
+func TestReindexResumesFromCheckpoint(t *testing.T) {
+	store := newReindexStore()
+	// Two users and the first two posts are written, then the database goes away.
+	store.failAfter = 4
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+	reindexer, _ := newTestReindexer(store, checkpointPath)
+
+	require.Error(t, reindexer.Run(context.Background(), index.Entities))
+	checkpoint, err := reindex.LoadCheckpoint(checkpointPath)
+	require.NoError(t, err)
+	assert.Equal(t, []index.Entity{index.Users}, checkpoint.Completed)
+	assert.Equal(t, index.Posts, checkpoint.Entity)
+	assert.Equal(t, orderedID(4), checkpoint.After)
+
+	store.failAfter = 0
+	store.transliterated, store.languages = nil, nil
+	require.NoError(t, reindexer.Run(context.Background(), index.Entities))
+	assert.Empty(t, store.transliterated, "completed entities are skipped")
+	require.Len(t, store.languages, 1)
+	assert.Equal(t, orderedID(5), store.languages[0].ID)
+}
+
+func TestReindexProgress(t *testing.T) {
+	p := reindex.Progress{Entity: index.Posts, Total: 1000, Done: 250, Elapsed: 5 * time.Second}
+	assert.Equal(t, 50.0, p.Rate())
+	assert.Equal(t, 15*time.Second, p.ETA())
+	assert.Equal(t, "posts: 250/1000 (25.0%), 50 rows/s, ETA 15s", p.String())
+
+	assert.Zero(t, reindex.Progress{Total: 10}.ETA())
+}
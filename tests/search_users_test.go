@@ -41,20 +41,14 @@ func TestSearchUsers(t *testing.T) {
 				userID := uuid.New()
 				nullQuery := sql.NullString{String: "john", Valid: true}
 
-				mockDB.On("SearchUsers", mock.Anything, nullQuery).Return([]database.User{
+				mockDB.On("SearchUsers", mock.Anything, nullQuery).Return([]database.SearchUser{
 					{
-						ID:                     userID,
-						CreatedAt:              testTime,
-						UpdatedAt:              testTime,
-						Email:                  "john@example.com",
-						Password:               "hashedpassword",
-						Username:               "johndoe",
-						Subscribers:            []uuid.UUID{},
-						SubscribedTo:           []uuid.UUID{},
-						IsPremium:              true,
-						VerificationCode:       12345,
-						VerificationExpireTime: testTime.Add(24 * time.Hour),
-						IsVerified:             true,
+						ID:         userID,
+						CreatedAt:  testTime,
+						UpdatedAt:  testTime,
+						Username:   "johndoe",
+						IsPremium:  true,
+						IsVerified: true,
 					},
 				}, nil).Once()
 			},
@@ -72,7 +66,7 @@ func TestSearchUsers(t *testing.T) {
 			query: "",
 			mockSetup: func() {
 				nullQuery := sql.NullString{String: "", Valid: false}
-				mockDB.On("SearchUsers", mock.Anything, nullQuery).Return([]database.User{}, nil).Once()
+				mockDB.On("SearchUsers", mock.Anything, nullQuery).Return([]database.SearchUser{}, nil).Once()
 			},
 			expectedError: false,
 			validateResp: func(t *testing.T, resp *pb.SearchUsersResponse) {
@@ -86,7 +80,7 @@ func TestSearchUsers(t *testing.T) {
 			mockSetup: func() {
 				nullQuery := sql.NullString{String: "error", Valid: true}
 				mockDB.On("SearchUsers", mock.Anything, nullQuery).Return(
-					[]database.User{}, errors.New("database error"),
+					[]database.SearchUser{}, errors.New("database error"),
 				).Once()
 			},
 			expectedError:  true,
@@ -150,12 +144,11 @@ func TestSearchUsersByDate(t *testing.T) {
 				testTime2 := time.Now()                      // newer
 
 				nullQuery := sql.NullString{String: "user", Valid: true}
-				mockDB.On("SearchUsersByDate", mock.Anything, nullQuery).Return([]database.User{
+				mockDB.On("SearchUsersByDate", mock.Anything, nullQuery).Return([]database.SearchUser{
 					{
 						ID:         userID1,
 						CreatedAt:  testTime1,
 						UpdatedAt:  testTime1,
-						Email:      "user1@example.com",
 						Username:   "user1",
 						IsPremium:  false,
 						IsVerified: true,
@@ -164,7 +157,6 @@ func TestSearchUsersByDate(t *testing.T) {
 						ID:         userID2,
 						CreatedAt:  testTime2,
 						UpdatedAt:  testTime2,
-						Email:      "user2@example.com",
 						Username:   "user2",
 						IsPremium:  true,
 						IsVerified: false,
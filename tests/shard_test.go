@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/fanout"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postIDs(posts []database.Post) []uuid.UUID {
+	ids := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	return ids
+}
+
+func TestShardedIndexMatchesSingleIndex(t *testing.T) {
+	single := newTestIndex()
+	sharded := index.New(analyzer.DefaultFields(), index.WithShards(8))
+	bodies := []string{"apple pie", "apply now", "Running with dogs", "dogs and cats", "a dog runs", "cats only"}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, body := range bodies {
+		post := database.Post{ID: orderedID(byte(i + 1)), CreatedAt: start.Add(time.Duration(i) * time.Hour), Body: body}
+		single.PutPost(post)
+		sharded.PutPost(post)
+	}
+	assert.Equal(t, len(bodies), sharded.Count(index.Posts))
+
+	for _, query := range []string{"dog", "cats", "apple", "aple", "Running", ""} {
+		want, err := single.SearchPostsByDate(context.Background(), validQuery(query))
+		require.NoError(t, err)
+		got, err := sharded.SearchPostsByDate(context.Background(), validQuery(query))
+		require.NoError(t, err)
+		assert.Equal(t, postIDs(want), postIDs(got), query)
+
+		// BM25 statistics are per shard, so only the matches themselves are compared.
+		want, err = single.SearchPosts(context.Background(), validQuery(query))
+		require.NoError(t, err)
+		got, err = sharded.SearchPosts(context.Background(), validQuery(query))
+		require.NoError(t, err)
+		assert.ElementsMatch(t, postIDs(want), postIDs(got), query)
+	}
+
+	// "apply" is one edit from "apple", but fuzzy matching only kicks in when no shard matched exactly.
+	posts, err := sharded.SearchPosts(context.Background(), validQuery("apple"))
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{orderedID(1)}, postIDs(posts))
+}
+
+func TestShardedIndexTopK(t *testing.T) {
+	ix := index.New(analyzer.DefaultFields(), index.WithShards(4), index.WithTopK(5))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var want []uuid.UUID
+	for i := 0; i < 20; i++ {
+		post := database.Post{ID: uuid.New(), CreatedAt: start.Add(time.Duration(i) * time.Minute), Body: fmt.Sprintf("dog number %d", i)}
+		ix.PutPost(post)
+		if i < 5 {
+			want = append(want, post.ID)
+		}
+	}
+
+	posts, err := ix.SearchPostsByDate(context.Background(), validQuery("dog"))
+	require.NoError(t, err)
+	assert.Equal(t, want, postIDs(posts))
+
+	ctx, partial := index.TrackPartial(context.Background())
+	_, err = ix.SearchPosts(ctx, validQuery("dog"))
+	require.NoError(t, err)
+	assert.False(t, partial.Load())
+}
+
+func TestFanOutLeavesOutSlowPartitions(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	results, timedOut, err := fanout.Run(context.Background(), 3, 20*time.Millisecond, func(ctx context.Context, i int) int {
+		if i == 1 {
+			<-release
+		}
+		return i
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, timedOut)
+	assert.Equal(t, []int{0, 2}, results)
+
+	_, timedOut, err = fanout.Run(context.Background(), 2, 10*time.Millisecond, func(ctx context.Context, i int) int {
+		<-release
+		return i
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 2, timedOut)
+}
+
+func TestFanOutDropsAnswersCutShort(t *testing.T) {
+	for run := 0; run < 50; run++ {
+		stopped := make(chan error, 1)
+		// The slow call stops as soon as its deadline passes and answers at once, like a shard search.
+		results, timedOut, err := fanout.Run(context.Background(), 2, 5*time.Millisecond, func(ctx context.Context, i int) int {
+			if i == 1 {
+				<-ctx.Done()
+				stopped <- ctx.Err()
+			}
+			return i
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, timedOut, "an answer returned after the deadline is not complete")
+		assert.Equal(t, []int{0}, results)
+		assert.ErrorIs(t, <-stopped, context.DeadlineExceeded, "calls see their deadline")
+	}
+}
+
+func TestFanOutFailsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := fanout.Run(ctx, 1, 0, func(ctx context.Context, i int) int { return i })
+	assert.ErrorIs(t, err, context.Canceled, "a single partition cut short by ctx isn't returned either")
+}
+
+func TestFanOutMerge(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+	lists := [][]int{{1, 4, 9}, {}, {2, 3, 10}, {5}}
+
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 9, 10}, fanout.Merge(lists, less, 0))
+	assert.Equal(t, []int{1, 2, 3}, fanout.Merge(lists, less, 3))
+	assert.Empty(t, fanout.Merge(nil, less, 3))
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/listener"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func snapshotTestIndex() *index.Index {
+	ix := newTestIndex()
+	ix.PutUser(database.SearchUser{ID: orderedID(1), CreatedAt: time.Now(), Username: "john"})
+	ix.PutPost(database.Post{ID: orderedID(2), Body: "Running with dogs", Language: validQuery("en"), LikedBy: []string{"a"}})
+	ix.PutComment(database.Comment{ID: orderedID(3), CommentText: "nice"})
+	ix.PutReport(database.Report{ID: orderedID(4), Reason: "spam"})
+	return ix
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	written, err := snapshotTestIndex().WriteSnapshot(&buf, index.HighWater{Txid: 42, ID: 7})
+	require.NoError(t, err)
+	assert.Equal(t, 4, written.Documents)
+
+	restored := newTestIndex()
+	info, err := restored.ReadSnapshot(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, index.HighWater{Txid: 42, ID: 7}, info.HighWater)
+	assert.Equal(t, 1, restored.Count(index.Comments))
+
+	posts, err := restored.SearchPosts(context.Background(), validQuery("run"))
+	require.NoError(t, err)
+	require.Len(t, posts, 1, "postings are rebuilt on load")
+	assert.Equal(t, "en", posts[0].Language.String)
+}
+
+func TestSnapshotCorruptionIsDetected(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := snapshotTestIndex().WriteSnapshot(&buf, index.HighWater{})
+	require.NoError(t, err)
+	data := buf.Bytes()
+
+	flipped := bytes.Clone(data)
+	flipped[len(flipped)-5] ^= 0xff
+	wrongVersion := bytes.Clone(data)
+	wrongVersion[11] = 99
+
+	testCases := map[string][]byte{
+		"flipped byte":  flipped,
+		"truncated":     data[:len(data)-10],
+		"wrong version": wrongVersion,
+		"not snapshot":  []byte("hello, world, this is not a snapshot at all, definitely not"),
+	}
+	for name, corrupt := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ix := newTestIndex()
+			ix.PutUser(database.SearchUser{ID: orderedID(9), Username: "kept"})
+			_, err := ix.ReadSnapshot(bytes.NewReader(corrupt))
+			assert.Error(t, err)
+			assert.Equal(t, 1, ix.Count(index.Users), "a bad snapshot leaves the index untouched")
+		})
+	}
+}
+
+func TestLoadLatestValidSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	ix := snapshotTestIndex()
+
+	older, err := ix.SaveSnapshot(dir, index.HighWater{Txid: 1, ID: 1})
+	require.NoError(t, err)
+	ix.Delete(index.Reports, orderedID(4))
+	newer, err := ix.SaveSnapshot(dir, index.HighWater{Txid: 2, ID: 2})
+	require.NoError(t, err)
+	require.NotEqual(t, older.Path, newer.Path)
+
+	restored := newTestIndex()
+	info, err := restored.LoadLatestSnapshot(dir)
+	require.NoError(t, err)
+	assert.Equal(t, newer.Path, info.Path)
+	assert.Equal(t, 0, restored.Count(index.Reports))
+
+	// Corrupt the newest snapshot: the older one is used instead.
+	data, err := os.ReadFile(newer.Path)
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	require.NoError(t, os.WriteFile(newer.Path, data, 0o600))
+
+	info, err = restored.LoadLatestSnapshot(dir)
+	require.NoError(t, err)
+	assert.Equal(t, older.Path, info.Path)
+	assert.Equal(t, 1, restored.Count(index.Reports))
+
+	_, err = restored.LoadLatestSnapshot(filepath.Join(dir, "missing"))
+	assert.ErrorIs(t, err, index.ErrNoSnapshot)
+}
+
+func TestOldSnapshotVersionsAreDeleted(t *testing.T) {
+	dir := t.TempDir()
+	saved, err := snapshotTestIndex().SaveSnapshot(dir, index.HighWater{})
+	require.NoError(t, err)
+
+	// Version 1 snapshots held full user rows, secrets included.
+	data, err := os.ReadFile(saved.Path)
+	require.NoError(t, err)
+	data[11] = 1
+	require.NoError(t, os.WriteFile(saved.Path, data, 0o600))
+
+	_, err = newTestIndex().LoadLatestSnapshot(dir)
+	assert.ErrorIs(t, err, index.ErrNoSnapshot)
+	assert.NoFileExists(t, saved.Path)
+}
+
+func TestSnapshotterPrunes(t *testing.T) {
+	dir := t.TempDir()
+	ix := snapshotTestIndex()
+	snapshotter := &index.Snapshotter{Index: func() *index.Index { return ix }, Dir: dir, Keep: 2}
+	for i := 0; i < 4; i++ {
+		require.NoError(t, snapshotter.RunOnce())
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.snap"))
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestOutboxReplayAfterSnapshot(t *testing.T) {
+	outbox := newMemoryOutbox()
+	applier := &recordingApplier{}
+	consumer := newTestConsumer(outbox, applier)
+
+	before, after := uuid.New(), uuid.New()
+	outbox.add(10, index.Posts, listener.Insert, before)
+	_, err := consumer.RunOnce(context.Background())
+	require.NoError(t, err)
+	highWater := consumer.Applied()
+	assert.Equal(t, index.HighWater{Txid: 10, ID: 1}, highWater)
+
+	outbox.add(11, index.Posts, listener.Insert, after)
+
+	// A restarted replica loads the snapshot and replays everything after its high-water mark.
+	restarted := newTestConsumer(outbox, &recordingApplier{})
+	replayed := restarted.Applier.(*recordingApplier)
+	require.NoError(t, restarted.SeekTo(context.Background(), highWater))
+	_, err = restarted.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, replayed.applied, 1)
+	assert.Equal(t, after, replayed.applied[0].ID)
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/mocks"
+	"github.com/imhasandl/search-service/internal/ngram"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidateSubstringPattern(t *testing.T) {
+	testCases := map[string]error{
+		"ello":                           nil,
+		"  ello  ":                       nil,
+		"при":                            nil,
+		"a1!":                            nil,
+		"el":                             ngram.ErrTooShort,
+		"   ":                            ngram.ErrTooShort,
+		"...":                            ngram.ErrNoAlphanumeric,
+		"%_%":                            ngram.ErrNoAlphanumeric,
+		"aaaa":                           ngram.ErrRepetitive,
+		"AaAa":                           ngram.ErrRepetitive,
+		string(make([]byte, 300)) + "ab": ngram.ErrTooLong,
+	}
+	for pattern, want := range testCases {
+		assert.Equal(t, want, ngram.Validate(pattern), "%q", pattern)
+	}
+}
+
+func TestTrigrams(t *testing.T) {
+	assert.Equal(t, []string{"hel", "ell", "llo"}, ngram.Trigrams("HeLLo"))
+	assert.Equal(t, []string{"aaa"}, ngram.Trigrams("aaaaa"))
+	assert.Equal(t, []string{"при", "рив"}, ngram.Trigrams("Прив"))
+	assert.Nil(t, ngram.Trigrams("hi"))
+}
+
+func TestIndexSearchPostsBySubstring(t *testing.T) {
+	ix := index.New(analyzer.DefaultFields(), index.WithShards(3))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	posts := []database.Post{
+		{ID: orderedID(1), CreatedAt: start, Body: "Hello world", Language: validQuery("en")},
+		{ID: orderedID(2), CreatedAt: start.Add(time.Hour), Body: "say HELLO", Language: validQuery("en")},
+		{ID: orderedID(3), CreatedAt: start.Add(2 * time.Hour), Body: "mellow yellow", Language: validQuery("ru")},
+		{ID: orderedID(4), CreatedAt: start.Add(3 * time.Hour), Body: "100% sure"},
+	}
+	for _, post := range posts {
+		ix.PutPost(post)
+	}
+	search := func(pattern string, language sql.NullString) []uuid.UUID {
+		t.Helper()
+		found, err := ix.SearchPostsBySubstring(context.Background(), database.SearchPostsBySubstringParams{Pattern: pattern, Language: language})
+		require.NoError(t, err)
+		return postIDs(found)
+	}
+
+	assert.Equal(t, []uuid.UUID{orderedID(3), orderedID(2), orderedID(1)}, search("ello", sql.NullString{}), "newest first, ignoring case")
+	assert.Equal(t, []uuid.UUID{orderedID(2), orderedID(1)}, search("ello", validQuery("en")))
+	assert.Equal(t, []uuid.UUID{orderedID(1)}, search("o w", sql.NullString{}))
+	assert.Equal(t, []uuid.UUID{orderedID(4)}, search("0%", sql.NullString{}), "shorter than a trigram, so every post is scanned")
+	assert.Empty(t, search("0%s", sql.NullString{}), "% matches literally")
+
+	ix.PutPost(database.Post{ID: orderedID(1), CreatedAt: start, Body: "Goodbye world"})
+	ix.Delete(index.Posts, orderedID(2))
+	assert.Equal(t, []uuid.UUID{orderedID(3)}, search("ello", sql.NullString{}))
+}
+
+func TestSearchPostsSubstringMode(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	testServer := server.NewServer(mockDB, "test-secret")
+	post := database.Post{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Body: "Hello world"}
+
+	mockDB.On("SearchPostsBySubstring", mock.Anything, database.SearchPostsBySubstringParams{
+		Pattern:  "ello",
+		Language: validQuery("en"),
+	}).Return([]database.Post{post}, nil).Once()
+
+	resp, err := testServer.SearchPosts(context.Background(), &pb.SearchPostsRequest{
+		Query:    " ello ",
+		Language: "en",
+		Mode:     pb.PostSearchMode_POST_SEARCH_MODE_SUBSTRING,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Post, 1)
+	assert.Equal(t, post.ID.String(), resp.Post[0].Id)
+	mockDB.AssertExpectations(t)
+
+	_, err = testServer.SearchPosts(context.Background(), &pb.SearchPostsRequest{Query: "el", Mode: pb.PostSearchMode_POST_SEARCH_MODE_SUBSTRING})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = testServer.SearchPosts(context.Background(), &pb.SearchPostsRequest{Query: "hello", Mode: pb.PostSearchMode(9)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/mocks"
+	"github.com/imhasandl/search-service/internal/synonyms"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testSynonyms = `
+# equivalences
+nyc, new york
+pic, pics => photo, picture
+`
+
+func TestParseSynonyms(t *testing.T) {
+	rules, err := synonyms.Parse(strings.NewReader(testSynonyms))
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+
+	assert.Equal(t, []string{"nyc", "new york"}, rules[0].Terms)
+	assert.False(t, rules[0].OneWay)
+	assert.Equal(t, []string{"pic", "pics"}, rules[1].Terms)
+	assert.Equal(t, []string{"photo", "picture"}, rules[1].Synonyms)
+	assert.True(t, rules[1].OneWay)
+
+	_, err = synonyms.Parse(strings.NewReader("lonely"))
+	assert.Error(t, err)
+	_, err = synonyms.Parse(strings.NewReader("pic =>"))
+	assert.Error(t, err)
+}
+
+func TestExpandSynonyms(t *testing.T) {
+	rules, err := synonyms.Parse(strings.NewReader(testSynonyms))
+	require.NoError(t, err)
+	set := synonyms.NewSet(rules)
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{name: "one-way", query: "pic", expected: []string{"pic", "photo", "picture"}},
+		{name: "one-way is not reversed", query: "photo", expected: []string{"photo"}},
+		{name: "equivalence", query: "nyc", expected: []string{"nyc", "new york"}},
+		{name: "multi-word equivalence", query: "new york", expected: []string{"new york", "nyc"}},
+		{name: "phrase inside query", query: "Sunset in New York", expected: []string{"Sunset in New York", "Sunset in nyc"}},
+		{name: "no match", query: "hello", expected: []string{"hello"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, set.Expand(tc.query, 0))
+		})
+	}
+
+	assert.Len(t, set.Expand("pic pic pic", 3), 3)
+}
+
+func TestSynonymStoreReload(t *testing.T) {
+	path := writeSynonyms(t, "nyc, new york\n")
+	store := synonyms.NewStore(synonyms.FileSource{Path: path}, 0)
+	require.NoError(t, store.Reload(context.Background()))
+	assert.Equal(t, []string{"pic"}, store.Expand("pic"))
+
+	require.NoError(t, os.WriteFile(path, []byte("pic => photo\n"), 0o644))
+	require.NoError(t, store.Reload(context.Background()))
+	assert.Equal(t, []string{"pic", "photo"}, store.Expand("pic"))
+
+	require.NoError(t, os.WriteFile(path, []byte("broken =>\n"), 0o644))
+	assert.Error(t, store.Reload(context.Background()))
+	assert.Equal(t, []string{"pic", "photo"}, store.Expand("pic"), "failed reload keeps the previous rules")
+}
+
+func TestSearchPostsWithSynonyms(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	store := synonyms.NewStore(synonyms.FileSource{Path: writeSynonyms(t, "pic => photo\n")}, 0)
+	require.NoError(t, store.Reload(context.Background()))
+	testServer := server.NewServer(mockDB, "test-secret", server.WithSynonyms(store))
+
+	shared := database.Post{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Body: "pic photo"}
+	photoOnly := database.Post{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Body: "photo of the day"}
+
+	mockDB.On("SearchPosts", mock.Anything, sql.NullString{String: "pic", Valid: true}).Return([]database.Post{shared}, nil).Once()
+	mockDB.On("SearchPosts", mock.Anything, sql.NullString{String: "photo", Valid: true}).Return([]database.Post{photoOnly, shared}, nil).Once()
+
+	resp, err := testServer.SearchPosts(context.Background(), &pb.SearchPostsRequest{Query: "pic"})
+	require.NoError(t, err)
+	require.Len(t, resp.Post, 2)
+	assert.Equal(t, shared.ID.String(), resp.Post[0].Id)
+	assert.Equal(t, photoOnly.ID.String(), resp.Post[1].Id)
+	mockDB.AssertExpectations(t)
+
+	list, err := testServer.ListSynonyms(context.Background(), &pb.ListSynonymsRequest{Query: "pic"})
+	require.NoError(t, err)
+	assert.Len(t, list.Rules, 1)
+	assert.Equal(t, []string{"pic", "photo"}, list.Expansions)
+}
+
+func writeSynonyms(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "synonyms.txt")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
@@ -0,0 +1,262 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/mocks"
+	"github.com/imhasandl/search-service/internal/tlsconfig"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// testCA is a certificate authority issuing certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM certificate and key of a leaf certificate for 127.0.0.1.
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "search-service"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(name, data, 0o600))
+}
+
+// serveTLS starts the search service with the TLS configuration of certificates and returns its address.
+func serveTLS(t *testing.T, certificates *tlsconfig.Reloader) string {
+	t.Helper()
+	mockDB := mocks.NewMockQueries()
+	mockDB.On("SearchUsers", mock.Anything, mock.Anything).Return([]database.SearchUser{}, nil)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(certificates.TLSConfig())))
+	pb.RegisterSearchServiceServer(s, server.NewServer(mockDB, "test-secret"))
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+// search calls SearchUsers over a new connection.
+func search(t *testing.T, addr string, creds credentials.TransportCredentials) error {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = pb.NewSearchServiceClient(conn).SearchUsers(ctx, &pb.SearchUsersRequest{Query: "john"})
+	return err
+}
+
+// clientTLS returns client credentials trusting ca, presenting cert if it is set,
+// and recording the serial numbers of the server certificates seen in serials.
+func clientTLS(ca *testCA, cert *tls.Certificate, serials *[]*big.Int) credentials.TransportCredentials {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    roots,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if serials != nil {
+				*serials = append(*serials, state.PeerCertificates[0].SerialNumber)
+			}
+			return nil
+		},
+	}
+	if cert != nil {
+		// Sent even when the server asks for another CA, to check the server refuses it.
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return cert, nil
+		}
+	}
+	return credentials.NewTLS(config)
+}
+
+func TestTLSListener(t *testing.T) {
+	ca := newTestCA(t, "server CA")
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	certPEM, keyPEM := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	certificates, err := tlsconfig.New(tlsconfig.Config{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	addr := serveTLS(t, certificates)
+
+	var serials []*big.Int
+	err = search(t, addr, clientTLS(ca, nil, &serials))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), serials[0].Int64())
+
+	err = search(t, addr, insecure.NewCredentials())
+	assert.Error(t, err, "plaintext clients are refused")
+
+	// Rotating the files is picked up without a restart.
+	certPEM, keyPEM = ca.issue(t, 3, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	changed, err := certificates.Reload()
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	err = search(t, addr, clientTLS(ca, nil, &serials))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), serials[1].Int64())
+
+	// A broken file keeps the last good certificate.
+	writeFile(t, keyFile, []byte("not a key"))
+	_, err = certificates.Reload()
+	assert.Error(t, err)
+	err = search(t, addr, clientTLS(ca, nil, &serials))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), serials[2].Int64())
+}
+
+func TestTLSReloadOnFileChange(t *testing.T) {
+	ca := newTestCA(t, "server CA")
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	certPEM, keyPEM := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	certificates, err := tlsconfig.New(tlsconfig.Config{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go certificates.Run(ctx, 10*time.Millisecond)
+	addr := serveTLS(t, certificates)
+
+	certPEM, keyPEM = ca.issue(t, 4, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	assert.Eventually(t, func() bool {
+		var serials []*big.Int
+		if err := search(t, addr, clientTLS(ca, nil, &serials)); err != nil || len(serials) == 0 {
+			return false
+		}
+		return serials[0].Int64() == 4
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestMutualTLSListener(t *testing.T) {
+	serverCA, clientCA, otherCA := newTestCA(t, "server CA"), newTestCA(t, "client CA"), newTestCA(t, "other CA")
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "clients.pem")
+	certPEM, keyPEM := serverCA.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	writeFile(t, caFile, clientCA.pem)
+
+	clientCert := func(ca *testCA) *tls.Certificate {
+		certPEM, keyPEM := ca.issue(t, 10, x509.ExtKeyUsageClientAuth)
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		require.NoError(t, err)
+		return &cert
+	}
+
+	t.Run("require", func(t *testing.T) {
+		certificates, err := tlsconfig.New(tlsconfig.Config{
+			CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ClientAuth: tlsconfig.ClientAuthRequire,
+		})
+		require.NoError(t, err)
+		addr := serveTLS(t, certificates)
+
+		err = search(t, addr, clientTLS(serverCA, clientCert(clientCA), nil))
+		assert.NoError(t, err)
+		err = search(t, addr, clientTLS(serverCA, nil, nil))
+		assert.Error(t, err, "clients without a certificate are refused")
+		err = search(t, addr, clientTLS(serverCA, clientCert(otherCA), nil))
+		assert.Error(t, err, "certificates of other CAs are refused")
+	})
+
+	t.Run("optional", func(t *testing.T) {
+		certificates, err := tlsconfig.New(tlsconfig.Config{
+			CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ClientAuth: tlsconfig.ClientAuthOptional,
+		})
+		require.NoError(t, err)
+		addr := serveTLS(t, certificates)
+
+		err = search(t, addr, clientTLS(serverCA, nil, nil))
+		assert.NoError(t, err)
+		err = search(t, addr, clientTLS(serverCA, clientCert(otherCA), nil))
+		assert.Error(t, err, "certificates sent are still verified")
+	})
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := tlsconfig.New(tlsconfig.Config{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: filepath.Join(dir, "missing.key")})
+	assert.Error(t, err)
+
+	_, err = tlsconfig.New(tlsconfig.Config{CertFile: "server.crt", KeyFile: "server.key", ClientAuth: tlsconfig.ClientAuthRequire})
+	assert.ErrorContains(t, err, "client CA file")
+
+	_, err = tlsconfig.New(tlsconfig.Config{CertFile: "server.crt", KeyFile: "server.key", ClientAuth: "sometimes"})
+	assert.ErrorContains(t, err, "unknown client auth")
+}
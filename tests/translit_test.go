@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/mocks"
+	"github.com/imhasandl/search-service/internal/translit"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransliterationPairs(t *testing.T) {
+	tr := translit.New(translit.DefaultTable)
+
+	testCases := []struct {
+		cyrillic string
+		latin    string
+	}{
+		{cyrillic: "Алишер", latin: "alisher"},
+		{cyrillic: "Шаҳноза", latin: "shahnoza"},
+		{cyrillic: "Ўткир", latin: "o'tkir"},
+		{cyrillic: "Ғайрат", latin: "g'ayrat"},
+		{cyrillic: "Қодир", latin: "qodir"},
+		{cyrillic: "Чўлпон", latin: "cho'lpon"},
+		{cyrillic: "Юлдуз", latin: "yulduz"},
+		{cyrillic: "Яна", latin: "yana"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.latin, func(t *testing.T) {
+			assert.Equal(t, tc.latin, tr.ToLatin(tc.cyrillic))
+			assert.Equal(t, strings.ToLower(tc.cyrillic), tr.ToCyrillic(tc.latin))
+		})
+	}
+}
+
+func TestTransliterationKey(t *testing.T) {
+	tr := translit.New(translit.DefaultTable)
+
+	assert.Equal(t, "alisher", tr.Key("Алишер"))
+	assert.Equal(t, "alisher", tr.Key("Alisher"))
+	assert.Equal(t, "otkir", tr.Key("Oʻtkir"))
+	assert.Equal(t, "otkir", tr.Key("Ўткир"))
+	assert.Equal(t, "john_doe42", tr.Key("john_doe42"))
+}
+
+func TestLoadTransliterationTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "table.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"cyrillic_to_latin": {"х": "kh", "а": "a", "с": "s", "н": "n"},
+		"latin_to_cyrillic": {"kh": "х", "a": "а", "s": "с", "n": "н"}
+	}`), 0o644))
+
+	table, err := translit.LoadTable(path)
+	require.NoError(t, err)
+	tr := translit.New(table)
+	assert.Equal(t, "khasan", tr.ToLatin("Хасан"))
+	assert.Equal(t, "хасан", tr.ToCyrillic("Khasan"))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"cyrillic_to_latin": {"а": "a"}}`), 0o644))
+	_, err = translit.LoadTable(path)
+	assert.Error(t, err)
+}
+
+func TestSearchUsersAcrossScripts(t *testing.T) {
+	mockDB := mocks.NewMockQueries()
+	testServer := server.NewServer(mockDB, "test-secret", server.WithTransliterator(translit.New(translit.DefaultTable)))
+
+	latinUser := database.SearchUser{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Username: "alisher"}
+	mockDB.On("SearchUsers", mock.Anything, sql.NullString{String: "Алишер", Valid: true}).Return([]database.SearchUser{}, nil).Once()
+	mockDB.On("SearchUsers", mock.Anything, sql.NullString{String: "алишер", Valid: true}).Return([]database.SearchUser{}, nil).Once()
+	mockDB.On("SearchUsers", mock.Anything, sql.NullString{String: "alisher", Valid: true}).Return([]database.SearchUser{latinUser}, nil).Once()
+
+	resp, err := testServer.SearchUsers(context.Background(), &pb.SearchUsersRequest{Query: "Алишер"})
+	require.NoError(t, err)
+	require.Len(t, resp.Users, 1)
+	assert.Equal(t, "alisher", resp.Users[0].Username)
+	mockDB.AssertExpectations(t)
+}
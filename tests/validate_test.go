@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/imhasandl/search-service/internal/validate"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// callValidated runs a unary call with req through the validator and returns the request the handler saw.
+func callValidated(v *validate.Validator, req any) (any, error) {
+	var seen any
+	_, err := v.UnaryServerInterceptor()(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: "/search.SearchService/SearchPosts"},
+		func(_ context.Context, req any) (any, error) {
+			seen = req
+			return nil, nil
+		})
+	return seen, err
+}
+
+func fieldViolations(t *testing.T, err error) map[string]string {
+	t.Helper()
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+
+	violations := make(map[string]string)
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, fv := range badRequest.FieldViolations {
+				violations[fv.Field] = fv.Description
+			}
+		}
+	}
+	return violations
+}
+
+func TestValidateTrimsWhitespace(t *testing.T) {
+	seen, err := callValidated(validate.New(), &pb.SearchPostsRequest{Query: "  hello world \n", Language: " en"})
+	require.NoError(t, err)
+	req := seen.(*pb.SearchPostsRequest)
+	assert.Equal(t, "hello world", req.Query)
+	assert.Equal(t, "en", req.Language)
+}
+
+func TestValidateKeepsLikeWildcards(t *testing.T) {
+	// LIKE wildcards are escaped by the queries, the validator passes them on as typed.
+	seen, err := callValidated(validate.New(), &pb.SearchPostsRequest{Query: "100%_off"})
+	require.NoError(t, err)
+	assert.Equal(t, "100%_off", seen.(*pb.SearchPostsRequest).Query)
+}
+
+func TestValidateRejectsInvalidFields(t *testing.T) {
+	v := validate.New(validate.WithMaxLength(10))
+
+	testCases := []struct {
+		name  string
+		req   any
+		field string
+	}{
+		{name: "too long", req: &pb.SearchPostsRequest{Query: strings.Repeat("a", 11)}, field: "query"},
+		{name: "control character", req: &pb.SearchPostsRequest{Query: "a\x00b"}, field: "query"},
+		{name: "inner newline", req: &pb.SearchUsersRequest{Query: "john\ndoe"}, field: "query"},
+		{name: "invalid UTF-8", req: &pb.SearchReportsRequest{Query: "a\xffb"}, field: "query"},
+		{name: "list element", req: &pb.VerifyIndexRequest{Entities: []string{"users", "posts\x7f"}}, field: "entities[1]"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			seen, err := callValidated(v, tc.req)
+			assert.Nil(t, seen, "the handler must not run")
+			assert.Contains(t, fieldViolations(t, err), tc.field)
+		})
+	}
+
+	// Length counts characters, not bytes.
+	_, err := callValidated(v, &pb.SearchUsersRequest{Query: "Мухаммаджон"[:20]})
+	assert.NoError(t, err)
+}
+
+func TestValidateReportsEveryField(t *testing.T) {
+	_, err := callValidated(validate.New(validate.WithMaxLength(3)), &pb.SearchPostsRequest{Query: "abcd", Language: "e\tn"})
+	violations := fieldViolations(t, err)
+	assert.Len(t, violations, 2)
+	assert.Contains(t, violations["query"], "at most 3 characters")
+	assert.Contains(t, violations["language"], "control characters")
+}
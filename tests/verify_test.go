@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/imhasandl/search-service/cmd/server"
+	"github.com/imhasandl/search-service/internal/analyzer"
+	"github.com/imhasandl/search-service/internal/database"
+	"github.com/imhasandl/search-service/internal/index"
+	"github.com/imhasandl/search-service/internal/listener"
+	"github.com/imhasandl/search-service/internal/verify"
+	pb "github.com/imhasandl/search-service/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newDriftedIndex returns a store and an index that is missing post 2, holds a stale post 3 and an orphaned post 9.
+func newDriftedIndex(t *testing.T) (*rowStore, *index.Index) {
+	t.Helper()
+	store := &rowStore{}
+	store.users = []database.SearchUser{{ID: orderedID(1), Username: "john"}}
+	store.posts = []database.Post{
+		{ID: orderedID(2), Body: "first"},
+		{ID: orderedID(3), Body: "second"},
+		{ID: orderedID(4), Body: "third"},
+	}
+	store.reports = []database.Report{{ID: orderedID(5), Reason: "spam"}}
+
+	ix := index.New(analyzer.DefaultFields(), index.WithShards(3))
+	require.NoError(t, index.Load(context.Background(), store, ix, 2))
+	ix.Delete(index.Posts, orderedID(2))
+	ix.PutPost(database.Post{ID: orderedID(3), Body: "second, before it was edited"})
+	ix.PutPost(database.Post{ID: orderedID(9), Body: "deleted long ago"})
+	return store, ix
+}
+
+func newTestChecker(store *rowStore, ix *index.Index) *verify.Checker {
+	return &verify.Checker{
+		DB:        store,
+		Index:     func() *index.Index { return ix },
+		Applier:   &listener.IndexApplier{DB: store, Index: ix, BatchSize: 2},
+		BatchSize: 2,
+	}
+}
+
+func TestVerifyFullScan(t *testing.T) {
+	store, ix := newDriftedIndex(t)
+	checker := newTestChecker(store, ix)
+
+	reports, err := checker.Check(context.Background(), verify.Options{})
+	require.NoError(t, err)
+	require.Len(t, reports, 3)
+	assert.True(t, reports[0].Consistent(), "users")
+	assert.True(t, reports[2].Consistent(), "reports")
+
+	posts := reports[1]
+	assert.Equal(t, index.Posts, posts.Entity)
+	assert.Equal(t, 3, posts.Checked)
+	assert.Equal(t, []uuid.UUID{orderedID(2)}, posts.Missing)
+	assert.Equal(t, []uuid.UUID{orderedID(3)}, posts.Stale)
+	assert.Equal(t, []uuid.UUID{orderedID(9)}, posts.Orphaned)
+	assert.Zero(t, posts.Repaired)
+}
+
+func TestVerifyRepair(t *testing.T) {
+	store, ix := newDriftedIndex(t)
+	checker := newTestChecker(store, ix)
+
+	reports, err := checker.Check(context.Background(), verify.Options{Entities: []index.Entity{index.Posts}, Repair: true})
+	require.NoError(t, err)
+	assert.Equal(t, 3, reports[0].Repaired)
+
+	reports, err = checker.Check(context.Background(), verify.Options{Entities: []index.Entity{index.Posts}})
+	require.NoError(t, err)
+	assert.True(t, reports[0].Consistent())
+	doc, ok := ix.Lookup(index.Posts, orderedID(3))
+	require.True(t, ok)
+	assert.Equal(t, "second", doc.(database.Post).Body)
+}
+
+func TestVerifySample(t *testing.T) {
+	store := &rowStore{}
+	for i := 1; i <= 50; i++ {
+		store.posts = append(store.posts, database.Post{ID: uuid.UUID{0: byte(i * 5), 15: byte(i)}, Body: "post"})
+	}
+	ix := newTestIndex()
+	require.NoError(t, index.Load(context.Background(), store, ix, 10))
+	checker := newTestChecker(store, ix)
+
+	reports, err := checker.Check(context.Background(), verify.Options{Entities: []index.Entity{index.Posts}, SampleSize: 10})
+	require.NoError(t, err)
+	assert.True(t, reports[0].Consistent())
+	assert.LessOrEqual(t, reports[0].Checked, 10)
+	assert.Positive(t, reports[0].Checked)
+}
+
+func TestVerifyIndexRPC(t *testing.T) {
+	store, ix := newDriftedIndex(t)
+	testServer := server.NewServer(ix, "test-secret", server.WithVerifier(newTestChecker(store, ix)))
+
+	resp, err := testServer.VerifyIndex(context.Background(), &pb.VerifyIndexRequest{Entities: []string{"posts"}})
+	require.NoError(t, err)
+	require.Len(t, resp.Entities, 1)
+	assert.Equal(t, int64(1), resp.Entities[0].MissingCount)
+	assert.Equal(t, []string{orderedID(9).String()}, resp.Entities[0].Orphaned)
+
+	_, err = testServer.VerifyIndex(context.Background(), &pb.VerifyIndexRequest{Entities: []string{"messages"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = server.NewServer(ix, "test-secret").VerifyIndex(context.Background(), &pb.VerifyIndexRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}